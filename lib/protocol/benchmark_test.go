@@ -3,11 +3,15 @@
 package protocol
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/binary"
+	"fmt"
+	"io"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/syncthing/syncthing/lib/dialer"
 )
@@ -102,6 +106,116 @@ func benchmarkRequestsConnPair(b *testing.B, conn0, conn1 net.Conn) {
 	}
 }
 
+func BenchmarkRequestsSlowWrites(b *testing.B) {
+	// Benchmarks the rate at which we can serve many concurrent small
+	// requests when every write to the link has a fixed cost, as is the
+	// case for syscalls and packets on a high latency, high bandwidth
+	// link, with and without buffering.
+	for _, size := range []int{0, 256 << 10} {
+		b.Run(fmt.Sprintf("buffer=%d", size), func(b *testing.B) {
+			conn0, conn1, err := getTCPConnectionPair()
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer conn0.Close()
+			defer conn1.Close()
+
+			var opts []Option
+			if size > 0 {
+				opts = append(opts, WithReadBufferSize(size), WithWriteBufferSize(size))
+			}
+			c0 := NewConnection(LocalDeviceID, conn0, &slowWriter{conn0, 50 * time.Microsecond}, new(fakeModel), "c0", CompressNever, opts...)
+			c0.Start()
+			c1 := NewConnection(LocalDeviceID, conn1, &slowWriter{conn1, 50 * time.Microsecond}, new(fakeModel), "c1", CompressNever, opts...)
+			c1.Start()
+			c0.ClusterConfig(ClusterConfig{})
+			c1.ClusterConfig(ClusterConfig{})
+
+			b.ReportAllocs()
+			b.SetBytes(4 << 10)
+			b.SetParallelism(16)
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					buf, err := c0.Request(context.Background(), "folder", "file", 0, 4<<10, nil, 0, false)
+					if err != nil {
+						b.Fatal(err)
+					}
+					if len(buf) != 4<<10 {
+						b.Fatal("Incorrect returned buf length", len(buf), "!=", 4<<10)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkIndexUpdatesWriteBuffer(b *testing.B) {
+	// Benchmarks the rate at which a stream of small index updates is
+	// sent for different write buffer sizes, when every write to the link
+	// has a fixed cost. Index updates are handed to the writer one at a
+	// time, so unlike for requests the buffer is usually flushed after
+	// every message.
+	files := make([]FileInfo, 10)
+	for i := range files {
+		files[i] = FileInfo{
+			Name:    fmt.Sprintf("dir/file%d", i),
+			Type:    FileInfoTypeFile,
+			Size:    128 << 10,
+			Version: Vector{}.Update(1),
+			Blocks:  []BlockInfo{{Size: 128 << 10, Hash: make([]byte, 32)}},
+		}
+	}
+	msgLen := (&IndexUpdate{Folder: "folder", Files: files}).ProtoSize()
+
+	for _, size := range []int{0, 4 << 10, 64 << 10, 1 << 20} {
+		b.Run(fmt.Sprintf("buffer=%d", size), func(b *testing.B) {
+			conn0, conn1, err := getTCPConnectionPair()
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer conn0.Close()
+			defer conn1.Close()
+
+			var opts []Option
+			if size > 0 {
+				opts = append(opts, WithWriteBufferSize(size))
+			}
+			c0 := NewConnection(LocalDeviceID, conn0, &slowWriter{conn0, 50 * time.Microsecond}, new(fakeModel), "c0", CompressNever, opts...)
+			c0.Start()
+			c1 := NewConnection(LocalDeviceID, conn1, conn1, new(fakeModel), "c1", CompressNever)
+			c1.Start()
+			c0.ClusterConfig(ClusterConfig{})
+			c1.ClusterConfig(ClusterConfig{})
+
+			b.ReportAllocs()
+			b.SetBytes(int64(msgLen))
+			b.SetParallelism(4)
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if err := c0.IndexUpdate(context.Background(), "folder", files); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+// slowWriter sleeps for the given time on every write
+type slowWriter struct {
+	io.Writer
+	delay time.Duration
+}
+
+func (w *slowWriter) Write(bs []byte) (int, error) {
+	time.Sleep(w.delay)
+	return w.Writer.Write(bs)
+}
+
 // returns the two endpoints of a TCP connection over lo0
 func getTCPConnectionPair() (net.Conn, net.Conn, error) {
 	lst, err := net.Listen("tcp", "127.0.0.1:0")
@@ -193,3 +307,76 @@ func (m *fakeModel) Closed(conn Connection, err error) {
 func (m *fakeModel) DownloadProgress(deviceID DeviceID, folder string, updates []FileDownloadProgressUpdate) error {
 	return nil
 }
+
+func BenchmarkSmallRequests(b *testing.B) {
+	// Benchmarks the rate at which we can serve many concurrent small
+	// requests, such as for metadata, over the loopback interface, with
+	// and without small message buffers.
+	for _, size := range []int{0, DefaultSmallMessageSize} {
+		b.Run(fmt.Sprintf("small=%d", size), func(b *testing.B) {
+			conn0, conn1, err := getTCPConnectionPair()
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer conn0.Close()
+			defer conn1.Close()
+
+			c0 := NewConnection(LocalDeviceID, conn0, conn0, new(fakeModel), "c0", CompressMetadata, WithSmallMessageSize(size))
+			c0.Start()
+			c1 := NewConnection(LocalDeviceID, conn1, conn1, new(fakeModel), "c1", CompressMetadata, WithSmallMessageSize(size))
+			c1.Start()
+			c0.ClusterConfig(ClusterConfig{})
+			c1.ClusterConfig(ClusterConfig{})
+
+			b.ReportAllocs()
+			b.SetBytes(256)
+			b.SetParallelism(4)
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					buf, err := c0.Request(context.Background(), "folder", "file", 0, 256, nil, 0, false)
+					if err != nil {
+						b.Fatal(err)
+					}
+					if len(buf) != 256 {
+						b.Fatal("Incorrect returned buf length", len(buf), "!=", 256)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkEncodeDecodeSmallResponse(b *testing.B) {
+	// Benchmarks the framing alone of small responses, with and without
+	// small message buffers.
+	for _, size := range []int{0, DefaultSmallMessageSize} {
+		b.Run(fmt.Sprintf("small=%d", size), func(b *testing.B) {
+			var buf bytes.Buffer
+			enc := NewEncoder(&buf, CompressMetadata)
+			enc.SetSmallMessageSize(size)
+			dec := NewDecoder(&buf)
+			dec.SetStreams(true)
+			dec.SetSmallMessageSize(size)
+			msg := &Response{ID: 1, Data: make([]byte, 256)}
+
+			b.ReportAllocs()
+			b.SetBytes(256)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				f, err := enc.frames(msg, streamData)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := enc.writeFrame(f); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := dec.Decode(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -113,10 +113,20 @@ func (f FileInfo) FileLocalFlags() uint32 {
 	return f.LocalFlags
 }
 
+// ModTime returns the modification time of the file, with nanosecond
+// precision.
 func (f FileInfo) ModTime() time.Time {
 	return time.Unix(f.ModifiedS, int64(f.ModifiedNs))
 }
 
+// SetModTime sets the modification time of the file, with nanosecond
+// precision. The time zone of t is irrelevant, as the time is stored as an
+// offset from the Unix epoch.
+func (f *FileInfo) SetModTime(t time.Time) {
+	f.ModifiedS = t.Unix()
+	f.ModifiedNs = int32(t.Nanosecond())
+}
+
 func (f FileInfo) SequenceNo() int64 {
 	return f.Sequence
 }
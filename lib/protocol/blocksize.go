@@ -0,0 +1,50 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+// BlockSize returns the block size agreed on with the other side during the
+// handshake, or zero if there is none, in which case the block size should
+// be chosen per file, as by the BlockSize function. See
+// WithPreferredBlockSize.
+func (c *rawConnection) BlockSize() int {
+	select {
+	case <-c.handshakeDone:
+		return c.agreedBlockSize
+	default:
+		return 0
+	}
+}
+
+// agreeBlockSize returns the block size to use given the preferences of
+// both sides, where zero means no preference. Preferences that aren't
+// valid block sizes, e.g. from a future version with other block sizes,
+// are ignored. When both sides prefer a valid block size we go with the
+// smaller one, as any file can be split into blocks of either size and
+// smaller blocks allow requesting just the data that changed.
+func agreeBlockSize(ours, theirs int) int {
+	if !isValidBlockSize(ours) {
+		ours = 0
+	}
+	if !isValidBlockSize(theirs) {
+		theirs = 0
+	}
+	switch {
+	case ours == 0:
+		return theirs
+	case theirs == 0:
+		return ours
+	case theirs < ours:
+		return theirs
+	default:
+		return ours
+	}
+}
+
+func isValidBlockSize(size int) bool {
+	for _, bs := range BlockSizes {
+		if size == bs {
+			return true
+		}
+	}
+	return false
+}
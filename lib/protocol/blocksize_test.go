@@ -0,0 +1,78 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestAgreeBlockSize(t *testing.T) {
+	cases := []struct {
+		ours, theirs, agreed int
+	}{
+		{0, 0, 0},
+		{MinBlockSize, 0, MinBlockSize},
+		{0, MaxBlockSize, MaxBlockSize},
+		{1 << MiB, 256 << KiB, 256 << KiB},
+		{256 << KiB, 1 << MiB, 256 << KiB},
+		{512 << KiB, 512 << KiB, 512 << KiB},
+		// Invalid sizes are ignored
+		{512 << KiB, 1000, 512 << KiB},
+		{32 << MiB, 0, 0},
+		{-1, -1, 0},
+	}
+	for _, tc := range cases {
+		if agreed := agreeBlockSize(tc.ours, tc.theirs); agreed != tc.agreed {
+			t.Errorf("agreeBlockSize(%d, %d) = %d, expected %d", tc.ours, tc.theirs, agreed, tc.agreed)
+		}
+	}
+}
+
+func TestBlockSizeNegotiation(t *testing.T) {
+	cases := []struct {
+		pref0, pref1, agreed int
+	}{
+		{0, 0, 0},
+		{MinBlockSize, 0, MinBlockSize},
+		{1 << MiB, 256 << KiB, 256 << KiB},
+		// A peer preferring a block size we don't know about
+		{512 << KiB, 3 << MiB, 512 << KiB},
+	}
+	for _, tc := range cases {
+		ar, aw := io.Pipe()
+		br, bw := io.Pipe()
+
+		c0 := NewConnection(c1ID, ar, bw, newTestModel(), "c0", CompressNever, WithPreferredBlockSize(tc.pref0)).(wireFormatConnection)
+		c1 := NewConnection(c0ID, br, aw, newTestModel(), "c1", CompressNever).(wireFormatConnection)
+		// Bypassing the option, which would ignore an unknown block size
+		c1.preferredBlockSize = tc.pref1
+		if bs := c0.BlockSize(); bs != 0 {
+			t.Errorf("Block size %d before the handshake", bs)
+		}
+		c0.Start()
+		c1.Start()
+		c0.ClusterConfig(ClusterConfig{})
+		c1.ClusterConfig(ClusterConfig{})
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		for _, c := range []wireFormatConnection{c0, c1} {
+			if err := c.WaitHandshake(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cancel()
+
+		if bs := c0.BlockSize(); bs != tc.agreed {
+			t.Errorf("%d vs %d: c0 agreed on %d, expected %d", tc.pref0, tc.pref1, bs, tc.agreed)
+		}
+		if bs := c1.BlockSize(); bs != tc.agreed {
+			t.Errorf("%d vs %d: c1 agreed on %d, expected %d", tc.pref0, tc.pref1, bs, tc.agreed)
+		}
+
+		ar.Close()
+		br.Close()
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"sync"
+)
+
+// byteSemaphore limits the number of bytes in use at any one time. Requests
+// for more than the maximum are capped to the maximum, so that they can
+// proceed when nothing else is in use.
+type byteSemaphore struct {
+	max       int
+	available int
+	mut       sync.Mutex
+	cond      *sync.Cond
+}
+
+func newByteSemaphore(max int) *byteSemaphore {
+	s := byteSemaphore{
+		max:       max,
+		available: max,
+	}
+	s.cond = sync.NewCond(&s.mut)
+	return &s
+}
+
+func (s *byteSemaphore) take(ctx context.Context, bytes int) error {
+	done := make(chan struct{})
+	var err error
+	go func() {
+		err = s.takeInner(ctx, bytes)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		// Broadcasting under the lock makes sure takeInner either sees
+		// the context done before waiting or is woken up.
+		s.mut.Lock()
+		s.cond.Broadcast()
+		s.mut.Unlock()
+		<-done
+	}
+	return err
+}
+
+func (s *byteSemaphore) takeInner(ctx context.Context, bytes int) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if bytes > s.max {
+		bytes = s.max
+	}
+	for bytes > s.available {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.cond.Wait()
+	}
+	s.available -= bytes
+	return nil
+}
+
+func (s *byteSemaphore) give(bytes int) {
+	s.mut.Lock()
+	if bytes > s.max {
+		bytes = s.max
+	}
+	s.available += bytes
+	if s.available > s.max {
+		s.available = s.max
+	}
+	s.cond.Broadcast()
+	s.mut.Unlock()
+}
@@ -0,0 +1,43 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestByteSemaphoreCancel(t *testing.T) {
+	s := newByteSemaphore(10)
+	if err := s.take(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nobody gives, so only the context can end the wait, however soon it
+	// is done.
+	for _, timeout := range []time.Duration{0, time.Microsecond, 10 * time.Millisecond} {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		errC := make(chan error, 1)
+		go func() {
+			errC <- s.take(ctx, 1)
+		}()
+		select {
+		case err := <-errC:
+			if err != context.DeadlineExceeded {
+				t.Errorf("take with timeout %v returned %v, expected context.DeadlineExceeded", timeout, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("take with timeout %v didn't return", timeout)
+		}
+		cancel()
+	}
+
+	// Nothing was taken by the cancelled calls.
+	s.give(10)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.take(ctx, 10); err != nil {
+		t.Errorf("take after give returned %v", err)
+	}
+}
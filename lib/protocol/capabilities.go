@@ -0,0 +1,56 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+// Capabilities is a set of optional protocol features, advertised by each
+// side in its cluster config. A feature may only be used when the other side
+// advertises it.
+type Capabilities uint64
+
+const (
+	// CapabilityPush means that the device accepts Push messages.
+	CapabilityPush Capabilities = 1 << iota
+	// CapabilityPong means that the device answers a Ping with an ID with
+	// a Pong.
+	CapabilityPong
+	// CapabilityRequestPriority means that the device understands request
+	// priorities.
+	CapabilityRequestPriority
+	// CapabilityQuota means that the device answers quota requests.
+	CapabilityQuota
+	// CapabilityIndexSummary means that the device accepts index summaries.
+	CapabilityIndexSummary
+	// CapabilityConditionalRequest means that the device answers requests
+	// carrying a present hash with ErrNotModified when the data is
+	// unchanged.
+	CapabilityConditionalRequest
+	// CapabilityPackedBlocks means that the device understands blocks sent
+	// as packed hashes in index messages.
+	CapabilityPackedBlocks
+	// CapabilityListing means that the device answers listing requests.
+	CapabilityListing
+	// CapabilityStreams means that the device understands messages split
+	// into frames on several streams.
+	CapabilityStreams
+	// CapabilityFileInfo means that the device answers file info requests.
+	CapabilityFileInfo
+	// CapabilityIndexAck means that the device wants index data it sends
+	// to be acknowledged.
+	CapabilityIndexAck
+	// CapabilityRangeRequest means that the device answers range requests.
+	CapabilityRangeRequest
+	// CapabilityInlineData means that the device understands the content
+	// of small files sent inline in index messages.
+	CapabilityInlineData
+	// CapabilityIndexReject means that the device wants to be told when
+	// index data it sends is rejected.
+	CapabilityIndexReject
+	// CapabilityIdleMode means that the device understands Idle messages,
+	// and advertises the idle ping interval it accepts.
+	CapabilityIdleMode
+)
+
+// Has returns true if all of the given capabilities are set.
+func (c Capabilities) Has(other Capabilities) bool {
+	return c&other == other
+}
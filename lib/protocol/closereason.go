@@ -0,0 +1,95 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import "sync/atomic"
+
+// CloseReason categorizes why a connection was closed.
+type CloseReason int32
+
+const (
+	// CloseReasonNone means that the connection hasn't been closed.
+	CloseReasonNone CloseReason = iota
+	// CloseReasonReadError means that reading or decoding a message from
+	// the other side failed.
+	CloseReasonReadError
+	// CloseReasonWriteError means that writing a message to the other side
+	// failed.
+	CloseReasonWriteError
+	// CloseReasonProtocolError means that the other side sent a message it
+	// shouldn't have, at least not at that time.
+	CloseReasonProtocolError
+	// CloseReasonPingTimeout means that nothing was received from the other
+	// side for too long.
+	CloseReasonPingTimeout
+	// CloseReasonLocalClose means that we closed the connection, by calling
+	// Close or by the model returning an error.
+	CloseReasonLocalClose
+	// CloseReasonPeerClose means that the other side closed the connection
+	// with a Close message.
+	CloseReasonPeerClose
+	// CloseReasonHandshakeFailure means that the model rejected the cluster
+	// config of the other side, or it wasn't received in time.
+	CloseReasonHandshakeFailure
+)
+
+func (r CloseReason) String() string {
+	switch r {
+	case CloseReasonNone:
+		return "none"
+	case CloseReasonReadError:
+		return "read error"
+	case CloseReasonWriteError:
+		return "write error"
+	case CloseReasonProtocolError:
+		return "protocol error"
+	case CloseReasonPingTimeout:
+		return "ping timeout"
+	case CloseReasonLocalClose:
+		return "local close"
+	case CloseReasonPeerClose:
+		return "peer close"
+	case CloseReasonHandshakeFailure:
+		return "handshake failure"
+	default:
+		return "unknown"
+	}
+}
+
+// A CloseReasonModel is told why the connection was closed, in addition to
+// Model.Closed being called. A Model passed to NewConnection that also
+// implements CloseReasonModel is used as such.
+type CloseReasonModel interface {
+	ClosedWithReason(conn Connection, reason CloseReason, err error)
+}
+
+// An AbandonedRequestsModel is told, once per connection, how many of our
+// requests of any kind, including pushes, were still waiting for a
+// response when the connection closed; their callers get
+// ErrClosed. Outstanding pings are not counted. A Model passed to
+// NewConnection that also implements AbandonedRequestsModel is used as
+// such. The call is made after Model.Closed.
+type AbandonedRequestsModel interface {
+	OnConnectionClosed(conn Connection, abandonedRequests int, err error)
+}
+
+// closeReasonError is returned by the dispatcher loop for errors that
+// close the connection for another reason than a protocol error.
+type closeReasonError struct {
+	reason CloseReason
+	err    error
+}
+
+func (e *closeReasonError) Error() string {
+	return e.err.Error()
+}
+
+func (e *closeReasonError) Unwrap() error {
+	return e.err
+}
+
+// CloseReason returns why the connection was closed, or CloseReasonNone if
+// it hasn't been.
+func (c *rawConnection) CloseReason() CloseReason {
+	return CloseReason(atomic.LoadInt32(&c.closeReason))
+}
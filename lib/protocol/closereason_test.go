@@ -0,0 +1,134 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/testutils"
+)
+
+type testCloseReasonModel struct {
+	*TestModel
+	reasons chan CloseReason
+}
+
+func (m *testCloseReasonModel) ClosedWithReason(conn Connection, reason CloseReason, err error) {
+	m.reasons <- reason
+}
+
+func newTestCloseReasonModel() *testCloseReasonModel {
+	return &testCloseReasonModel{newTestModel(), make(chan CloseReason, 1)}
+}
+
+func (m *testCloseReasonModel) expectReason(t *testing.T, conn Connection, exp CloseReason) {
+	t.Helper()
+	select {
+	case reason := <-m.reasons:
+		if reason != exp {
+			t.Errorf("Closed with reason %v, expected %v", reason, exp)
+		}
+		if reason := conn.(interface{ CloseReason() CloseReason }).CloseReason(); reason != exp {
+			t.Errorf("Connection has reason %v, expected %v", reason, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for close with reason %v", exp)
+	}
+}
+
+func TestCloseReasonLocalAndPeer(t *testing.T) {
+	m0 := newTestCloseReasonModel()
+	m1 := newTestCloseReasonModel()
+	c0, c1 := newTestConnections(t, m0, m1, CompressNever, nil, nil)
+
+	if reason := c0.CloseReason(); reason != CloseReasonNone {
+		t.Errorf("Open connection has reason %v", reason)
+	}
+
+	c0.Close(errors.New("because"))
+	m0.expectReason(t, c0, CloseReasonLocalClose)
+	m1.expectReason(t, c1, CloseReasonPeerClose)
+}
+
+func TestCloseReasonProtocolError(t *testing.T) {
+	r, w := io.Pipe()
+	m := newTestCloseReasonModel()
+	c := NewConnection(c0ID, r, ioutil.Discard, m, "name", CompressNever)
+	c.Start()
+
+	// An index before the cluster config is a protocol error.
+	go NewEncoder(w, CompressNever).Encode(&Index{Folder: "default"})
+
+	m.expectReason(t, c, CloseReasonProtocolError)
+}
+
+func TestCloseReasonReadError(t *testing.T) {
+	r, w := io.Pipe()
+	m := newTestCloseReasonModel()
+	c := NewConnection(c0ID, r, ioutil.Discard, m, "name", CompressNever)
+	c.Start()
+
+	w.CloseWithError(errors.New("broken"))
+
+	m.expectReason(t, c, CloseReasonReadError)
+}
+
+func TestCloseReasonHandshakeFailure(t *testing.T) {
+	m := newTestCloseReasonModel()
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, m, "name", CompressNever, WithHandshakeTimeout(10*time.Millisecond))
+	c.Start()
+
+	m.expectReason(t, c, CloseReasonHandshakeFailure)
+}
+
+type testAbandonedModel struct {
+	*TestModel
+	abandoned chan int
+}
+
+func (m *testAbandonedModel) OnConnectionClosed(conn Connection, abandonedRequests int, err error) {
+	m.abandoned <- abandonedRequests
+}
+
+func TestAbandonedRequests(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	m0 := newTestModel()
+	m0.requestFn = func(string, string, int32, int64) (RequestResponse, error) {
+		<-release
+		return &fakeRequestResponse{[]byte("data")}, nil
+	}
+	m1 := &testAbandonedModel{newTestModel(), make(chan int, 1)}
+	_, c1 := newTestConnections(t, m0, m1, CompressNever, nil, nil)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := c1.Request(context.Background(), "default", "foo", 0, 4, nil, 0, false)
+			errs <- err
+		}()
+	}
+	for len(c1.InFlight()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	c1.Close(errors.New("because"))
+	select {
+	case n := <-m1.abandoned:
+		if n != 2 {
+			t.Errorf("%d abandoned requests reported, expected 2", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for abandoned requests")
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != ErrClosed {
+			t.Errorf("Abandoned request returned %v, expected ErrClosed", err)
+		}
+	}
+}
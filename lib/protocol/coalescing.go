@@ -0,0 +1,110 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"time"
+)
+
+type requestKey struct {
+	folder        string
+	name          string
+	offset        int64
+	size          int
+	hash          string
+	weakHash      uint32
+	fromTemporary bool
+	presentHash   string
+}
+
+// A sharedRequest is a request on the wire with one or more callers
+// waiting for the result.
+type sharedRequest struct {
+	waiters  int
+	deadline time.Time // zero if the request doesn't expire
+	cancel   context.CancelFunc
+	done     chan struct{} // closed when val and err are set
+	val      []byte
+	err      error
+}
+
+// sharedRequest attaches the caller to an identical request already in
+// flight, or starts a new one. The request on the wire is cancelled only
+// when all waiting callers have given up on it. It carries the deadline of
+// the caller that started it, so a caller only joins if that deadline is
+// no earlier than its own; otherwise the other side might give up on the
+// request too soon.
+func (c *rawConnection) sharedRequest(ctx context.Context, folder string, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
+	key := requestKey{folder, name, offset, size, string(hash), weakHash, fromTemporary, string(presentHash(ctx))}
+	deadline, _ := ctx.Deadline()
+
+	c.sharedRequestsMut.Lock()
+	sr, ok := c.sharedRequests[key]
+	if !ok || !sr.outlasts(deadline) {
+		// The request outlives the context of any single caller, but keeps
+		// the priority and deadline of the first one, and the present hash
+		// all of them share.
+		reqCtx := ContextWithRequestPriority(context.Background(), requestPriority(ctx))
+		reqCtx = ContextWithPresentHash(reqCtx, presentHash(ctx))
+		var cancel context.CancelFunc
+		if deadline.IsZero() {
+			reqCtx, cancel = context.WithCancel(reqCtx)
+		} else {
+			reqCtx, cancel = context.WithDeadline(reqCtx, deadline)
+		}
+		sr = &sharedRequest{
+			deadline: deadline,
+			cancel:   cancel,
+			done:     make(chan struct{}),
+		}
+		c.sharedRequests[key] = sr
+		go func() {
+			sr.val, sr.err = c.request(reqCtx, folder, name, offset, size, hash, weakHash, fromTemporary)
+			c.forgetSharedRequest(key, sr)
+			cancel()
+			close(sr.done)
+		}()
+	}
+	sr.waiters++
+	c.sharedRequestsMut.Unlock()
+
+	select {
+	case <-sr.done:
+		if sr.err != nil {
+			return nil, sr.err
+		}
+		// Each caller gets its own copy, as callers are free to modify or
+		// hold on to the returned data.
+		return append([]byte{}, sr.val...), nil
+
+	case <-ctx.Done():
+		c.sharedRequestsMut.Lock()
+		sr.waiters--
+		if sr.waiters == 0 {
+			sr.cancel()
+			if c.sharedRequests[key] == sr {
+				delete(c.sharedRequests, key)
+			}
+		}
+		c.sharedRequestsMut.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// outlasts returns true if the request doesn't expire before the given
+// deadline, where zero means never.
+func (sr *sharedRequest) outlasts(deadline time.Time) bool {
+	if sr.deadline.IsZero() {
+		return true
+	}
+	return !deadline.IsZero() && !deadline.After(sr.deadline)
+}
+
+func (c *rawConnection) forgetSharedRequest(key requestKey, sr *sharedRequest) {
+	c.sharedRequestsMut.Lock()
+	if c.sharedRequests[key] == sr {
+		delete(c.sharedRequests, key)
+	}
+	c.sharedRequestsMut.Unlock()
+}
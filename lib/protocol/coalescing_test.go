@@ -0,0 +1,186 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestCoalescing(t *testing.T) {
+	var calls int32
+	unblock := make(chan struct{})
+	m0 := newTestModel()
+	m0.requestFn = func(folder, name string, size int32, offset int64) (RequestResponse, error) {
+		atomic.AddInt32(&calls, 1)
+		<-unblock
+		return &fakeRequestResponse{[]byte(name)}, nil
+	}
+
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, nil, []Option{WithRequestCoalescing()})
+
+	// Ten identical requests and one for a different file
+	names := []string{"foo", "foo", "foo", "foo", "foo", "foo", "foo", "foo", "foo", "foo", "bar"}
+	results := make([][]byte, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			data, err := c1.Request(context.Background(), "default", name, 0, 3, nil, 0, false)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = data
+		}(i, name)
+	}
+
+	// Give the requests time to be sent before letting the model respond.
+	time.Sleep(100 * time.Millisecond)
+	close(unblock)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("Model got %d requests, expected 2", n)
+	}
+	for i, name := range names {
+		if !bytes.Equal(results[i], []byte(name)) {
+			t.Errorf("Request %d got %q, expected %q", i, results[i], name)
+		}
+	}
+	if &results[0][0] == &results[1][0] {
+		t.Error("Callers should get separate copies of the response data")
+	}
+}
+
+func TestRequestCoalescingCancel(t *testing.T) {
+	unblock := make(chan struct{})
+	m0 := newTestModel()
+	m0.requestFn = func(folder, name string, size int32, offset int64) (RequestResponse, error) {
+		<-unblock
+		return &fakeRequestResponse{[]byte(name)}, nil
+	}
+
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, nil, []Option{WithRequestCoalescing()})
+	defer close(unblock)
+
+	// One caller gives up, the other should still get the response.
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := c1.Request(ctx, "default", "foo", 0, 3, nil, 0, false)
+		errs <- err
+	}()
+
+	res := make(chan []byte, 1)
+	go func() {
+		data, err := c1.Request(context.Background(), "default", "foo", 0, 3, nil, 0, false)
+		if err != nil {
+			t.Error(err)
+		}
+		res <- data
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("Unexpected error %v, expected %v", err, context.Canceled)
+	}
+
+	unblock <- struct{}{}
+	select {
+	case data := <-res:
+		if string(data) != "foo" {
+			t.Errorf("Unexpected response %q", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for response")
+	}
+}
+
+func TestRequestCoalescingDeadline(t *testing.T) {
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	defer ar.Close()
+	defer br.Close()
+
+	c := NewConnection(c0ID, ar, bw, newTestModel(), "name", CompressNever, WithRequestCoalescing(), WithoutPinger()).(wireFormatConnection).rawConnection
+	c.Start()
+	c.ClusterConfig(ClusterConfig{})
+	enc := NewEncoder(aw, CompressNever)
+	if err := enc.Encode(&ClusterConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	dec := NewDecoder(br)
+	if _, err := dec.Decode(); err != nil { // our cluster config
+		t.Fatal(err)
+	}
+	nextRequest := func() *Request {
+		t.Helper()
+		msg, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, ok := msg.(*Request)
+		if !ok {
+			t.Fatalf("Expected a request, got %T", msg)
+		}
+		return req
+	}
+
+	errs := make(chan error, 3)
+	request := func(timeout time.Duration) {
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		defer cancel()
+		_, err := c.Request(ctx, "default", "foo", 0, 3, nil, 0, false)
+		errs <- err
+	}
+	waiters := func() int {
+		c.sharedRequestsMut.Lock()
+		defer c.sharedRequestsMut.Unlock()
+		for _, sr := range c.sharedRequests {
+			return sr.waiters
+		}
+		return 0
+	}
+
+	// The first request carries its deadline to the other side.
+	go request(10 * time.Second)
+	first := nextRequest()
+	if first.TimeoutMs <= 5000 || first.TimeoutMs > 10000 {
+		t.Errorf("First request has timeout %d ms, expected about 10 s", first.TimeoutMs)
+	}
+
+	// One with an earlier deadline joins it.
+	go request(5 * time.Second)
+	for waiters() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// One without a deadline can't, as the other side might give up on
+	// the first too soon.
+	go request(0)
+	second := nextRequest()
+	if second.TimeoutMs != 0 {
+		t.Errorf("Second request has timeout %d ms, expected none", second.TimeoutMs)
+	}
+
+	for _, id := range []int32{first.ID, second.ID} {
+		if err := enc.Encode(&Response{ID: id, Data: []byte("foo")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
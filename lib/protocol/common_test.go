@@ -2,7 +2,11 @@
 
 package protocol
 
-import "time"
+import (
+	"io"
+	"testing"
+	"time"
+)
 
 type TestModel struct {
 	data          []byte
@@ -14,6 +18,8 @@ type TestModel struct {
 	weakHash      uint32
 	fromTemporary bool
 	indexFn       func(DeviceID, string, []FileInfo)
+	indexUpdateFn func(DeviceID, string, []FileInfo)
+	requestFn     func(folder, name string, size int32, offset int64) (RequestResponse, error)
 	ccFn          func(DeviceID, ClusterConfig)
 	closedCh      chan struct{}
 	closedErr     error
@@ -33,10 +39,16 @@ func (t *TestModel) Index(deviceID DeviceID, folder string, files []FileInfo) er
 }
 
 func (t *TestModel) IndexUpdate(deviceID DeviceID, folder string, files []FileInfo) error {
+	if t.indexUpdateFn != nil {
+		t.indexUpdateFn(deviceID, folder, files)
+	}
 	return nil
 }
 
 func (t *TestModel) Request(deviceID DeviceID, folder, name string, size int32, offset int64, hash []byte, weakHash uint32, fromTemporary bool) (RequestResponse, error) {
+	if t.requestFn != nil {
+		return t.requestFn(folder, name, size, offset)
+	}
 	t.folder = folder
 	t.name = name
 	t.offset = offset
@@ -74,6 +86,37 @@ func (t *TestModel) closedError() error {
 	}
 }
 
+// newTestConnections returns two started connections to each other over
+// pipes, which are closed when the test is done: c0, the connection to
+// c1ID with model m0 and options opts0, and c1, the connection to c0ID with
+// m1 and opts1. Both compress as given, don't ping, and have sent an empty
+// ClusterConfig. They are returned as the type NewConnection returns, with
+// all methods.
+func newTestConnections(t *testing.T, m0, m1 Model, compress Compression, opts0, opts1 []Option) (c0, c1 wireFormatConnection) {
+	t.Helper()
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	t.Cleanup(func() {
+		ar.Close()
+		br.Close()
+	})
+	c0 = NewConnection(c1ID, ar, bw, m0, "c0", compress, append([]Option{WithoutPinger()}, opts0...)...).(wireFormatConnection)
+	c0.Start()
+	c1 = NewConnection(c0ID, br, aw, m1, "c1", compress, append([]Option{WithoutPinger()}, opts1...)...).(wireFormatConnection)
+	c1.Start()
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+	return c0, c1
+}
+
+// withoutCapability makes the connection not advertise the capability, as
+// if it were an older version.
+func withoutCapability(capability Capabilities) Option {
+	return func(c *rawConnection) {
+		c.capabilities &^= capability
+	}
+}
+
 type fakeRequestResponse struct {
 	data []byte
 }
@@ -0,0 +1,65 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"sync"
+)
+
+// CompressionStatistics describes how well the messages of one type sent
+// on a connection compressed. Byte counts are of message contents, not
+// message headers: Uncompressed is what the messages marshalled to, and
+// Compressed what was sent for them, which is the same for messages that
+// were sent uncompressed, e.g. as too small to compress or as data found
+// to be incompressible.
+type CompressionStatistics struct {
+	Messages     int64
+	Uncompressed int64
+	Compressed   int64
+}
+
+// Ratio returns the compressed size as a fraction of the uncompressed one,
+// where lower is better, or one if nothing was sent.
+func (s CompressionStatistics) Ratio() float64 {
+	if s.Uncompressed == 0 {
+		return 1
+	}
+	return float64(s.Compressed) / float64(s.Uncompressed)
+}
+
+// compressionStatistics keeps CompressionStatistics per message type.
+type compressionStatistics struct {
+	mut   sync.Mutex
+	types map[MessageType]*CompressionStatistics
+}
+
+func newCompressionStatistics() *compressionStatistics {
+	return &compressionStatistics{
+		types: make(map[MessageType]*CompressionStatistics),
+	}
+}
+
+// outMessage records an outgoing message of the given type, which
+// marshalled to uncompressed bytes and was sent as compressed bytes.
+func (s *compressionStatistics) outMessage(msgType MessageType, uncompressed, compressed int) {
+	s.mut.Lock()
+	cs, ok := s.types[msgType]
+	if !ok {
+		cs = new(CompressionStatistics)
+		s.types[msgType] = cs
+	}
+	cs.Messages++
+	cs.Uncompressed += int64(uncompressed)
+	cs.Compressed += int64(compressed)
+	s.mut.Unlock()
+}
+
+func (s *compressionStatistics) snapshot() map[MessageType]CompressionStatistics {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	res := make(map[MessageType]CompressionStatistics, len(s.types))
+	for msgType, cs := range s.types {
+		res[msgType] = *cs
+	}
+	return res
+}
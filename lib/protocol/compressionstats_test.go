@@ -0,0 +1,96 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/rand"
+)
+
+func TestCompressionStatistics(t *testing.T) {
+	for _, streams := range []bool{true, false} {
+		t.Run(fmt.Sprintf("streams=%v", streams), func(t *testing.T) {
+			testCompressionStatistics(t, streams)
+		})
+	}
+}
+
+func testCompressionStatistics(t *testing.T, streams bool) {
+	// Random data, which doesn't compress.
+	data := make([]byte, 64<<10)
+	if _, err := io.ReadFull(rand.Reader, data); err != nil {
+		t.Fatal(err)
+	}
+	m0 := newTestModel()
+	m0.data = data
+	received := make(chan struct{}, 1)
+	m1 := newTestModel()
+	m1.data = []byte("abc")
+	m1.indexFn = func(DeviceID, string, []FileInfo) {
+		received <- struct{}{}
+	}
+	var opts1 []Option
+	if !streams {
+		opts1 = append(opts1, withoutCapability(CapabilityStreams))
+	}
+	c0, c1 := newTestConnections(t, m0, m1, CompressAlways, []Option{WithCompressionStatistics()}, opts1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// An index of similar directories, which compresses well, a response of the
+	// random data, and a request too small to compress.
+	files := make([]FileInfo, 100)
+	for i := range files {
+		files[i] = FileInfo{Name: fmt.Sprintf("some/longish/directory/dir%d", i), Type: FileInfoTypeDirectory, ModifiedS: 1600000000}
+	}
+	if err := c0.Index(ctx, "default", files); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for index")
+	}
+	if _, err := c1.Request(ctx, "default", "foo", 0, len(data), nil, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c0.Request(ctx, "default", "foo", 0, 3, nil, 0, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The statistics are updated once a message has been written, which
+	// may be after the other side has read it.
+	var stats map[MessageType]CompressionStatistics
+	for {
+		stats = c0.Statistics().Compression
+		if stats[messageTypeIndex].Messages > 0 && stats[messageTypeResponse].Messages > 0 && stats[messageTypeRequest].Messages > 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Timed out waiting for statistics, got %v", stats)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if idx := stats[messageTypeIndex]; idx.Messages != 1 || idx.Ratio() > 0.5 {
+		t.Errorf("Index compressed as %+v, expected better", idx)
+	}
+	if res := stats[messageTypeResponse]; res.Messages != 1 || res.Uncompressed < int64(len(data)) || res.Ratio() < 0.95 {
+		t.Errorf("Response compressed as %+v, expected no better", res)
+	}
+	if req := stats[messageTypeRequest]; req.Messages != 1 || req.Uncompressed == 0 || req.Compressed != req.Uncompressed {
+		t.Errorf("Request compressed as %+v, expected it uncompressed", req)
+	}
+	if _, ok := stats[messageTypePing]; ok {
+		t.Error("Got statistics for pings, none of which were sent")
+	}
+	if stats := c1.Statistics().Compression; stats != nil {
+		t.Errorf("Got compression statistics %v without enabling them", stats)
+	}
+}
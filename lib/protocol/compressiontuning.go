@@ -0,0 +1,157 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Compression auto tuning picks the compression for outgoing messages
+// based on the speed of the link, as measured by the number of bytes
+// written per second spent waiting for the underlying writer. Time spent
+// compressing isn't counted, and neither is time spent with nothing to
+// send, so an idle connection doesn't look like a slow one.
+//
+// Slow links compress everything, as the time spent compressing is small
+// compared to the time saved sending. Fast links compress nothing, as
+// there compressing would be the bottleneck. Links in between compress
+// metadata only. To avoid thrashing, a level is kept until the speed is
+// clearly past a threshold, by compressionTuneMargin, and for
+// compressionTuneSamples intervals in a row.
+const (
+	compressionTuneInterval = 10 * time.Second
+	compressionTuneSamples  = 3
+	compressionTuneMargin   = 0.25
+	compressionTuneMinBytes = 256 << 10 // less written in an interval is too little to judge by
+	slowLinkBytesPerSec     = 1 << 20   // compress everything below this
+	fastLinkBytesPerSec     = 32 << 20  // compress nothing above this
+)
+
+type compressionTuner struct {
+	busy   int64 // nanoseconds spent writing (atomic, must remain 64-bit aligned)
+	mut    sync.Mutex
+	pinned bool // set by SetCompression
+
+	// Only used by the tuning goroutine
+	lastBytes int64
+	lastBusy  int64
+	candidate Compression
+	streak    int
+}
+
+// timedWriter adds the time spent in calls to Write and Flush to busy.
+type timedWriter struct {
+	io.Writer
+	busy *int64 // nanoseconds (atomic)
+}
+
+func (w *timedWriter) Write(bs []byte) (int, error) {
+	t0 := time.Now()
+	n, err := w.Writer.Write(bs)
+	atomic.AddInt64(w.busy, int64(time.Since(t0)))
+	return n, err
+}
+
+func (w *timedWriter) Flush() error {
+	f, ok := w.Writer.(flusher)
+	if !ok {
+		return nil
+	}
+	t0 := time.Now()
+	err := f.Flush()
+	atomic.AddInt64(w.busy, int64(time.Since(t0)))
+	return err
+}
+
+// sample takes the byte total written so far and returns the compression
+// to switch to, if any.
+func (t *compressionTuner) sample(totalBytes int64, cur Compression) (Compression, bool) {
+	busy := atomic.LoadInt64(&t.busy)
+	bytes, elapsed := totalBytes-t.lastBytes, busy-t.lastBusy
+	t.lastBytes, t.lastBusy = totalBytes, busy
+	if bytes < compressionTuneMinBytes || elapsed <= 0 {
+		// Not enough data to say anything; keep the streak, if any, as
+		// it is.
+		return cur, false
+	}
+
+	rate := float64(bytes) / time.Duration(elapsed).Seconds()
+	next := tunedCompression(cur, rate)
+	if next == cur {
+		t.streak = 0
+		return cur, false
+	}
+	if next != t.candidate {
+		t.candidate, t.streak = next, 0
+	}
+	t.streak++
+	if t.streak < compressionTuneSamples {
+		return cur, false
+	}
+	t.streak = 0
+	return next, true
+}
+
+// tunedCompression returns the compression suitable for a link speed of
+// rate bytes per second, when currently using cur.
+func tunedCompression(cur Compression, rate float64) Compression {
+	lo, hi := 1-compressionTuneMargin, 1+compressionTuneMargin
+	switch {
+	case rate < slowLinkBytesPerSec*lo:
+		return CompressAlways
+	case rate > fastLinkBytesPerSec*hi:
+		return CompressNever
+	case rate > slowLinkBytesPerSec*hi && rate < fastLinkBytesPerSec*lo:
+		return CompressMetadata
+	case rate <= slowLinkBytesPerSec*hi:
+		// Close to the slow threshold, where either side is fine, but
+		// not no compression at all.
+		if cur == CompressNever {
+			return CompressMetadata
+		}
+	default:
+		// Close to the fast threshold, where either side is fine, but
+		// not compressing everything.
+		if cur == CompressAlways {
+			return CompressMetadata
+		}
+	}
+	return cur
+}
+
+// compressionTuning periodically adjusts the compression to the link speed,
+// until the connection is closed or the compression is pinned.
+func (c *rawConnection) compressionTuning() {
+	t := c.compressionTuner
+	t.mut.Lock()
+	pinned := t.pinned
+	t.lastBytes = c.cw.Tot()
+	t.mut.Unlock()
+	if pinned {
+		return
+	}
+
+	ticker := time.NewTicker(compressionTuneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.mut.Lock()
+			if t.pinned {
+				t.mut.Unlock()
+				return
+			}
+			cur := Compression(atomic.LoadInt32(&c.compression))
+			if next, ok := t.sample(c.cw.Tot(), cur); ok {
+				atomic.StoreInt32(&c.compression, int32(next))
+				l.Debugf("auto tuned compression for %s to %v", c.id, next)
+			}
+			t.mut.Unlock()
+		case <-c.closed:
+			return
+		}
+	}
+}
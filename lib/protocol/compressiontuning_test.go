@@ -0,0 +1,108 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/testutils"
+)
+
+func TestTunedCompression(t *testing.T) {
+	cases := []struct {
+		cur  Compression
+		rate float64
+		exp  Compression
+	}{
+		// Clearly slow, medium or fast
+		{CompressMetadata, 100 << 10, CompressAlways},
+		{CompressNever, 100 << 10, CompressAlways},
+		{CompressAlways, 4 << 20, CompressMetadata},
+		{CompressNever, 4 << 20, CompressMetadata},
+		{CompressAlways, 100 << 20, CompressNever},
+		{CompressMetadata, 100 << 20, CompressNever},
+		// Close to the slow threshold
+		{CompressAlways, 1.1 * slowLinkBytesPerSec, CompressAlways},
+		{CompressMetadata, 0.9 * slowLinkBytesPerSec, CompressMetadata},
+		{CompressNever, 0.9 * slowLinkBytesPerSec, CompressMetadata},
+		// Close to the fast threshold
+		{CompressNever, 0.9 * fastLinkBytesPerSec, CompressNever},
+		{CompressMetadata, 1.1 * fastLinkBytesPerSec, CompressMetadata},
+		{CompressAlways, 1.1 * fastLinkBytesPerSec, CompressMetadata},
+	}
+	for _, tc := range cases {
+		if res := tunedCompression(tc.cur, tc.rate); res != tc.exp {
+			t.Errorf("tunedCompression(%v, %v) == %v, expected %v", tc.cur, tc.rate, res, tc.exp)
+		}
+	}
+}
+
+func TestCompressionTunerSamples(t *testing.T) {
+	var tuner compressionTuner
+	var total int64
+	// sample adds a second of writing at the given rate.
+	sample := func(rate int64, cur Compression) (Compression, bool) {
+		total += rate
+		tuner.busy += int64(time.Second)
+		return tuner.sample(total, cur)
+	}
+
+	cur := CompressMetadata
+	for i := 1; i < compressionTuneSamples; i++ {
+		if _, ok := sample(100<<20, cur); ok {
+			t.Fatalf("Switched after %d samples", i)
+		}
+	}
+	// Too little data doesn't count either way.
+	if _, ok := sample(1<<10, cur); ok {
+		t.Fatal("Switched on too little data")
+	}
+	next, ok := sample(100<<20, cur)
+	if !ok || next != CompressNever {
+		t.Fatalf("Got %v, %v, expected to switch to never", next, ok)
+	}
+	cur = next
+
+	// A sample that disagrees starts over.
+	for i := 0; i < 2*compressionTuneSamples; i++ {
+		rate := int64(100 << 10)
+		if i%2 == 1 {
+			rate = 100 << 20
+		}
+		if next, ok := sample(rate, cur); ok {
+			t.Fatalf("Switched to %v on alternating samples", next)
+		}
+	}
+}
+
+func TestCompressionAutoTuningPinned(t *testing.T) {
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, ioutil.Discard, newTestModel(), "name", CompressAlways, WithCompressionAutoTuning()).(wireFormatConnection).rawConnection
+	if comp := c.Compression(); comp != CompressAlways {
+		t.Fatalf("Initial compression %v, expected always", comp)
+	}
+	if c.compressionTuner.pinned {
+		t.Fatal("Pinned before SetCompression")
+	}
+
+	c.SetCompression(CompressMetadata)
+	if !c.compressionTuner.pinned {
+		t.Error("Not pinned after SetCompression")
+	}
+	if comp := c.Compression(); comp != CompressMetadata {
+		t.Errorf("Compression %v after SetCompression, expected metadata", comp)
+	}
+
+	// A pinned tuner returns right away.
+	done := make(chan struct{})
+	go func() {
+		c.compressionTuning()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Tuning didn't stop after pinning")
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+)
+
+type presentHashKey struct{}
+
+// ContextWithPresentHash returns a context that makes requests made with it
+// conditional: when the requested data has the given SHA-256 hash, i.e. is
+// the data we already have, the request fails with ErrNotModified instead
+// of the data being sent again. The hash is only sent to devices that
+// support it; others always send the data.
+func ContextWithPresentHash(ctx context.Context, hash []byte) context.Context {
+	return context.WithValue(ctx, presentHashKey{}, hash)
+}
+
+func presentHash(ctx context.Context) []byte {
+	hash, _ := ctx.Value(presentHashKey{}).([]byte)
+	return hash
+}
+
+func hashMatches(data, hash []byte) bool {
+	sum := sha256.Sum256(data)
+	return bytes.Equal(sum[:], hash)
+}
@@ -0,0 +1,46 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"testing"
+	"time"
+)
+
+func TestConditionalRequest(t *testing.T) {
+	for _, supported := range []bool{true, false} {
+		m0 := newTestModel()
+		m0.data = []byte("the data")
+		var opts0 []Option
+		if !supported {
+			opts0 = append(opts0, withoutCapability(CapabilityConditionalRequest))
+		}
+		_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, opts0, nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		if err := c1.WaitHandshake(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		matching := sha256.Sum256(m0.data)
+		buf, err := c1.Request(ContextWithPresentHash(ctx, matching[:]), "default", "foo", 0, len(m0.data), nil, 0, false)
+		if supported {
+			if err != ErrNotModified {
+				t.Errorf("Expected ErrNotModified for matching hash, got %v", err)
+			}
+		} else if err != nil || !bytes.Equal(buf, m0.data) {
+			t.Errorf("Expected data from device without support, got %q, %v", buf, err)
+		}
+
+		other := sha256.Sum256([]byte("other data"))
+		buf, err = c1.Request(ContextWithPresentHash(ctx, other[:]), "default", "foo", 0, len(m0.data), nil, 0, false)
+		if err != nil || !bytes.Equal(buf, m0.data) {
+			t.Errorf("Expected data for other hash, got %q, %v", buf, err)
+		}
+
+		cancel()
+	}
+}
@@ -9,3 +9,10 @@ import (
 var (
 	l = logger.DefaultLogger.NewFacility("protocol", "The BEP protocol")
 )
+
+// shouldDebug tells whether debug logging is enabled, for skipping the
+// arguments of debug messages on hot paths, which would otherwise be
+// allocated even when not logged.
+func shouldDebug() bool {
+	return l.ShouldDebug("protocol")
+}
@@ -0,0 +1,41 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+// DiffIndex returns the files that need to be sent in an index update to
+// bring a peer with the previous index up to date with the current one.
+// Files are matched by name, which must be unique within each index. A file
+// is considered changed when it is not equivalent (as by IsEquivalent, with
+// an exact modification time comparison) to the previous file of the same
+// name, i.e. when its type, flags, permissions, size, modification time,
+// block hashes or symlink target differ. Added and changed files are
+// returned in the order of the current index, removed files in the order of
+// the previous one. A rename shows up as the removal of the old name and the
+// addition of the new one.
+func DiffIndex(prev, cur []FileInfo) (added, changed, removed []FileInfo) {
+	prevByName := make(map[string]int, len(prev))
+	for i, f := range prev {
+		prevByName[f.Name] = i
+	}
+
+	seen := make(map[string]struct{}, len(cur))
+	for _, f := range cur {
+		seen[f.Name] = struct{}{}
+		i, ok := prevByName[f.Name]
+		if !ok {
+			added = append(added, f)
+			continue
+		}
+		if !prev[i].IsEquivalent(f, 0) {
+			changed = append(changed, f)
+		}
+	}
+
+	for _, f := range prev {
+		if _, ok := seen[f.Name]; !ok {
+			removed = append(removed, f)
+		}
+	}
+
+	return added, changed, removed
+}
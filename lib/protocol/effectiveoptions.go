@@ -0,0 +1,87 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"time"
+)
+
+// EffectiveOptions describes what is in force for a connection, as set by
+// options and agreed on with the other side, as returned by
+// EffectiveOptions. Limits are zero when there is none. There is no
+// protocol version to tell; what each side supports is told by its
+// capabilities instead.
+type EffectiveOptions struct {
+	// Compression is the current compression of outgoing messages, which
+	// may change over time with WithCompressionAutoTuning.
+	Compression Compression
+	// Capabilities are what we advertise, PeerCapabilities what the other
+	// side advertises, and thus zero before the handshake.
+	Capabilities     Capabilities
+	PeerCapabilities Capabilities
+	// HandshakeDone is true once the other side's cluster config has been
+	// processed; the values agreed on with the other side are zero until
+	// then.
+	HandshakeDone bool
+	// BlockSize and MaxIndexFiles are agreed on with the other side, as
+	// returned by the methods of the same name.
+	BlockSize     int
+	MaxIndexFiles int
+	// IdlePingInterval is agreed on with the other side, and zero unless
+	// both support idle mode, see SetIdle.
+	IdlePingInterval time.Duration
+
+	MaxRequestSize          int
+	MaxConcurrentRequests   int
+	MaxPendingResponseBytes int
+	MaxPendingPushBytes     int
+	HandshakeTimeout        time.Duration
+	ReadBufferSize          int
+	WriteBufferSize         int
+	SmallMessageSize        int
+	Checksums               bool
+	LowLatency              bool
+	Pinger                  bool
+	PingAttempts            int
+	PingBackoff             time.Duration
+	RequestAttempts         int
+	RequestBackoff          time.Duration
+}
+
+// EffectiveOptions returns a snapshot of what is in force for the
+// connection, for diagnostics. It is safe to call at any time.
+func (c *rawConnection) EffectiveOptions() EffectiveOptions {
+	opts := EffectiveOptions{
+		Compression:      c.Compression(),
+		Capabilities:     c.capabilities,
+		MaxRequestSize:   c.maxRequestSize,
+		HandshakeTimeout: c.handshakeTimeout,
+		ReadBufferSize:   c.readBufferSize,
+		WriteBufferSize:  c.writeBufferSize,
+		SmallMessageSize: c.smallMessageSize,
+		Checksums:        c.checksums,
+		LowLatency:       c.lowLatency,
+		Pinger:           !c.noPinger,
+		PingAttempts:     c.pingAttempts,
+		PingBackoff:      c.pingBackoff,
+		RequestAttempts:  c.requestAttempts,
+		RequestBackoff:   c.requestBackoff,
+	}
+	select {
+	case <-c.handshakeDone:
+		opts.HandshakeDone = true
+		opts.PeerCapabilities = c.peerCapabilities
+		opts.BlockSize = c.agreedBlockSize
+		opts.MaxIndexFiles = c.agreedMaxIndexFiles
+		opts.IdlePingInterval = c.agreedIdlePingInterval
+	default:
+	}
+	c.requests.mut.Lock()
+	opts.MaxConcurrentRequests = c.requests.max
+	c.requests.mut.Unlock()
+	if c.responseBytes != nil {
+		opts.MaxPendingResponseBytes = c.responseBytes.max
+	}
+	opts.MaxPendingPushBytes = c.pushBytes.max
+	return opts
+}
@@ -0,0 +1,54 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestEffectiveOptions(t *testing.T) {
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	defer ar.Close()
+	defer br.Close()
+
+	c0 := NewConnection(c1ID, ar, bw, newTestModel(), "c0", CompressMetadata, WithoutPinger(), WithChecksums(), WithMaxConcurrentRequests(3), WithPreferredBlockSize(1<<20), WithMaxIndexFiles(1000)).(wireFormatConnection)
+	c1 := NewConnection(c0ID, br, aw, newTestModel(), "c1", CompressMetadata, WithoutPinger(), WithPreferredBlockSize(256<<10), WithMaxIndexFiles(500)).(wireFormatConnection)
+
+	opts := c0.EffectiveOptions()
+	if opts.HandshakeDone || opts.PeerCapabilities != 0 || opts.BlockSize != 0 || opts.MaxIndexFiles != 0 {
+		t.Errorf("Negotiated values before the handshake: %+v", opts)
+	}
+	if opts.Pinger || !opts.Checksums || opts.MaxConcurrentRequests != 3 || opts.MaxRequestSize != MaxBlockSize {
+		t.Errorf("Options not in effect: %+v", opts)
+	}
+	if opts.Compression != CompressMetadata || !opts.Capabilities.Has(CapabilityPong) {
+		t.Errorf("Unexpected compression or capabilities: %+v", opts)
+	}
+
+	c0.Start()
+	c1.Start()
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c0.WaitHandshake(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	opts = c0.EffectiveOptions()
+	if !opts.HandshakeDone || opts.PeerCapabilities != c1.EffectiveOptions().Capabilities {
+		t.Errorf("Peer capabilities not in effect after the handshake: %+v", opts)
+	}
+	if opts.BlockSize != 256<<10 || opts.MaxIndexFiles != 500 {
+		t.Errorf("Expected the lower block size and index limit to be agreed on, got %+v", opts)
+	}
+
+	c0.SetMaxConcurrentRequests(5)
+	if n := c0.EffectiveOptions().MaxConcurrentRequests; n != 5 {
+		t.Errorf("Max concurrent requests %d after setting it to 5", n)
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrDecryptionFailed is the error the connection is closed with when data
+// from the other side can't be decrypted with the pre-shared key, i.e. when
+// the keys differ or the data was tampered with.
+var ErrDecryptionFailed = errors.New("decryption failed (wrong key or corrupted data)")
+
+const (
+	// MinPresharedKeyLen is the shortest key accepted by
+	// WithPresharedKey.
+	MinPresharedKeyLen = 16
+
+	saltLen = 16
+
+	// maxRecordSize is the most plaintext in a single encrypted record.
+	// Larger writes are split into several records.
+	maxRecordSize = 64 << KiB
+)
+
+// Each side starts its stream with a random salt, and derives the key for
+// each direction from the pre-shared key and both salts, the sender's
+// first. The two directions thus use different keys, the nonce can be a
+// simple counter, and a recorded stream doesn't decrypt in a later session,
+// where the other side picked another salt. After the salt follow records:
+// a four byte big endian length, then that many bytes of AES-GCM sealed
+// data. The top bit of the length marks the last record, which is empty
+// and says that the stream ended where the sender meant it to. The length,
+// including that bit, is authenticated with the record.
+
+// finalRecord is the bit of the record length marking the last record.
+const finalRecord = 1 << 31
+
+// newEncryptedStreams returns the reader and writer encrypting and
+// decrypting the given ones with the pre-shared key. If the key is
+// unusable, reading and writing fail with an error saying so. The writer
+// reads the salt of the other side when the reader hasn't yet, before
+// sending the first record; the reader returns io.EOF only after the last
+// record, see encryptedWriter.Close.
+func newEncryptedStreams(key []byte, r io.Reader, w io.Writer) (io.Reader, *encryptedWriter) {
+	if len(key) < MinPresharedKeyLen {
+		err := errors.Errorf("pre-shared key shorter than %d bytes", MinPresharedKeyLen)
+		return failingReader{err}, &encryptedWriter{err: err}
+	}
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		err = errors.Wrap(err, "generating salt")
+		return failingReader{err}, &encryptedWriter{err: err}
+	}
+	return newSaltedStreams(key, salt, r, w)
+}
+
+// newSaltedStreams is newEncryptedStreams with the given salt of our own.
+func newSaltedStreams(key, salt []byte, r io.Reader, w io.Writer) (*encryptedReader, *encryptedWriter) {
+	s := &encryptedSession{psk: key, ownSalt: salt, r: r}
+	return &encryptedReader{r: r, s: s}, &encryptedWriter{w: w, s: s}
+}
+
+// encryptedSession is what the reader and writer of a connection share:
+// the salts, of which the other side's is read by whichever needs it
+// first.
+type encryptedSession struct {
+	psk     []byte
+	ownSalt []byte
+	r       io.Reader
+
+	mut      sync.Mutex
+	peerSalt []byte
+	err      error // sticky
+}
+
+// cipher returns the cipher for the direction from the other side to
+// us (incoming) or from us to the other side, reading the salt of the other
+// side if that hasn't been done yet.
+func (s *encryptedSession) cipher(incoming bool) (cipher.AEAD, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.peerSalt == nil && s.err == nil {
+		salt := make([]byte, saltLen)
+		if _, err := io.ReadFull(s.r, salt); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			s.err = err
+		} else if bytes.Equal(salt, s.ownSalt) {
+			// Our own stream, reflected back at us.
+			s.err = ErrDecryptionFailed
+		} else {
+			s.peerSalt = salt
+		}
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	if incoming {
+		return newRecordCipher(s.psk, s.peerSalt, s.ownSalt)
+	}
+	return newRecordCipher(s.psk, s.ownSalt, s.peerSalt)
+}
+
+// newRecordCipher returns the cipher for the direction from the side with
+// the first salt to the side with the second.
+func newRecordCipher(psk, from, to []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, psk)
+	mac.Write(from)
+	mac.Write(to)
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// recordNonce returns the nonce for the record with the given sequence
+// number.
+func recordNonce(nonce []byte, seq uint64) []byte {
+	for i := range nonce[:len(nonce)-8] {
+		nonce[i] = 0
+	}
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], seq)
+	return nonce
+}
+
+type encryptedWriter struct {
+	w     io.Writer
+	s     *encryptedSession
+	aead  cipher.AEAD // nil until our salt has been sent
+	seq   uint64
+	nonce [12]byte
+	buf   []byte
+	err   error // sticky
+}
+
+func (w *encryptedWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > maxRecordSize {
+			chunk = chunk[:maxRecordSize]
+		}
+		if err := w.writeRecord(chunk, 0); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
+
+// Close sends the last record, telling the other side that the stream
+// ended as meant to, and flushes the underlying writer. Nothing can be
+// written after.
+func (w *encryptedWriter) Close() error {
+	if err := w.writeRecord(nil, finalRecord); err != nil {
+		return err
+	}
+	w.err = io.ErrClosedPipe
+	return w.Flush()
+}
+
+func (w *encryptedWriter) writeRecord(plain []byte, flags uint32) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.aead == nil {
+		if _, err := w.w.Write(w.s.ownSalt); err != nil {
+			w.err = err
+			return err
+		}
+		w.aead, w.err = w.s.cipher(false)
+		if w.err != nil {
+			return w.err
+		}
+	}
+
+	w.buf = append(w.buf[:0], 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(w.buf, uint32(len(plain)+w.aead.Overhead())|flags)
+	w.buf = w.aead.Seal(w.buf, recordNonce(w.nonce[:], w.seq), plain, w.buf[:4])
+	w.seq++
+
+	if _, err := w.w.Write(w.buf); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}
+
+// Flush flushes the underlying writer, if it can be.
+func (w *encryptedWriter) Flush() error {
+	if f, ok := w.w.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+type encryptedReader struct {
+	r     io.Reader
+	s     *encryptedSession
+	aead  cipher.AEAD // nil until the salt has been read
+	seq   uint64
+	nonce [12]byte
+	buf   []byte
+	plain []byte // decrypted but not yet returned
+	err   error  // sticky; io.EOF after the last record
+}
+
+func (r *encryptedReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.readRecord()
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *encryptedReader) readRecord() error {
+	if r.aead == nil {
+		aead, err := r.s.cipher(true)
+		if err != nil {
+			return err
+		}
+		r.aead = aead
+	}
+
+	var hdr [4]byte
+	if _, err := io.ReadFull(r.r, hdr[:]); err != nil {
+		if err == io.EOF {
+			// The stream must end with the last record.
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	v := binary.BigEndian.Uint32(hdr[:])
+	l := int(v &^ finalRecord)
+	if l < r.aead.Overhead() || l > maxRecordSize+r.aead.Overhead() {
+		return ErrDecryptionFailed
+	}
+	if cap(r.buf) < l {
+		r.buf = make([]byte, l)
+	}
+	r.buf = r.buf[:l]
+	if _, err := io.ReadFull(r.r, r.buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	plain, err := r.aead.Open(r.buf[:0], recordNonce(r.nonce[:], r.seq), r.buf, hdr[:])
+	if err != nil {
+		return ErrDecryptionFailed
+	}
+	r.seq++
+	if v&finalRecord != 0 {
+		if len(plain) != 0 {
+			return ErrDecryptionFailed
+		}
+		return io.EOF
+	}
+	r.plain = plain
+	return nil
+}
+
+type failingReader struct{ err error }
+
+func (r failingReader) Read([]byte) (int, error) { return 0, r.err }
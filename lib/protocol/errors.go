@@ -4,6 +4,8 @@ package protocol
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 var (
@@ -11,20 +13,36 @@ var (
 	ErrGeneric    = errors.New("generic error")
 	ErrNoSuchFile = errors.New("no such file")
 	ErrInvalid    = errors.New("file is invalid")
+
+	ErrRequestTooLarge = errors.New("request too large")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrNotReady        = errors.New("not ready")
+	ErrNotModified     = errors.New("not modified")
+	ErrExpired         = errors.New("request expired")
 )
 
 var lookupError = map[ErrorCode]error{
-	ErrorCodeNoError:     ErrNoError,
-	ErrorCodeGeneric:     ErrGeneric,
-	ErrorCodeNoSuchFile:  ErrNoSuchFile,
-	ErrorCodeInvalidFile: ErrInvalid,
+	ErrorCodeNoError:         ErrNoError,
+	ErrorCodeGeneric:         ErrGeneric,
+	ErrorCodeNoSuchFile:      ErrNoSuchFile,
+	ErrorCodeInvalidFile:     ErrInvalid,
+	ErrorCodeRequestTooLarge: ErrRequestTooLarge,
+	ErrorCodeUnauthorized:    ErrUnauthorized,
+	ErrorCodeNotReady:        ErrNotReady,
+	ErrorCodeNotModified:     ErrNotModified,
+	ErrorCodeExpired:         ErrExpired,
 }
 
 var lookupCode = map[error]ErrorCode{
-	ErrNoError:    ErrorCodeNoError,
-	ErrGeneric:    ErrorCodeGeneric,
-	ErrNoSuchFile: ErrorCodeNoSuchFile,
-	ErrInvalid:    ErrorCodeInvalidFile,
+	ErrNoError:         ErrorCodeNoError,
+	ErrGeneric:         ErrorCodeGeneric,
+	ErrNoSuchFile:      ErrorCodeNoSuchFile,
+	ErrInvalid:         ErrorCodeInvalidFile,
+	ErrRequestTooLarge: ErrorCodeRequestTooLarge,
+	ErrUnauthorized:    ErrorCodeUnauthorized,
+	ErrNotReady:        ErrorCodeNotReady,
+	ErrNotModified:     ErrorCodeNotModified,
+	ErrExpired:         ErrorCodeExpired,
 }
 
 func codeToError(code ErrorCode) error {
@@ -42,3 +60,75 @@ func errorToCode(err error) ErrorCode {
 	}
 	return code
 }
+
+// A NotReadyError can be returned by the model when it can't serve a
+// request yet, e.g. because the folder is still being scanned. The
+// requester gets a NotReadyError back, and should retry the request after
+// RetryAfter, or after a backoff of its own choosing if that is zero.
+// errors.Is(err, ErrNotReady) is true for a NotReadyError.
+type NotReadyError struct {
+	RetryAfter time.Duration
+}
+
+func (e *NotReadyError) Error() string {
+	if e.RetryAfter <= 0 {
+		return ErrNotReady.Error()
+	}
+	return fmt.Sprintf("%v, retry after %v", ErrNotReady, e.RetryAfter)
+}
+
+func (e *NotReadyError) Is(target error) bool {
+	return target == ErrNotReady
+}
+
+// A TemporaryError can be returned by the model for a request that failed
+// in a way that may go away when retried, e.g. because the disk is busy.
+// Such requests are retried as configured by WithRequestRetries before the
+// wrapped error is sent to the requester. Any other error with a Temporary
+// method returning true is treated the same.
+type TemporaryError struct {
+	Err error
+}
+
+func (e *TemporaryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TemporaryError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TemporaryError) Temporary() bool {
+	return true
+}
+
+func isTemporary(err error) bool {
+	var te interface{ Temporary() bool }
+	return errors.As(err, &te) && te.Temporary()
+}
+
+// errorResponse returns the response for a request that failed with err.
+func errorResponse(id int32, err error) *Response {
+	var te *TemporaryError
+	if errors.As(err, &te) {
+		err = te.Err
+	}
+	resp := &Response{
+		ID:   id,
+		Code: errorToCode(err),
+	}
+	var nr *NotReadyError
+	if errors.As(err, &nr) {
+		resp.Code = ErrorCodeNotReady
+		resp.RetryAfterMs = int64(nr.RetryAfter / time.Millisecond)
+	}
+	return resp
+}
+
+// responseError returns the error carried by the response, if any.
+func responseError(resp Response) error {
+	if resp.Code == ErrorCodeNotReady {
+		return &NotReadyError{RetryAfter: time.Duration(resp.RetryAfterMs) * time.Millisecond}
+	}
+	return codeToError(resp.Code)
+}
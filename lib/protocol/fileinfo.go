@@ -0,0 +1,94 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+)
+
+// A FileInfoModel answers requests for the current metadata of single
+// files. A Model passed to NewConnection that also implements
+// FileInfoModel makes the connection advertise CapabilityFileInfo.
+type FileInfoModel interface {
+	// The peer device asked for the current FileInfo of the file with the
+	// given name, in wire format, in the folder. The returned bool is false
+	// if the file is unknown; a deleted file is returned as such.
+	FileInfo(deviceID DeviceID, folder, name string) (FileInfo, bool)
+}
+
+// FileInfo asks the other side for the current metadata of the file with
+// the given name in the folder, as it would be sent in an index, without
+// waiting for an index exchange. It returns ErrNoSuchFile if the other side
+// doesn't know about the file; a file it knows to be deleted is returned
+// with Deleted set. FileInfo waits for the handshake to complete, and
+// returns ErrUnsupported if the other side doesn't answer such requests.
+func (c *rawConnection) FileInfo(ctx context.Context, folder, name string) (FileInfo, error) {
+	if err := checkFilename(name); err != nil {
+		return FileInfo{}, err
+	}
+	if err := c.WaitHandshake(ctx); err != nil {
+		return FileInfo{}, err
+	}
+	if !c.peerCapabilities.Has(CapabilityFileInfo) {
+		return FileInfo{}, ErrUnsupported
+	}
+
+	id, rc, err := c.newAwaiting(messageTypeFileInfoRequest)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	ok := c.send(ctx, &FileInfoRequest{
+		ID:     id,
+		Folder: folder,
+		Name:   name,
+	}, nil)
+	if !ok {
+		c.forgetAwaiting(id)
+		return FileInfo{}, ErrClosed
+	}
+
+	select {
+	case res, ok := <-rc:
+		if !ok {
+			return FileInfo{}, ErrClosed
+		}
+		if res.err != nil {
+			return FileInfo{}, res.err
+		}
+		resp, ok := res.msg.(*FileInfoResponse)
+		if !ok {
+			// The other side answered with the wrong kind of message
+			return FileInfo{}, ErrGeneric
+		}
+		if resp.File.Name != name {
+			return FileInfo{}, fmt.Errorf("protocol error: file info: got %q for %q", resp.File.Name, name)
+		}
+		if err := checkFileInfoConsistency(resp.File); err != nil {
+			return FileInfo{}, fmt.Errorf("protocol error: file info: %q: %v", name, err)
+		}
+		return resp.File, nil
+	case <-ctx.Done():
+		c.forgetAwaiting(id)
+		return FileInfo{}, ctx.Err()
+	}
+}
+
+func (c *rawConnection) handleFileInfoRequest(req FileInfoRequest) {
+	resp := &FileInfoResponse{ID: req.ID}
+	err := ErrUnsupported
+	if c.fileInfoModel != nil {
+		var ok bool
+		resp.File, ok = c.fileInfoModel.FileInfo(c.id, req.Folder, req.Name)
+		err = nil
+		if !ok {
+			resp.File, err = FileInfo{}, ErrNoSuchFile
+		}
+	}
+	resp.Code = errorToCode(err)
+	c.send(context.Background(), resp, nil)
+}
+
+func (c *rawConnection) handleFileInfoResponse(resp FileInfoResponse) error {
+	return c.resolveAwaiting(resp.ID, messageTypeFileInfoResponse, asyncResult{err: codeToError(resp.Code), msg: &resp})
+}
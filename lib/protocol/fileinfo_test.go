@@ -0,0 +1,68 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fileInfoTestModel struct {
+	*TestModel
+	files map[string]FileInfo
+}
+
+func (m *fileInfoTestModel) FileInfo(_ DeviceID, _, name string) (FileInfo, bool) {
+	f, ok := m.files[name]
+	return f, ok
+}
+
+func TestFileInfo(t *testing.T) {
+	files := map[string]FileInfo{
+		"dir/file": {
+			Name:    "dir/file",
+			Type:    FileInfoTypeFile,
+			Size:    3,
+			Version: Vector{}.Update(1),
+			Blocks:  []BlockInfo{{Size: 3, Hash: []byte("hash")}},
+		},
+		"deleted": {
+			Name:    "deleted",
+			Type:    FileInfoTypeFile,
+			Deleted: true,
+			Version: Vector{}.Update(1).Update(1),
+		},
+	}
+	m0 := &fileInfoTestModel{newTestModel(), files}
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressAlways, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for name, exp := range files {
+		f, err := c1.FileInfo(ctx, "default", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !f.IsEquivalent(exp, 0) || !f.Version.Equal(exp.Version) {
+			t.Errorf("Got %v, expected %v", f, exp)
+		}
+	}
+
+	if _, err := c1.FileInfo(ctx, "default", "missing"); err != ErrNoSuchFile {
+		t.Errorf("Unknown file returned %v, expected %v", err, ErrNoSuchFile)
+	}
+	if _, err := c1.FileInfo(ctx, "default", "../escape"); err == nil {
+		t.Error("Invalid name was accepted")
+	}
+}
+
+func TestFileInfoUnsupported(t *testing.T) {
+	_, c1 := newTestConnections(t, newTestModel(), newTestModel(), CompressAlways, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c1.FileInfo(ctx, "default", "foo"); err != ErrUnsupported {
+		t.Errorf("Expected %v, got %v", ErrUnsupported, err)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"sync"
+)
+
+// FolderStatistics describes the traffic for a single folder on a
+// connection. Byte counts are of uncompressed message contents, including
+// index data, requests and response data, but not message headers.
+// Messages are counted for those carrying a folder, such as index data and
+// requests, and for responses to requests and range requests, which are
+// attributed to the folder of the request. Message headers and the other
+// messages, such as cluster configs, pings and the responses to listing
+// and file info requests, are overhead shared by all folders, only counted
+// in the totals of the connection.
+type FolderStatistics struct {
+	InBytesTotal  int64
+	OutBytesTotal int64
+	InMessages    int64
+	OutMessages   int64
+	InRequests    int64 // requests received from the other side
+	OutRequests   int64 // requests sent to the other side
+}
+
+// folderStatistics keeps FolderStatistics per folder ID.
+type folderStatistics struct {
+	mut     sync.Mutex
+	folders map[string]*FolderStatistics
+}
+
+func newFolderStatistics() *folderStatistics {
+	return &folderStatistics{
+		folders: make(map[string]*FolderStatistics),
+	}
+}
+
+// inMessage records an incoming message, if it pertains to a folder.
+func (s *folderStatistics) inMessage(msg Message) {
+	folder, ok := messageFolder(msg)
+	if !ok {
+		return
+	}
+	_, isReq := msg.(*Request)
+	s.mut.Lock()
+	fs := s.getLocked(folder)
+	fs.InBytesTotal += int64(msg.ProtoSize())
+	fs.InMessages++
+	if isReq {
+		fs.InRequests++
+	}
+	s.mut.Unlock()
+}
+
+// outMessage records an outgoing message, if it pertains to a folder.
+func (s *folderStatistics) outMessage(msg Message) {
+	folder, ok := messageFolder(msg)
+	if !ok {
+		return
+	}
+	_, isReq := msg.(*Request)
+	s.mut.Lock()
+	fs := s.getLocked(folder)
+	fs.OutBytesTotal += int64(msg.ProtoSize())
+	fs.OutMessages++
+	if isReq {
+		fs.OutRequests++
+	}
+	s.mut.Unlock()
+}
+
+// inResponse records a response with the given amount of data received for
+// a request on the given folder, as the response message itself doesn't
+// carry the folder.
+func (s *folderStatistics) inResponse(folder string, size int) {
+	s.mut.Lock()
+	fs := s.getLocked(folder)
+	fs.InBytesTotal += int64(size)
+	fs.InMessages++
+	s.mut.Unlock()
+}
+
+// outResponse records a response with the given amount of data sent for a
+// request on the given folder.
+func (s *folderStatistics) outResponse(folder string, size int) {
+	s.mut.Lock()
+	fs := s.getLocked(folder)
+	fs.OutBytesTotal += int64(size)
+	fs.OutMessages++
+	s.mut.Unlock()
+}
+
+func (s *folderStatistics) getLocked(folder string) *FolderStatistics {
+	fs, ok := s.folders[folder]
+	if !ok {
+		fs = new(FolderStatistics)
+		s.folders[folder] = fs
+	}
+	return fs
+}
+
+func (s *folderStatistics) get(folder string) FolderStatistics {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if fs, ok := s.folders[folder]; ok {
+		return *fs
+	}
+	return FolderStatistics{}
+}
+
+func (s *folderStatistics) snapshot() map[string]FolderStatistics {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	res := make(map[string]FolderStatistics, len(s.folders))
+	for folder, fs := range s.folders {
+		res[folder] = *fs
+	}
+	return res
+}
+
+func messageFolder(msg Message) (string, bool) {
+	switch msg := msg.(type) {
+	case *Index:
+		return msg.Folder, true
+	case *IndexUpdate:
+		return msg.Folder, true
+	case *IndexSummary:
+		return msg.Folder, true
+	case *IndexAck:
+		return msg.Folder, true
+	case *Request:
+		return msg.Folder, true
+	case *DownloadProgress:
+		return msg.Folder, true
+	case *Push:
+		return msg.Folder, true
+	case *ListingRequest:
+		return msg.Folder, true
+	case *FileInfoRequest:
+		return msg.Folder, true
+	case *RangeRequest:
+		return msg.Folder, true
+	}
+	return "", false
+}
+
+// FolderStatistics returns the traffic so far for the folder on the
+// connection, which is all zero unless folder statistics are enabled.
+func (c *rawConnection) FolderStatistics(folder string) FolderStatistics {
+	if c.folderStats == nil {
+		return FolderStatistics{}
+	}
+	return c.folderStats.get(folder)
+}
@@ -0,0 +1,137 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/testutils"
+)
+
+func TestFolderStatistics(t *testing.T) {
+	indexReceived := make(chan struct{})
+	m0 := newTestModel()
+	m0.requestFn = func(_, _ string, size int32, _ int64) (RequestResponse, error) {
+		return &fakeRequestResponse{make([]byte, size)}, nil
+	}
+	m0.indexFn = func(DeviceID, string, []FileInfo) {
+		close(indexReceived)
+	}
+
+	c0, c1 := newTestConnections(t, m0, newTestModel(), CompressAlways, []Option{WithFolderStatistics()}, []Option{WithFolderStatistics()})
+
+	files := []FileInfo{{Name: "foo", Type: FileInfoTypeFile, Size: 128, Blocks: []BlockInfo{{Size: 128, Hash: make([]byte, 32)}}}}
+	idxSize := int64((&Index{Folder: "a", Files: files}).ProtoSize())
+	reqSize := int64((&Request{ID: 0, Folder: "b", Name: "foo", Size: 128}).ProtoSize())
+	ranges := []Range{{Size: 64}, {Offset: 64, Size: 32}}
+	rangeReqSize := int64((&RangeRequest{ID: 1, Folder: "c", Name: "foo", Ranges: ranges}).ProtoSize())
+
+	if err := c1.Index(context.Background(), "a", files); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-indexReceived:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for index")
+	}
+	if _, err := c1.Request(context.Background(), "b", "foo", 0, 128, nil, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c1.RequestRanges(context.Background(), "c", "foo", ranges); err != nil {
+		t.Fatal(err)
+	}
+
+	expected1 := map[string]FolderStatistics{
+		"a": {OutBytesTotal: idxSize, OutMessages: 1},
+		"b": {OutBytesTotal: reqSize, InBytesTotal: 128, OutMessages: 1, InMessages: 1, OutRequests: 1},
+		"c": {OutBytesTotal: rangeReqSize, InBytesTotal: 96, OutMessages: 1, InMessages: 1},
+	}
+	expected0 := map[string]FolderStatistics{
+		"a": {InBytesTotal: idxSize, InMessages: 1},
+		"b": {InBytesTotal: reqSize, OutBytesTotal: 128, InMessages: 1, OutMessages: 1, InRequests: 1},
+		"c": {InBytesTotal: rangeReqSize, OutBytesTotal: 96, InMessages: 1, OutMessages: 1},
+	}
+
+	// The serving side records the response after it has been sent, which
+	// may be after the request returned on the other side.
+	deadline := time.Now().Add(time.Second)
+	for {
+		if folderStatsEqual(c0.Statistics().Folders, expected0) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if got := c1.Statistics().Folders; !folderStatsEqual(got, expected1) {
+		t.Errorf("Requesting side got %v, expected %v", got, expected1)
+	}
+	if got := c0.Statistics().Folders; !folderStatsEqual(got, expected0) {
+		t.Errorf("Serving side got %v, expected %v", got, expected0)
+	}
+	for folder, exp := range expected1 {
+		if got := c1.FolderStatistics(folder); got != exp {
+			t.Errorf("Requesting side got %v for %q, expected %v", got, folder, exp)
+		}
+	}
+	if got := c1.FolderStatistics("unknown"); got != (FolderStatistics{}) {
+		t.Errorf("Got %v for an unknown folder", got)
+	}
+}
+
+func TestFolderStatisticsDisabled(t *testing.T) {
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, newTestModel(), "name", CompressAlways)
+	if stats := c.Statistics(); stats.Folders != nil {
+		t.Errorf("Unexpected folder statistics %v", stats.Folders)
+	}
+}
+
+func folderStatsEqual(a, b map[string]FolderStatistics) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for folder, fs := range a {
+		if b[folder] != fs {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStatisticsBaseline(t *testing.T) {
+	saved := Statistics{
+		At:            time.Now(),
+		InBytesTotal:  1000,
+		OutBytesTotal: 2000,
+		Folders: map[string]FolderStatistics{
+			"default": {InBytesTotal: 100, OutBytesTotal: 200, InRequests: 1, OutRequests: 2},
+		},
+		Latency: 5 * time.Millisecond,
+	}
+
+	bs, err := json.Marshal(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored Statistics
+	if err := json.Unmarshal(bs, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.At.Equal(saved.At) || restored.InBytesTotal != saved.InBytesTotal || restored.OutBytesTotal != saved.OutBytesTotal || restored.Latency != saved.Latency || !folderStatsEqual(restored.Folders, saved.Folders) {
+		t.Fatalf("Statistics %+v became %+v", saved, restored)
+	}
+
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, newTestModel(), "name", CompressAlways, WithStatisticsBaseline(restored), WithFolderStatistics())
+	stats := c.Statistics()
+	if stats.InBytesTotal != 1000 || stats.OutBytesTotal != 2000 {
+		t.Errorf("Unexpected totals %d/%d", stats.InBytesTotal, stats.OutBytesTotal)
+	}
+	if !folderStatsEqual(stats.Folders, saved.Folders) {
+		t.Errorf("Unexpected folder statistics %v", stats.Folders)
+	}
+	if stats.Latency != 0 {
+		t.Errorf("Latency should not have been carried over")
+	}
+}
@@ -0,0 +1,636 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	lz4 "github.com/bkaradzic/go-lz4"
+	"github.com/pkg/errors"
+)
+
+// ErrUnknownMessage is returned by Decoder.Decode for messages of a type we
+// don't know about. The message has been consumed in full, so decoding can
+// continue with the next message.
+var ErrUnknownMessage = errors.New("unknown message")
+
+// Message is any of the BEP messages, e.g. *Index or *Request.
+type Message interface {
+	ProtoSize() int
+	Marshal() ([]byte, error)
+	MarshalTo([]byte) (int, error)
+	Unmarshal([]byte) error
+}
+
+// An Encoder writes framed messages, i.e. a header followed by the possibly
+// compressed message, to an underlying writer. It is not safe for
+// concurrent use.
+type Encoder struct {
+	w           io.Writer
+	compression Compression
+	checksums   bool
+	small       []byte             // for frames of small messages
+	payloads    [numStreams][]byte // for small messages being written in frames, by stream
+	payloadSize int                // of the last message written by Encode, possibly compressed
+}
+
+// NewEncoder returns an Encoder writing to w, compressing messages as
+// given.
+func NewEncoder(w io.Writer, compression Compression) *Encoder {
+	return &Encoder{
+		w:           w,
+		compression: compression,
+	}
+}
+
+// Reset makes the Encoder write to w, keeping its settings, so that it can
+// be reused for another stream.
+func (e *Encoder) Reset(w io.Writer) {
+	e.w = w
+}
+
+// SetCompression sets how messages written from now on are compressed.
+// Every message header says whether the message is compressed, so this can
+// be changed at any time without the decoding side knowing in advance.
+func (e *Encoder) SetCompression(compression Compression) {
+	e.compression = compression
+}
+
+// SetChecksums sets whether a checksum of the uncompressed message is
+// included in the header of messages written from now on.
+func (e *Encoder) SetChecksums(enabled bool) {
+	e.checksums = enabled
+}
+
+// Encode writes a single message, in one call to the underlying writer.
+func (e *Encoder) Encode(msg Message) error {
+	if e.shouldCompress(msg) {
+		return e.encodeCompressed(msg)
+	}
+	return e.encodeUncompressed(msg)
+}
+
+func (e *Encoder) encodeCompressed(msg Message) error {
+	size := msg.ProtoSize()
+	buf := getBuffer(e.small, size)
+	if _, err := msg.MarshalTo(buf); err != nil {
+		return errors.Wrap(err, "marshalling message")
+	}
+
+	compressed, err := lz4Compress(buf)
+	if err != nil {
+		return errors.Wrap(err, "compressing message")
+	}
+
+	hdr := Header{
+		Type:        typeOf(msg),
+		Compression: MessageCompressionLZ4,
+	}
+	if e.checksums {
+		hdr.Checksum = checksum(buf[:size])
+	}
+	hdrSize := hdr.ProtoSize()
+	if hdrSize > 1<<16-1 {
+		panic("impossibly large header")
+	}
+
+	totSize := 2 + hdrSize + 4 + len(compressed)
+	buf = upgradeBuffer(e.small, buf, totSize)
+
+	// Header length
+	binary.BigEndian.PutUint16(buf, uint16(hdrSize))
+	// Header
+	if _, err := hdr.MarshalTo(buf[2:]); err != nil {
+		return errors.Wrap(err, "marshalling header")
+	}
+	// Message length
+	binary.BigEndian.PutUint32(buf[2+hdrSize:], uint32(len(compressed)))
+	// Message
+	copy(buf[2+hdrSize+4:], compressed)
+	e.payloadSize = len(compressed)
+	BufferPool.Put(compressed)
+
+	n, err := e.w.Write(buf)
+	putBuffer(buf)
+
+	if shouldDebug() {
+		l.Debugf("wrote %d bytes on the wire (2 bytes length, %d bytes header, 4 bytes message length, %d bytes message (%d uncompressed)), err=%v", n, hdrSize, len(compressed), size, err)
+	}
+	if err != nil {
+		return errors.Wrap(err, "writing message")
+	}
+	return nil
+}
+
+func (e *Encoder) encodeUncompressed(msg Message) error {
+	size := msg.ProtoSize()
+
+	hdr := Header{
+		Type: typeOf(msg),
+	}
+	if e.checksums {
+		// Placeholder of the right size, filled in once the message is
+		// marshalled.
+		hdr.Checksum = make([]byte, crc32.Size)
+	}
+	hdrSize := hdr.ProtoSize()
+	if hdrSize > 1<<16-1 {
+		panic("impossibly large header")
+	}
+
+	totSize := 2 + hdrSize + 4 + size
+	buf := getBuffer(e.small, totSize)
+
+	// Message
+	if _, err := msg.MarshalTo(buf[2+hdrSize+4:]); err != nil {
+		return errors.Wrap(err, "marshalling message")
+	}
+	if e.checksums {
+		hdr.Checksum = checksum(buf[2+hdrSize+4 : totSize])
+	}
+	// Header length
+	binary.BigEndian.PutUint16(buf, uint16(hdrSize))
+	// Header
+	if _, err := hdr.MarshalTo(buf[2:]); err != nil {
+		return errors.Wrap(err, "marshalling header")
+	}
+	// Message length
+	binary.BigEndian.PutUint32(buf[2+hdrSize:], uint32(size))
+	e.payloadSize = size
+
+	n, err := e.w.Write(buf[:totSize])
+	putBuffer(buf)
+
+	if shouldDebug() {
+		l.Debugf("wrote %d bytes on the wire (2 bytes length, %d bytes header, 4 bytes message length, %d bytes message), err=%v", n, hdrSize, size, err)
+	}
+	if err != nil {
+		return errors.Wrap(err, "writing message")
+	}
+	return nil
+}
+
+func (e *Encoder) shouldCompress(msg Message) bool {
+	switch e.compression {
+	case CompressNever:
+		return false
+
+	case CompressAlways:
+		// Use compression for large enough messages
+		return msg.ProtoSize() >= compressionThreshold
+
+	case CompressMetadata:
+		_, isResponse := msg.(*Response)
+		// Compress if it's large enough and not a response message
+		return !isResponse && msg.ProtoSize() >= compressionThreshold
+
+	default:
+		panic("unknown compression setting")
+	}
+}
+
+// A Decoder reads framed messages, as written by an Encoder, from an
+// underlying reader. It is not safe for concurrent use.
+type Decoder struct {
+	r             io.Reader
+	fourByteBuf   []byte
+	small         []byte                    // for small messages, see SetSmallMessageSize
+	reuseIndex    bool                      // unmarshal index files into slices from the pool
+	maxIndexFiles int                       // refuse index messages with more files, unless zero
+	partial       map[int32]*partialMessage // by stream, for messages split into frames
+	partialLen    int                       // bytes held in partial
+	maxPartialLen int                       // most bytes held in partial, see defaultMaxPartialLen
+	streams       bool                      // accept messages split into frames, see SetStreams
+	size          int                       // bytes on the wire of the message being decoded
+	offset        int64                     // bytes read from the stream
+	frameStart    int64                     // offset of the frame being decoded
+}
+
+// NewDecoder returns a Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r:             r,
+		fourByteBuf:   make([]byte, 4),
+		maxPartialLen: defaultMaxPartialLen,
+	}
+}
+
+// Reset makes the Decoder read from r, discarding any state from the
+// previous stream, so that it can be reused for another stream.
+func (d *Decoder) Reset(r io.Reader) {
+	d.r = r
+	d.partial = nil
+	d.partialLen = 0
+	d.offset, d.frameStart = 0, 0
+}
+
+// SetStreams sets whether the Decoder accepts messages split into frames,
+// as written for a peer with CapabilityStreams. Without it, the default, a
+// frame that doesn't complete its message is an error.
+func (d *Decoder) SetStreams(enabled bool) {
+	d.streams = enabled
+}
+
+// Decode reads the next message. Messages of unknown types are read and
+// discarded, returning ErrUnknownMessage. Any other error leaves the
+// stream in an undefined state, and tells the offset in the stream of the
+// frame that failed to decode, e.g. "at offset 12345: unmarshalling
+// message: ...".
+func (d *Decoder) Decode() (Message, error) {
+	hdr, err := d.DecodeHeader()
+	if err != nil {
+		return nil, err
+	}
+	return d.DecodeMessage(hdr)
+}
+
+// DecodeHeader reads the header preceding the next message, which must then
+// be read using DecodeMessage. For a message split into frames, this is the
+// header of the last frame; earlier frames are read along the way, as they
+// come.
+func (d *Decoder) DecodeHeader() (Header, error) {
+	d.size = 0
+	for {
+		hdr, err := d.decodeFrameHeader()
+		if err != nil || !hdr.More {
+			return hdr, d.atOffset(err)
+		}
+		if err := d.decodeFrame(hdr); err != nil {
+			return Header{}, d.atOffset(err)
+		}
+	}
+}
+
+// atOffset adds the offset of the frame being decoded to the error, except
+// to ErrUnknownMessage which isn't a failure to decode.
+func (d *Decoder) atOffset(err error) error {
+	if err == nil || err == ErrUnknownMessage {
+		return err
+	}
+	return errors.Wrapf(err, "at offset %d", d.frameStart)
+}
+
+// readFull reads exactly len(buf) bytes, keeping track of the offset.
+func (d *Decoder) readFull(buf []byte) error {
+	n, err := io.ReadFull(d.r, buf)
+	d.offset += int64(n)
+	return err
+}
+
+// decodeFrameHeader reads the header of the next frame.
+func (d *Decoder) decodeFrameHeader() (Header, error) {
+	// First comes a 2 byte header length
+
+	d.frameStart = d.offset
+	if err := d.readFull(d.fourByteBuf[:2]); err != nil {
+		return Header{}, errors.Wrap(err, "reading length")
+	}
+	hdrLen := int16(binary.BigEndian.Uint16(d.fourByteBuf))
+	if hdrLen < 0 {
+		return Header{}, fmt.Errorf("negative header length %d", hdrLen)
+	}
+
+	// Then comes the header
+
+	buf := getBuffer(d.small, int(hdrLen))
+	if err := d.readFull(buf); err != nil {
+		return Header{}, errors.Wrap(err, "reading header")
+	}
+
+	var hdr Header
+	if err := hdr.Unmarshal(buf); err != nil {
+		return Header{}, errors.Wrap(err, "unmarshalling header")
+	}
+	if hdr.Stream < 0 || hdr.Stream >= numStreams {
+		return Header{}, fmt.Errorf("unknown stream %d", hdr.Stream)
+	}
+	if hdr.More && !d.streams {
+		return Header{}, fmt.Errorf("frame on stream %d without streams", hdr.Stream)
+	}
+
+	putBuffer(buf)
+	d.size += 2 + int(hdrLen)
+	return hdr, nil
+}
+
+// decodeFrame reads a frame that doesn't complete its message and keeps its
+// data for the frames to come on the same stream.
+func (d *Decoder) decodeFrame(hdr Header) error {
+	pm := d.partial[hdr.Stream]
+	if pm == nil {
+		pm = &partialMessage{msgType: hdr.Type}
+		if d.partial == nil {
+			d.partial = make(map[int32]*partialMessage)
+		}
+		d.partial[hdr.Stream] = pm
+	} else if pm.msgType != hdr.Type {
+		return fmt.Errorf("frame of %v continues %v on stream %d", hdr.Type, pm.msgType, hdr.Stream)
+	}
+
+	msgLen, err := d.decodeLength(hdr.Type, len(pm.data))
+	if err != nil {
+		return err
+	}
+	if msgLen > maxFrameSize {
+		return fmt.Errorf("frame length %d exceeds maximum %d", msgLen, maxFrameSize)
+	}
+	if d.partialLen+msgLen > d.maxPartialLen {
+		return fmt.Errorf("frames of unfinished messages exceed %d bytes", d.maxPartialLen)
+	}
+	d.partialLen += msgLen
+	start := len(pm.data)
+	pm.data = append(pm.data, make([]byte, msgLen)...)
+	if err := d.readFull(pm.data[start:]); err != nil {
+		return errors.Wrap(err, "reading frame")
+	}
+	pm.size += d.size + 4 + msgLen
+	d.size = 0
+	return nil
+}
+
+// decodeLength reads a message length, checking that together with the
+// given bytes already read of the message it is within bounds for the
+// type of message.
+func (d *Decoder) decodeLength(msgType MessageType, sofar int) (int, error) {
+	if err := d.readFull(d.fourByteBuf[:4]); err != nil {
+		return 0, errors.Wrap(err, "reading message length")
+	}
+	msgLen := int32(binary.BigEndian.Uint32(d.fourByteBuf))
+	if msgLen < 0 {
+		return 0, fmt.Errorf("negative message length %d", msgLen)
+	} else if max := maxMessageLen(msgType); int64(sofar)+int64(msgLen) > max {
+		if msgType == messageTypeResponse {
+			return 0, errors.Wrapf(ErrResponseTooLong, "response length %d exceeds maximum %d", int64(sofar)+int64(msgLen), max)
+		}
+		return 0, fmt.Errorf("message length %d exceeds maximum %d", int64(sofar)+int64(msgLen), max)
+	}
+	return int(msgLen), nil
+}
+
+// defaultMaxPartialLen is the most bytes held of messages split into
+// frames that are yet to be completed, across all streams: room for the
+// longest message on the index stream along with a response on the data
+// stream.
+const defaultMaxPartialLen = MaxMessageLen + maxResponseLen
+
+// maxResponseLen is the longest Response message, with room for the fields
+// other than the data of a block of MaxBlockSize bytes.
+const maxResponseLen = MaxBlockSize + 1<<10
+
+// maxMessageLen returns the longest message of the given type we accept,
+// compressed or not. Responses are held to the block size, so that a peer
+// sending more than we could have asked for is caught before it has all
+// been read.
+func maxMessageLen(msgType MessageType) int64 {
+	if msgType == messageTypeResponse {
+		return maxResponseLen
+	}
+	return MaxMessageLen
+}
+
+// DecodeMessage reads the message following the given header.
+func (d *Decoder) DecodeMessage(hdr Header) (Message, error) {
+	msg, err := d.decodeMessage(hdr)
+	return msg, d.atOffset(err)
+}
+
+func (d *Decoder) decodeMessage(hdr Header) (Message, error) {
+	// Any earlier frames of the message have been read already
+
+	pm := d.partial[hdr.Stream]
+	var sofar int
+	if pm != nil {
+		if pm.msgType != hdr.Type {
+			return nil, fmt.Errorf("frame of %v continues %v on stream %d", hdr.Type, pm.msgType, hdr.Stream)
+		}
+		delete(d.partial, hdr.Stream)
+		d.partialLen -= len(pm.data)
+		sofar = len(pm.data)
+		d.size += pm.size
+	}
+
+	// Then comes a 4 byte message length
+
+	msgLen, err := d.decodeLength(hdr.Type, sofar)
+	if err != nil {
+		return nil, err
+	}
+	if pm != nil && msgLen > maxFrameSize {
+		return nil, fmt.Errorf("frame length %d exceeds maximum %d", msgLen, maxFrameSize)
+	}
+	d.size += 4 + msgLen
+
+	// Then comes the message
+
+	buf := getBuffer(d.small, sofar+msgLen)
+	if pm != nil {
+		copy(buf, pm.data)
+	}
+	if err := d.readFull(buf[sofar:]); err != nil {
+		return nil, errors.Wrap(err, "reading message")
+	}
+
+	// ... which might be compressed
+
+	switch hdr.Compression {
+	case MessageCompressionNone:
+		// Nothing
+
+	case MessageCompressionLZ4:
+		if len(buf) >= 4 && hdr.Type == messageTypeResponse && binary.BigEndian.Uint32(buf) > maxResponseLen {
+			return nil, errors.Wrapf(ErrResponseTooLong, "decompressed response length %d exceeds maximum %d", binary.BigEndian.Uint32(buf), maxResponseLen)
+		}
+		decomp, err := lz4Decompress(buf)
+		putBuffer(buf)
+		if err != nil {
+			return nil, errors.Wrap(err, "decompressing message")
+		}
+		buf = decomp
+
+	default:
+		return nil, fmt.Errorf("unknown message compression %d", hdr.Compression)
+	}
+
+	// ... and might carry a checksum of the uncompressed message
+
+	if len(hdr.Checksum) > 0 && !checksumMatches(buf, hdr.Checksum) {
+		return nil, ErrChecksumMismatch
+	}
+
+	// ... and is then unmarshalled
+
+	msg, err := newMessage(hdr.Type)
+	if err != nil {
+		return nil, err
+	}
+	if d.maxIndexFiles > 0 && (hdr.Type == messageTypeIndex || hdr.Type == messageTypeIndexUpdate) {
+		if err := checkIndexFiles(buf, d.maxIndexFiles); err != nil {
+			return nil, err
+		}
+	}
+	if d.reuseIndex {
+		switch msg := msg.(type) {
+		case *Index:
+			msg.Files = getIndexFiles()
+		case *IndexUpdate:
+			msg.Files = getIndexFiles()
+		}
+	}
+	if err := msg.Unmarshal(buf); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling message")
+	}
+	putBuffer(buf)
+
+	return msg, nil
+}
+
+var crc32Table = crc32.MakeTable(crc32.Castagnoli)
+
+// checksum returns the big endian CRC32 (Castagnoli) of the given data, as
+// carried in the message header.
+func checksum(data []byte) []byte {
+	bs := make([]byte, crc32.Size)
+	binary.BigEndian.PutUint32(bs, crc32.Checksum(data, crc32Table))
+	return bs
+}
+
+func checksumMatches(data, expected []byte) bool {
+	return len(expected) == crc32.Size && binary.BigEndian.Uint32(expected) == crc32.Checksum(data, crc32Table)
+}
+
+func typeOf(msg Message) MessageType {
+	switch msg.(type) {
+	case *ClusterConfig:
+		return messageTypeClusterConfig
+	case *Index:
+		return messageTypeIndex
+	case *IndexUpdate:
+		return messageTypeIndexUpdate
+	case *Request:
+		return messageTypeRequest
+	case *Response:
+		return messageTypeResponse
+	case *DownloadProgress:
+		return messageTypeDownloadProgress
+	case *Ping:
+		return messageTypePing
+	case *Close:
+		return messageTypeClose
+	case *Push:
+		return messageTypePush
+	case *Pong:
+		return messageTypePong
+	case *QuotaRequest:
+		return messageTypeQuotaRequest
+	case *QuotaResponse:
+		return messageTypeQuotaResponse
+	case *ListingRequest:
+		return messageTypeListingRequest
+	case *ListingResponse:
+		return messageTypeListingResponse
+	case *FileInfoRequest:
+		return messageTypeFileInfoRequest
+	case *FileInfoResponse:
+		return messageTypeFileInfoResponse
+	case *IndexAck:
+		return messageTypeIndexAck
+	case *RangeRequest:
+		return messageTypeRangeRequest
+	case *RangeResponse:
+		return messageTypeRangeResponse
+	case *IndexSummary:
+		return messageTypeIndexSummary
+	case *Idle:
+		return messageTypeIdle
+	default:
+		panic("bug: unknown message type")
+	}
+}
+
+func newMessage(t MessageType) (Message, error) {
+	switch t {
+	case messageTypeClusterConfig:
+		return new(ClusterConfig), nil
+	case messageTypeIndex:
+		return new(Index), nil
+	case messageTypeIndexUpdate:
+		return new(IndexUpdate), nil
+	case messageTypeRequest:
+		return new(Request), nil
+	case messageTypeResponse:
+		return new(Response), nil
+	case messageTypeDownloadProgress:
+		return new(DownloadProgress), nil
+	case messageTypePing:
+		return new(Ping), nil
+	case messageTypeClose:
+		return new(Close), nil
+	case messageTypePush:
+		return new(Push), nil
+	case messageTypePong:
+		return new(Pong), nil
+	case messageTypeQuotaRequest:
+		return new(QuotaRequest), nil
+	case messageTypeQuotaResponse:
+		return new(QuotaResponse), nil
+	case messageTypeListingRequest:
+		return new(ListingRequest), nil
+	case messageTypeListingResponse:
+		return new(ListingResponse), nil
+	case messageTypeFileInfoRequest:
+		return new(FileInfoRequest), nil
+	case messageTypeFileInfoResponse:
+		return new(FileInfoResponse), nil
+	case messageTypeIndexAck:
+		return new(IndexAck), nil
+	case messageTypeRangeRequest:
+		return new(RangeRequest), nil
+	case messageTypeRangeResponse:
+		return new(RangeResponse), nil
+	case messageTypeIndexSummary:
+		return new(IndexSummary), nil
+	case messageTypeIdle:
+		return new(Idle), nil
+	default:
+		return nil, ErrUnknownMessage
+	}
+}
+
+func lz4Compress(src []byte) ([]byte, error) {
+	var err error
+	buf := BufferPool.Get(lz4.CompressBound(len(src)))
+	compressed, err := lz4.Encode(buf, src)
+	if err != nil {
+		return nil, err
+	}
+	if &compressed[0] != &buf[0] {
+		panic("bug: lz4.Compress allocated, which it must not (should use buffer pool)")
+	}
+
+	binary.BigEndian.PutUint32(compressed, binary.LittleEndian.Uint32(compressed))
+	return compressed, nil
+}
+
+func lz4Decompress(src []byte) ([]byte, error) {
+	if len(src) < 4 {
+		return nil, errors.New("compressed message too short")
+	}
+	size := binary.BigEndian.Uint32(src)
+	if size > MaxMessageLen {
+		return nil, fmt.Errorf("decompressed message length %d exceeds maximum %d", size, MaxMessageLen)
+	}
+	binary.LittleEndian.PutUint32(src, size)
+	var err error
+	buf := BufferPool.Get(int(size))
+	decoded, err := lz4.Decode(buf, src)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) > 0 && &decoded[0] != &buf[0] {
+		panic("bug: lz4.Decode allocated, which it must not (should use buffer pool)")
+	}
+	return decoded, nil
+}
@@ -0,0 +1,199 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	msgs := []Message{
+		&ClusterConfig{Folders: []Folder{{ID: "default", Label: "Default"}}},
+		&Index{Folder: "default", Files: []FileInfo{{Name: "foo", Size: 128, Blocks: []BlockInfo{{Size: 128, Hash: make([]byte, 32)}}}}},
+		&Request{ID: 1, Folder: "default", Name: "foo", Size: 128},
+		&Response{ID: 1, Data: bytes.Repeat([]byte("response "), 64)},
+		&Ping{},
+		&Close{Reason: "because"},
+	}
+
+	for _, checksums := range []bool{false, true} {
+		for _, comp := range []Compression{CompressNever, CompressMetadata, CompressAlways} {
+			var buf bytes.Buffer
+			enc := NewEncoder(&buf, comp)
+			enc.SetChecksums(checksums)
+			for _, msg := range msgs {
+				if err := enc.Encode(msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			dec := NewDecoder(&buf)
+			for _, msg := range msgs {
+				res, err := dec.Decode()
+				if err != nil {
+					t.Fatalf("%v/%v: %v", comp, checksums, err)
+				}
+				exp, _ := msg.Marshal()
+				got, _ := res.Marshal()
+				if typeOf(res) != typeOf(msg) || !bytes.Equal(got, exp) {
+					t.Errorf("%v/%v: decoded %v, expected %v", comp, checksums, res, msg)
+				}
+			}
+			if _, err := dec.Decode(); err == nil || errors.Cause(err) != io.EOF {
+				t.Errorf("%v/%v: expected EOF at end of stream, got %v", comp, checksums, err)
+			}
+		}
+	}
+}
+
+func TestEncoderDecoderReset(t *testing.T) {
+	enc := NewEncoder(nil, CompressAlways)
+	enc.SetChecksums(true)
+	dec := NewDecoder(nil)
+
+	for i := 0; i < 5; i++ {
+		var buf bytes.Buffer
+		enc.Reset(&buf)
+		msg := &Request{ID: int32(i), Folder: "default", Name: "foo", Size: 128}
+		if err := enc.Encode(msg); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Encode(&Ping{}); err != nil {
+			t.Fatal(err)
+		}
+
+		dec.Reset(&buf)
+		res, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("Stream %d: %v", i, err)
+		}
+		if req, ok := res.(*Request); !ok || req.ID != int32(i) {
+			t.Errorf("Stream %d: decoded %v, expected %v", i, res, msg)
+		}
+		// The rest of the stream is abandoned, which must not affect the
+		// next one.
+	}
+}
+
+func TestDecodeUnknownMessage(t *testing.T) {
+	var buf bytes.Buffer
+
+	// A message of a type from the future
+	hdr := Header{Type: MessageType(1000)}
+	hdrBs, _ := hdr.Marshal()
+	bs := make([]byte, 2)
+	binary.BigEndian.PutUint16(bs, uint16(len(hdrBs)))
+	buf.Write(bs)
+	buf.Write(hdrBs)
+	bs = make([]byte, 4)
+	binary.BigEndian.PutUint32(bs, 3)
+	buf.Write(bs)
+	buf.Write([]byte{1, 2, 3})
+
+	// ... followed by one we know
+	if err := NewEncoder(&buf, CompressNever).Encode(&Ping{ID: 42}); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := NewDecoder(&buf)
+	hdr, err := dec.DecodeHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Type != MessageType(1000) {
+		t.Errorf("Unexpected message type %v", hdr.Type)
+	}
+	if _, err := dec.DecodeMessage(hdr); err != ErrUnknownMessage {
+		t.Fatalf("Expected ErrUnknownMessage, got %v", err)
+	}
+
+	msg, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ping, ok := msg.(*Ping); !ok || ping.ID != 42 {
+		t.Errorf("Unexpected message %v after unknown message", msg)
+	}
+}
+
+func TestDecodeChecksumMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, CompressNever)
+	enc.SetChecksums(true)
+	if err := enc.Encode(&Close{Reason: "because"}); err != nil {
+		t.Fatal(err)
+	}
+
+	bs := buf.Bytes()
+	bs[len(bs)-1] ^= 0xff
+	if _, err := NewDecoder(bytes.NewReader(bs)).Decode(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestDecodeErrorOffset(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, CompressNever)
+	if err := enc.Encode(&Ping{ID: 42}); err != nil {
+		t.Fatal(err)
+	}
+	offset := buf.Len()
+
+	// A close message that doesn't unmarshal: a field of an invalid wire
+	// type.
+	hdr := Header{Type: messageTypeClose}
+	bs := make([]byte, 2+hdr.ProtoSize()+4+1)
+	binary.BigEndian.PutUint16(bs, uint16(hdr.ProtoSize()))
+	if _, err := hdr.MarshalTo(bs[2:]); err != nil {
+		t.Fatal(err)
+	}
+	binary.BigEndian.PutUint32(bs[2+hdr.ProtoSize():], 1)
+	bs[len(bs)-1] = 0xff
+	buf.Write(bs)
+
+	dec := NewDecoder(&buf)
+	if _, err := dec.Decode(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := dec.Decode()
+	if err == nil {
+		t.Fatal("Expected a parse error")
+	}
+	if exp := fmt.Sprintf("at offset %d: unmarshalling message", offset); !strings.HasPrefix(err.Error(), exp) {
+		t.Errorf("Error %q doesn't start with %q", err, exp)
+	}
+}
+
+func TestDecodeResponseTooLong(t *testing.T) {
+	// A response announcing more data than any request can ask for is
+	// refused from its length alone, before the data is read.
+	hdr := Header{Type: messageTypeResponse}
+	bs := make([]byte, 2+hdr.ProtoSize()+4)
+	binary.BigEndian.PutUint16(bs, uint16(hdr.ProtoSize()))
+	if _, err := hdr.MarshalTo(bs[2:]); err != nil {
+		t.Fatal(err)
+	}
+	binary.BigEndian.PutUint32(bs[2+hdr.ProtoSize():], maxResponseLen+1)
+
+	_, err := NewDecoder(bytes.NewReader(bs)).Decode()
+	if !errors.Is(err, ErrResponseTooLong) {
+		t.Errorf("Decoding returned %v, expected ErrResponseTooLong", err)
+	}
+
+	// Other messages may be that long.
+	hdr.Type = messageTypeIndex
+	if _, err := hdr.MarshalTo(bs[2:]); err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewDecoder(bytes.NewReader(bs)).Decode()
+	if errors.Is(err, ErrResponseTooLong) || !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		t.Errorf("Decoding a truncated index returned %v, expected EOF", err)
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+// +build gofuzz
+
+package protocol
+
+import (
+	"bytes"
+)
+
+// Fuzz is the entry point for go-fuzz. Reading a message must never panic,
+// regardless of the input.
+func Fuzz(data []byte) int {
+	msg, err := NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		return 0
+	}
+	if msg == nil {
+		panic("neither message nor error")
+	}
+	return 1
+}
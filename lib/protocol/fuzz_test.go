@@ -0,0 +1,127 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+)
+
+// readMessageFrom reads a single message from data, which must not panic
+// regardless of the contents of data.
+func readMessageFrom(data []byte) (msg Message, err error) {
+	return NewDecoder(bytes.NewReader(data)).Decode()
+}
+
+// encodeMessages returns a number of valid messages as they'd be sent on the
+// wire, with varying message types, compression and checksums.
+func encodeMessages(t *testing.T) [][]byte {
+	t.Helper()
+
+	msgs := []Message{
+		&ClusterConfig{Folders: []Folder{{ID: "default", Devices: []Device{{ID: c0ID, Addresses: []string{"dynamic"}}}}}},
+		&Index{Folder: "default", Files: []FileInfo{{Name: "foo", Size: 128, Blocks: []BlockInfo{{Size: 128, Hash: make([]byte, 32)}}, Version: Vector{Counters: []Counter{{ID: 1, Value: 2}}}}}},
+		&IndexUpdate{Folder: "default", Files: []FileInfo{{Name: "bar", Type: FileInfoTypeDirectory}}},
+		&Request{ID: 1, Folder: "default", Name: "foo", Size: 128, Hash: make([]byte, 32)},
+		&Response{ID: 1, Data: bytes.Repeat([]byte("response "), 64)},
+		&DownloadProgress{Folder: "default", Updates: []FileDownloadProgressUpdate{{Name: "foo", BlockIndexes: []int32{1, 2, 3}}}},
+		&Ping{},
+		&Ping{ID: 3},
+		&Pong{ID: 3, LatencyNs: 12345},
+		&QuotaRequest{ID: 4, Folder: "default"},
+		&QuotaResponse{ID: 4, Free: 1 << 30, Total: 1 << 40},
+		&IndexSummary{Folder: "default", Hashes: []uint64{1, 2, 3}},
+		&ListingRequest{ID: 5, Folder: "default", Path: "dir", After: "a", Limit: 10},
+		&ListingResponse{ID: 5, Entries: []ListingEntry{{Name: "b", Size: 128, ModifiedS: 1}}, Next: "b"},
+		&Close{Reason: "because"},
+		&Push{ID: 2, Folder: "default", Name: "foo", Data: []byte("data")},
+	}
+
+	var res [][]byte
+	for _, checksums := range []bool{false, true} {
+		for _, comp := range []Compression{CompressNever, CompressAlways} {
+			for _, msg := range msgs {
+				var buf bytes.Buffer
+				enc := NewEncoder(&buf, comp)
+				enc.SetChecksums(checksums)
+				if err := enc.Encode(msg); err != nil {
+					t.Fatal(err)
+				}
+				res = append(res, buf.Bytes())
+			}
+		}
+	}
+	return res
+}
+
+func TestReadMessageValid(t *testing.T) {
+	for _, data := range encodeMessages(t) {
+		if _, err := readMessageFrom(data); err != nil {
+			t.Errorf("Unexpected error reading %x: %v", data, err)
+		}
+	}
+}
+
+func TestReadMessageMalformed(t *testing.T) {
+	// Specific cases that have caused trouble
+	cases := []string{
+		// LZ4 compressed message too short for the decompressed size
+		"0002100100000002aabb",
+		// LZ4 compressed message with an excessive decompressed size
+		"000210010000000affffffff000000000000",
+		// LZ4 compressed message with a zero decompressed size
+		"000210010000000400000000",
+	}
+	for _, c := range cases {
+		data, err := hex.DecodeString(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkReadMessage(t, data)
+	}
+
+	// Random mutations and truncations of valid messages
+	rnd := rand.New(rand.NewSource(42))
+	valid := encodeMessages(t)
+	for i := 0; i < 10000; i++ {
+		data := append([]byte(nil), valid[rnd.Intn(len(valid))]...)
+		for n := rnd.Intn(4) + 1; n > 0; n-- {
+			switch rnd.Intn(3) {
+			case 0:
+				data[rnd.Intn(len(data))] = byte(rnd.Intn(256))
+			case 1:
+				data = data[:rnd.Intn(len(data))]
+				if len(data) == 0 {
+					data = []byte{0}
+				}
+			case 2:
+				// Keep lengths plausible by rewriting the message length
+				// field, which follows the header.
+				if len(data) < 2 {
+					continue
+				}
+				hdrLen := int(binary.BigEndian.Uint16(data))
+				if len(data) >= 2+hdrLen+4 {
+					binary.BigEndian.PutUint32(data[2+hdrLen:], uint32(rnd.Intn(len(data))))
+				}
+			}
+		}
+		checkReadMessage(t, data)
+	}
+}
+
+func checkReadMessage(t *testing.T, data []byte) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("Panic reading %x: %v", data, r)
+		}
+	}()
+	msg, err := readMessageFrom(data)
+	if err == nil && msg == nil {
+		t.Fatalf("Neither message nor error reading %x", data)
+	}
+}
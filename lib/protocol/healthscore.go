@@ -0,0 +1,108 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// healthLatencyScale is the latency scoring one half.
+	healthLatencyScale = 100 * time.Millisecond
+	// healthThroughputScale is the byte rate scoring one half.
+	healthThroughputScale = 1 << 20
+	// errorRateAlpha is the weight of the latest request in the smoothed
+	// error rate.
+	errorRateAlpha = 0.1
+)
+
+// HealthWeights are the relative weights of the components of the health
+// score, see Health.Score. Only their ratios matter.
+type HealthWeights struct {
+	Latency    float64
+	Throughput float64
+	Errors     float64
+	Idle       float64
+}
+
+// DefaultHealthWeights weigh all components equally.
+var DefaultHealthWeights = HealthWeights{Latency: 1, Throughput: 1, Errors: 1, Idle: 1}
+
+// Score combines the health into a single number between zero and one, for
+// comparing connections; higher is better. It is zero for a connection
+// that is not alive, and otherwise the weighted average of these
+// components, each between zero and one:
+//
+//	latency:    100ms / (100ms + Latency)
+//	throughput: BytesPerSec / (BytesPerSec + 1 MiB/s)
+//	errors:     1 - ErrorRate
+//	idle:       max(0, 1 - (At - LastReceive) / ReceiveTimeout)
+//
+// Latency and throughput are left out while they are zero, i.e. unknown.
+// The score is one if no component with a non-zero weight is left. It
+// depends on nothing but the health and the weights.
+func (h Health) Score(w HealthWeights) float64 {
+	if !h.Alive {
+		return 0
+	}
+
+	var sum, weights float64
+	add := func(weight, score float64) {
+		sum += weight * score
+		weights += weight
+	}
+	if h.Latency > 0 {
+		add(w.Latency, float64(healthLatencyScale)/float64(healthLatencyScale+h.Latency))
+	}
+	if h.BytesPerSec > 0 {
+		add(w.Throughput, h.BytesPerSec/(h.BytesPerSec+healthThroughputScale))
+	}
+	add(w.Errors, 1-h.ErrorRate)
+	idle := 1 - float64(h.At.Sub(h.LastReceive))/float64(ReceiveTimeout)
+	if idle < 0 {
+		idle = 0
+	} else if idle > 1 {
+		idle = 1
+	}
+	add(w.Idle, idle)
+
+	if weights == 0 {
+		return 1
+	}
+	return sum / weights
+}
+
+// HealthScore returns the score of the current health of the connection,
+// using the weights set with WithHealthWeights.
+func (c *rawConnection) HealthScore() float64 {
+	return c.HealthCheck().Score(c.healthWeights)
+}
+
+// errorRate is an exponentially weighted moving average of the fraction of
+// requests that failed.
+type errorRate struct {
+	mut  sync.Mutex
+	rate float64
+}
+
+// record adds the outcome of a request. Not modified answers are a
+// success, and closing the connection isn't the fault of the request.
+func (r *errorRate) record(err error) {
+	if err == ErrClosed {
+		return
+	}
+	sample := 0.0
+	if err != nil && err != ErrNotModified {
+		sample = 1
+	}
+	r.mut.Lock()
+	r.rate += errorRateAlpha * (sample - r.rate)
+	r.mut.Unlock()
+}
+
+func (r *errorRate) get() float64 {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return r.rate
+}
@@ -0,0 +1,68 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHealthScore(t *testing.T) {
+	at := time.Unix(1000, 0)
+	cases := []struct {
+		name    string
+		health  Health
+		weights HealthWeights
+		score   float64
+	}{
+		{"not alive", Health{At: at, LastReceive: at}, DefaultHealthWeights, 0},
+		{"perfect", Health{At: at, LastReceive: at, Alive: true}, DefaultHealthWeights, 1},
+		{"latency", Health{At: at, LastReceive: at, Alive: true, Latency: 100 * time.Millisecond}, DefaultHealthWeights, (0.5 + 1 + 1) / 3},
+		{"throughput", Health{At: at, LastReceive: at, Alive: true, BytesPerSec: 1 << 20}, DefaultHealthWeights, (0.5 + 1 + 1) / 3},
+		{"errors", Health{At: at, LastReceive: at, Alive: true, ErrorRate: 0.25}, DefaultHealthWeights, (0.75 + 1) / 2},
+		{"idle", Health{At: at, LastReceive: at.Add(-ReceiveTimeout / 2), Alive: true}, DefaultHealthWeights, (1 + 0.5) / 2},
+		{"long idle", Health{At: at, LastReceive: at.Add(-2 * ReceiveTimeout), Alive: true}, DefaultHealthWeights, 0.5},
+		{"weighted", Health{At: at, LastReceive: at, Alive: true, Latency: 100 * time.Millisecond, ErrorRate: 1}, HealthWeights{Latency: 3, Errors: 1}, (3*0.5 + 0) / 4},
+		{"no weights left", Health{At: at, LastReceive: at, Alive: true, ErrorRate: 1}, HealthWeights{Latency: 1}, 1},
+	}
+	for _, tc := range cases {
+		if score := tc.health.Score(tc.weights); math.Abs(score-tc.score) > 1e-9 {
+			t.Errorf("%s: score %v, expected %v", tc.name, score, tc.score)
+		}
+	}
+}
+
+func TestHealthScoreErrors(t *testing.T) {
+	m0 := newTestModel()
+	m0.requestFn = func(string, string, int32, int64) (RequestResponse, error) {
+		return nil, ErrNoSuchFile
+	}
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, nil, []Option{WithHealthWeights(HealthWeights{Errors: 1})})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c1.WaitHandshake(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if score := c1.HealthScore(); score != 1 {
+		t.Errorf("Score %v before any requests, expected 1", score)
+	}
+
+	if _, err := c1.Request(ctx, "default", "foo", 0, 4, nil, 0, false); err != ErrNoSuchFile {
+		t.Fatalf("Request returned %v", err)
+	}
+	if score := c1.HealthScore(); math.Abs(score-(1-errorRateAlpha)) > 1e-9 {
+		t.Errorf("Score %v after a failed request, expected %v", score, 1-errorRateAlpha)
+	}
+
+	c1.Close(errors.New("done"))
+	for !c1.Closed() {
+		time.Sleep(time.Millisecond)
+	}
+	if score := c1.HealthScore(); score != 0 {
+		t.Errorf("Score %v after close, expected 0", score)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultIdlePingInterval is the longest time between pings we accept
+// while idle mode is in force, unless set with WithIdlePingInterval.
+const DefaultIdlePingInterval = 15 * time.Minute
+
+// An IdleModel is told when the other side enters or leaves idle mode, see
+// SetIdle. While the other side is idle the model should hold back index
+// data and anything else the other side hasn't asked for, and send it once
+// the other side is no longer idle.
+type IdleModel interface {
+	PeerIdle(deviceID DeviceID, idle bool)
+}
+
+// SetIdle enters or leaves idle mode, telling the other side. While we are
+// idle the other side doesn't send anything we haven't asked for, and both
+// sides ping at the idle ping interval agreed on during the handshake
+// instead of every PingSendInterval, so that an otherwise idle device,
+// e.g. a phone on battery, keeps the connection with as little traffic as
+// possible. Sending index data, download progress or a push leaves idle
+// mode, telling the other side first, so there is no need to call SetIdle
+// before that. SetIdle returns ErrUnsupported if the other side doesn't
+// support idle mode.
+func (c *rawConnection) SetIdle(ctx context.Context, idle bool) error {
+	if !c.peerSupports(CapabilityIdleMode) || c.agreedIdlePingInterval == 0 {
+		return ErrUnsupported
+	}
+	var to int32
+	if idle {
+		to = 1
+	}
+	// Changes are queued in the order they are made, so that the other
+	// side ends up in agreement with us.
+	c.idleMut.Lock()
+	if atomic.SwapInt32(&c.idle, to) == to {
+		c.idleMut.Unlock()
+		return nil
+	}
+	done := make(chan struct{})
+	sent := c.send(ctx, &Idle{Idle: idle}, done)
+	c.idleMut.Unlock()
+	if !sent {
+		return ErrClosed
+	}
+	select {
+	case <-done:
+		return nil
+	case <-c.closed:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PeerIdle returns true if the other side is in idle mode, and doesn't want
+// to be sent anything it hasn't asked for.
+func (c *rawConnection) PeerIdle() bool {
+	return atomic.LoadInt32(&c.peerIdle) != 0
+}
+
+// leaveIdle leaves idle mode ahead of sending the message, when it is
+// something the other side hasn't asked for. The Idle message goes out on
+// the control stream, which is always written first.
+func (c *rawConnection) leaveIdle(ctx context.Context, msg Message) {
+	switch msg.(type) {
+	case *Index, *IndexUpdate, *IndexSummary, *DownloadProgress, *Push:
+	default:
+		return
+	}
+	if atomic.LoadInt32(&c.idle) == 0 {
+		return
+	}
+	c.idleMut.Lock()
+	if atomic.SwapInt32(&c.idle, 0) == 1 {
+		l.Debugln(c.id, "leaving idle mode to send", typeOf(msg))
+		c.send(ctx, &Idle{}, nil)
+	}
+	c.idleMut.Unlock()
+}
+
+func (c *rawConnection) handleIdle(msg Idle) {
+	l.Debugf("Idle(%v, %v)", c.id, msg.Idle)
+	var idle int32
+	if msg.Idle {
+		idle = 1
+	}
+	if atomic.SwapInt32(&c.peerIdle, idle) == idle {
+		return
+	}
+	if c.idleModel != nil {
+		c.idleModel.PeerIdle(c.id, msg.Idle)
+	}
+}
+
+// idleMode returns true while either side is idle, and both support idle
+// mode.
+func (c *rawConnection) idleMode() bool {
+	return (atomic.LoadInt32(&c.idle) != 0 || c.PeerIdle()) && c.agreedIdlePingInterval > 0
+}
+
+// pingInterval returns how often we make sure to send a message, which is
+// the agreed idle ping interval while either side is idle.
+func (c *rawConnection) pingInterval() time.Duration {
+	if c.idleMode() {
+		return c.agreedIdlePingInterval
+	}
+	return PingSendInterval
+}
+
+// receiveTimeout returns the longest we wait for a message from the other
+// side, which is longer in the same proportion as the ping interval while
+// either side is idle.
+func (c *rawConnection) receiveTimeout() time.Duration {
+	if c.idleMode() {
+		return time.Duration(float64(c.agreedIdlePingInterval) * float64(ReceiveTimeout) / float64(PingSendInterval))
+	}
+	return ReceiveTimeout
+}
+
+// agreeIdlePingInterval returns the shorter of both intervals, or zero if
+// either side doesn't support idle mode.
+func agreeIdlePingInterval(ours, theirs time.Duration) time.Duration {
+	if ours <= 0 || theirs <= 0 {
+		return 0
+	}
+	if theirs < PingSendInterval {
+		theirs = PingSendInterval
+	}
+	if theirs < ours {
+		return theirs
+	}
+	return ours
+}
@@ -0,0 +1,140 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type idleTestModel struct {
+	*TestModel
+	events chan string
+}
+
+func newIdleTestModel() *idleTestModel {
+	m := &idleTestModel{TestModel: newTestModel(), events: make(chan string, 10)}
+	m.indexFn = func(_ DeviceID, folder string, _ []FileInfo) {
+		m.events <- "index " + folder
+	}
+	return m
+}
+
+func (m *idleTestModel) PeerIdle(_ DeviceID, idle bool) {
+	m.events <- fmt.Sprint("idle ", idle)
+}
+
+func (m *idleTestModel) DownloadProgress(_ DeviceID, folder string, _ []FileDownloadProgressUpdate) error {
+	m.events <- "progress " + folder
+	return nil
+}
+
+func (m *idleTestModel) expect(t *testing.T, event string) {
+	t.Helper()
+	select {
+	case got := <-m.events:
+		if got != event {
+			t.Fatalf("Got %q, expected %q", got, event)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for %q", event)
+	}
+}
+
+func TestIdleMode(t *testing.T) {
+	m0, m1 := newIdleTestModel(), newIdleTestModel()
+	c0, c1 := newTestConnections(t, m0, m1, CompressNever, []Option{WithIdlePingInterval(10 * time.Minute)}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, c := range []wireFormatConnection{c0, c1} {
+		if err := c.WaitHandshake(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if d := c.EffectiveOptions().IdlePingInterval; d != 10*time.Minute {
+			t.Errorf("Agreed on idle ping interval %v, expected the shorter of both", d)
+		}
+	}
+
+	raw0 := c0.rawConnection
+	raw1 := c1.rawConnection
+	if raw0.pingInterval() != PingSendInterval || raw0.receiveTimeout() != ReceiveTimeout {
+		t.Errorf("Idle intervals in force before entering idle mode")
+	}
+
+	if err := c1.SetIdle(ctx, true); err != nil {
+		t.Fatal(err)
+	}
+	m0.expect(t, "idle true")
+	if !c0.PeerIdle() {
+		t.Error("Other side not idle")
+	}
+	for _, c := range []*rawConnection{raw0, raw1} {
+		if d := c.pingInterval(); d != 10*time.Minute {
+			t.Errorf("Ping interval %v while idle", d)
+		}
+		if d := c.receiveTimeout(); d != 2000*time.Second {
+			t.Errorf("Receive timeout %v while idle", d)
+		}
+	}
+
+	// The idle side isn't sent download progress, but still gets what
+	// else the model decides to send.
+	c0.DownloadProgress(ctx, "progress", nil)
+	if err := c0.Index(ctx, "first", nil); err != nil {
+		t.Fatal(err)
+	}
+	m1.expect(t, "index first")
+
+	// Sending index data leaves idle mode, telling the other side first.
+	if err := c1.Index(ctx, "second", nil); err != nil {
+		t.Fatal(err)
+	}
+	m0.expect(t, "idle false")
+	m0.expect(t, "index second")
+	if c0.PeerIdle() || raw1.idleMode() {
+		t.Error("Still in idle mode")
+	}
+	if err := c1.SetIdle(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-m0.events:
+		t.Errorf("Unexpected %q when not changing idle mode", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestIdleModeUnsupported(t *testing.T) {
+	c0, _ := newTestConnections(t, newTestModel(), newTestModel(), CompressNever, nil, []Option{withoutCapability(CapabilityIdleMode)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c0.WaitHandshake(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c0.SetIdle(ctx, true); err != ErrUnsupported {
+		t.Errorf("Got %v, expected ErrUnsupported", err)
+	}
+	if d := c0.EffectiveOptions().IdlePingInterval; d != 0 {
+		t.Errorf("Agreed on idle ping interval %v without support", d)
+	}
+}
+
+func TestAgreeIdlePingInterval(t *testing.T) {
+	cases := []struct {
+		ours, theirs, agreed time.Duration
+	}{
+		{15 * time.Minute, 0, 0},
+		{15 * time.Minute, 10 * time.Minute, 10 * time.Minute},
+		{10 * time.Minute, 15 * time.Minute, 10 * time.Minute},
+		{15 * time.Minute, time.Second, PingSendInterval},
+	}
+	for _, tc := range cases {
+		if agreed := agreeIdlePingInterval(tc.ours, tc.theirs); agreed != tc.agreed {
+			t.Errorf("agreeIdlePingInterval(%v, %v) = %v, expected %v", tc.ours, tc.theirs, agreed, tc.agreed)
+		}
+	}
+}
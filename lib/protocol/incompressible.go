@@ -0,0 +1,21 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+// An IncompressibleResponse is a RequestResponse that knows whether its
+// data compresses, e.g. because the block is part of an already compressed
+// file such as a JPEG. A RequestResponse returned by the model that
+// implements IncompressibleResponse and says its data is incompressible is
+// sent uncompressed, saving the CPU time of trying. Other responses are
+// compressed as set for the connection.
+type IncompressibleResponse interface {
+	RequestResponse
+	Incompressible() bool
+}
+
+// isIncompressible returns true if the response says its data doesn't
+// compress.
+func isIncompressible(res RequestResponse) bool {
+	ir, ok := res.(IncompressibleResponse)
+	return ok && ir.Incompressible()
+}
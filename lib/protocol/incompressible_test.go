@@ -0,0 +1,94 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+type incompressibleResponse struct {
+	fakeRequestResponse
+	incompressible bool
+}
+
+func (r *incompressibleResponse) Incompressible() bool {
+	return r.incompressible
+}
+
+func TestIncompressibleResponse(t *testing.T) {
+	for _, incompressible := range []bool{false, true} {
+		t.Run(fmt.Sprintf("incompressible=%v", incompressible), func(t *testing.T) {
+			ar, aw := io.Pipe()
+			br, bw := io.Pipe()
+			defer ar.Close()
+			defer br.Close()
+
+			m := newTestModel()
+			m.requestFn = func(string, string, int32, int64) (RequestResponse, error) {
+				// Compressible data, whatever the hint says.
+				return &incompressibleResponse{fakeRequestResponse{make([]byte, 128<<10)}, incompressible}, nil
+			}
+			c := NewConnection(c0ID, ar, bw, m, "name", CompressAlways, WithoutPinger())
+			c.Start()
+			c.ClusterConfig(ClusterConfig{})
+			enc := NewEncoder(aw, CompressNever)
+			if err := enc.Encode(&ClusterConfig{}); err != nil {
+				t.Fatal(err)
+			}
+			dec := NewDecoder(br)
+			if _, err := dec.Decode(); err != nil { // our cluster config
+				t.Fatal(err)
+			}
+
+			if err := enc.Encode(&Request{ID: 1, Folder: "default", Name: "foo", Size: 128 << 10}); err != nil {
+				t.Fatal(err)
+			}
+			hdr, err := dec.DecodeHeader()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hdr.Type != messageTypeResponse {
+				t.Fatalf("Expected a response, got %v", hdr.Type)
+			}
+			exp := MessageCompressionLZ4
+			if incompressible {
+				exp = MessageCompressionNone
+			}
+			if hdr.Compression != exp {
+				t.Errorf("Response sent with compression %v, expected %v", hdr.Compression, exp)
+			}
+			msg, err := dec.DecodeMessage(hdr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp := msg.(*Response); len(resp.Data) != 128<<10 {
+				t.Errorf("Got %d bytes, expected %d", len(resp.Data), 128<<10)
+			}
+		})
+	}
+}
+
+func BenchmarkEncodeIncompressibleResponse(b *testing.B) {
+	// Compares compressing a response of random data, as is done without
+	// a hint, to sending it as is.
+	data := make([]byte, 128<<10)
+	rand.Read(data)
+	resp := &Response{ID: 1, Data: data}
+
+	for _, comp := range []Compression{CompressAlways, CompressNever} {
+		b.Run(comp.String(), func(b *testing.B) {
+			enc := NewEncoder(ioutil.Discard, comp)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if err := enc.Encode(resp); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"sync"
+)
+
+// An IndexAckModel is told how far the other side has applied the index
+// data we sent it. A Model passed to NewConnection that also implements
+// IndexAckModel makes the connection advertise CapabilityIndexAck, and the
+// other side then acknowledges every Index and IndexUpdate once its model
+// has accepted the files.
+//
+// The model may use the acknowledged sequence as a point to resume from,
+// e.g. by sending only files with a higher sequence number in an
+// IndexUpdate after reconnecting, or to hold back further index data
+// until earlier data has been applied. When the other side doesn't
+// acknowledge anything, because it is of an older version, IndexAcked is
+// never called and the model must fall back to its usual index exchange.
+type IndexAckModel interface {
+	// The peer device applied the index data we sent for the folder, up to
+	// and including the given sequence number. Calls for a folder are made
+	// with increasing sequence numbers.
+	IndexAcked(deviceID DeviceID, folder string, sequence int64)
+}
+
+// An IndexRejectModel is told when the other side failed to apply index
+// data we sent it, e.g. because its disk is full. A Model passed to
+// NewConnection that also implements IndexRejectModel makes the connection
+// advertise CapabilityIndexReject, and the other side then reports such
+// failures when it was created with WithIndexRejection, instead of closing
+// the connection.
+type IndexRejectModel interface {
+	// The peer device failed to apply an Index or IndexUpdate we sent for
+	// the folder, with files up to the given sequence number, for the
+	// given reason. Nothing more is acknowledged for the folder on the
+	// connection, so the model should send the files again later, e.g.
+	// after reconnecting.
+	IndexRejected(deviceID DeviceID, folder string, sequence int64, reason string)
+}
+
+// indexAcks keeps the highest acknowledged sequence number per folder.
+type indexAcks struct {
+	mut  sync.Mutex
+	seqs map[string]int64
+}
+
+// update records the sequence number, returning true if it is higher than
+// any acknowledged before for the folder.
+func (a *indexAcks) update(folder string, seq int64) bool {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	if seq <= a.seqs[folder] {
+		return false
+	}
+	if a.seqs == nil {
+		a.seqs = make(map[string]int64)
+	}
+	a.seqs[folder] = seq
+	return true
+}
+
+// ackIndex acknowledges the files of an index message applied by the
+// model, if the other side wants to know. It must be called before the
+// files are reused.
+func (c *rawConnection) ackIndex(folder string, files []FileInfo) {
+	if !c.peerSupports(CapabilityIndexAck) || c.rejectedFolders[folder] {
+		return
+	}
+	seq := maxSequence(files)
+	if seq == 0 {
+		return
+	}
+	// Not waiting for the writer here, as the dispatcher mustn't block on
+	// it; the other side copes with acks out of order.
+	go c.send(context.Background(), &IndexAck{Folder: folder, Sequence: seq}, nil)
+}
+
+// rejectIndex tells the other side that the model failed to apply the
+// files of an index message, returning false when we aren't to, in which
+// case the connection closes instead. As acks are cumulative, nothing more
+// is acknowledged for the folder afterwards. It must be called before the
+// files are reused.
+func (c *rawConnection) rejectIndex(folder string, files []FileInfo, err error) bool {
+	if !c.indexRejection || !c.peerSupports(CapabilityIndexReject) {
+		return false
+	}
+	l.Debugf("Rejecting index data for %v from %v: %v", folder, c.id, err)
+	if c.rejectedFolders == nil {
+		c.rejectedFolders = make(map[string]bool)
+	}
+	c.rejectedFolders[folder] = true
+	go c.send(context.Background(), &IndexAck{Folder: folder, Sequence: maxSequence(files), Error: err.Error()}, nil)
+	return true
+}
+
+// maxSequence returns the highest sequence number among the files.
+func maxSequence(files []FileInfo) int64 {
+	var seq int64
+	for _, f := range files {
+		if f.Sequence > seq {
+			seq = f.Sequence
+		}
+	}
+	return seq
+}
+
+func (c *rawConnection) handleIndexAck(ack IndexAck) {
+	if ack.Error != "" {
+		l.Debugf("IndexAck(%v, %v, %d) rejected: %s", c.id, ack.Folder, ack.Sequence, ack.Error)
+		if c.indexRejectModel != nil {
+			c.indexRejectModel.IndexRejected(c.id, ack.Folder, ack.Sequence, ack.Error)
+		}
+		return
+	}
+	l.Debugf("IndexAck(%v, %v, %d)", c.id, ack.Folder, ack.Sequence)
+	if c.indexAckModel == nil || !c.indexAcks.update(ack.Folder, ack.Sequence) {
+		return
+	}
+	c.indexAckModel.IndexAcked(c.id, ack.Folder, ack.Sequence)
+}
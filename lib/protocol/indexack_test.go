@@ -0,0 +1,229 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+type indexAckTestModel struct {
+	*TestModel
+	acks chan int64
+}
+
+func (m *indexAckTestModel) IndexAcked(_ DeviceID, _ string, sequence int64) {
+	m.acks <- sequence
+}
+
+// failingIndexModel fails index updates with files above a sequence
+// number.
+type failingIndexModel struct {
+	*TestModel
+	failAbove int64
+	received  chan int64
+}
+
+func (m *failingIndexModel) Index(_ DeviceID, _ string, files []FileInfo) error {
+	return m.apply(files)
+}
+
+func (m *failingIndexModel) IndexUpdate(_ DeviceID, _ string, files []FileInfo) error {
+	return m.apply(files)
+}
+
+func (m *failingIndexModel) apply(files []FileInfo) error {
+	for _, f := range files {
+		if m.failAbove > 0 && f.Sequence > m.failAbove {
+			return errors.New("disk full")
+		}
+	}
+	for _, f := range files {
+		m.received <- f.Sequence
+	}
+	return nil
+}
+
+func TestIndexAckResume(t *testing.T) {
+	var files []FileInfo
+	for i := 1; i <= 10; i++ {
+		files = append(files, FileInfo{
+			Name:     fmt.Sprintf("file%d", i),
+			Type:     FileInfoTypeDirectory,
+			Sequence: int64(i),
+		})
+	}
+
+	connect := func(m0, m1 Model) (c0, c1 Connection, done func()) {
+		ar, aw := io.Pipe()
+		br, bw := io.Pipe()
+		c0 = NewConnection(c1ID, ar, bw, m0, "c0", CompressNever, WithoutPinger())
+		c0.Start()
+		c1 = NewConnection(c0ID, br, aw, m1, "c1", CompressNever, WithoutPinger())
+		c1.Start()
+		c0.ClusterConfig(ClusterConfig{})
+		c1.ClusterConfig(ClusterConfig{})
+		return c0, c1, func() {
+			ar.Close()
+			br.Close()
+		}
+	}
+	expectAck := func(m *indexAckTestModel, exp int64) {
+		t.Helper()
+		select {
+		case seq := <-m.acks:
+			if seq != exp {
+				t.Fatalf("Acked %d, expected %d", seq, exp)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for ack of %d", exp)
+		}
+	}
+	ctx := context.Background()
+
+	// The other side applies the first half, then fails and disconnects.
+	m0 := &indexAckTestModel{newTestModel(), make(chan int64, 10)}
+	m1 := &failingIndexModel{newTestModel(), 5, make(chan int64, 10)}
+	c0, _, done := connect(m0, m1)
+	if err := c0.Index(ctx, "default", files[:5]); err != nil {
+		t.Fatal(err)
+	}
+	expectAck(m0, 5)
+	if err := c0.IndexUpdate(ctx, "default", files[5:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := m1.closedError(); err == nil {
+		t.Fatal("Expected the connection to close")
+	}
+	done()
+	select {
+	case seq := <-m0.acks:
+		t.Fatalf("Unexpected ack of %d", seq)
+	default:
+	}
+
+	// After reconnecting, only what wasn't acked is sent.
+	m0 = &indexAckTestModel{newTestModel(), make(chan int64, 10)}
+	m1 = &failingIndexModel{newTestModel(), 0, make(chan int64, 10)}
+	c0, _, done = connect(m0, m1)
+	defer done()
+	if err := c0.IndexUpdate(ctx, "default", files[5:]); err != nil {
+		t.Fatal(err)
+	}
+	expectAck(m0, 10)
+	for i := int64(6); i <= 10; i++ {
+		if seq := <-m1.received; seq != i {
+			t.Errorf("Received %d, expected %d", seq, i)
+		}
+	}
+}
+
+func TestIndexAckUnsupported(t *testing.T) {
+	// Without the capability on our side, the other side doesn't ack.
+	received := make(chan struct{})
+	m1 := newTestModel()
+	m1.indexFn = func(DeviceID, string, []FileInfo) {
+		close(received)
+	}
+	acks := make(chan struct{}, 1)
+	tap := WithTap(func(_ Direction, msgType MessageType, _ int32, _ int) {
+		if msgType == messageTypeIndexAck {
+			acks <- struct{}{}
+		}
+	})
+	c0, _ := newTestConnections(t, newTestModel(), m1, CompressNever, []Option{tap}, nil)
+
+	if err := c0.Index(context.Background(), "default", []FileInfo{{Name: "foo", Type: FileInfoTypeDirectory, Sequence: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	<-received
+	select {
+	case <-acks:
+		t.Error("Received an ack without asking for it")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+type indexRejectTestModel struct {
+	*indexAckTestModel
+	rejects chan string
+}
+
+func (m *indexRejectTestModel) IndexRejected(_ DeviceID, folder string, sequence int64, reason string) {
+	m.rejects <- fmt.Sprintf("%s/%d: %s", folder, sequence, reason)
+}
+
+func TestIndexReject(t *testing.T) {
+	var files []FileInfo
+	for i := 1; i <= 10; i++ {
+		files = append(files, FileInfo{
+			Name:     fmt.Sprintf("file%d", i),
+			Type:     FileInfoTypeDirectory,
+			Sequence: int64(i),
+		})
+	}
+
+	for _, wanted := range []bool{true, false} {
+		t.Run(fmt.Sprintf("wanted=%v", wanted), func(t *testing.T) {
+			// The other side fails to apply files above 5.
+			acks := &indexAckTestModel{newTestModel(), make(chan int64, 10)}
+			var m0 Model = acks
+			rejects := make(chan string, 10)
+			if wanted {
+				m0 = &indexRejectTestModel{acks, rejects}
+			}
+			m1 := &failingIndexModel{newTestModel(), 5, make(chan int64, 20)}
+			c0, c1 := newTestConnections(t, m0, m1, CompressNever, nil, []Option{WithIndexRejection()})
+
+			ctx := context.Background()
+			if err := c0.Index(ctx, "default", files[:5]); err != nil {
+				t.Fatal(err)
+			}
+			if seq := <-acks.acks; seq != 5 {
+				t.Fatalf("Acked %d, expected 5", seq)
+			}
+			if err := c0.IndexUpdate(ctx, "default", files[5:]); err != nil {
+				t.Fatal(err)
+			}
+			if !wanted {
+				// Without anyone to tell, the connection closes as before.
+				if err := m1.closedError(); err == nil {
+					t.Fatal("Expected the connection to close")
+				}
+				return
+			}
+			select {
+			case reject := <-rejects:
+				if reject != "default/10: disk full" {
+					t.Errorf("Unexpected rejection %q", reject)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for rejection")
+			}
+
+			// The connection carries on, but the folder is no longer
+			// acked, unlike others.
+			if err := c0.IndexUpdate(ctx, "default", files[:1]); err != nil {
+				t.Fatal(err)
+			}
+			if err := c0.IndexUpdate(ctx, "other", files[:2]); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case seq := <-acks.acks:
+				if seq != 2 {
+					t.Errorf("Acked %d, expected only 2 for the other folder", seq)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for ack")
+			}
+			if c1.Closed() {
+				t.Error("Connection closed after rejecting index data")
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import "sync"
+
+// maxPooledIndexFiles is the capacity above which index slices are left to
+// the garbage collector rather than kept for reuse, so that a single huge
+// index doesn't pin its memory forever.
+const maxPooledIndexFiles = 1 << 14
+
+var indexFilesPool = sync.Pool{
+	New: func() interface{} {
+		return new([]FileInfo)
+	},
+}
+
+// getIndexFiles returns an empty slice, with the capacity of one used
+// before if there is one, for unmarshalling the files of an index into.
+func getIndexFiles() []FileInfo {
+	fs := indexFilesPool.Get().(*[]FileInfo)
+	return (*fs)[:0]
+}
+
+// putIndexFiles makes the backing array of the slice available for reuse.
+// The slice must not be used afterwards.
+func putIndexFiles(fs []FileInfo) {
+	if cap(fs) == 0 || cap(fs) > maxPooledIndexFiles {
+		return
+	}
+	// Don't keep what the files refer to alive.
+	fs = fs[:cap(fs)]
+	for i := range fs {
+		fs[i] = FileInfo{}
+	}
+	fs = fs[:0]
+	indexFilesPool.Put(&fs)
+}
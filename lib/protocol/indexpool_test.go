@@ -0,0 +1,103 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func encodedIndexUpdates(t testing.TB, n, files int) []byte {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, CompressNever)
+	for i := 0; i < n; i++ {
+		idx := &IndexUpdate{Folder: "default"}
+		for j := 0; j < files; j++ {
+			idx.Files = append(idx.Files, FileInfo{
+				Name:    fmt.Sprintf("file%d-%d", i, j),
+				Size:    128,
+				Version: Vector{}.Update(1),
+				Blocks:  []BlockInfo{{Size: 128, Hash: make([]byte, 32)}},
+			})
+		}
+		if err := enc.Encode(idx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeIndexReuse(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader(encodedIndexUpdates(t, 10, 100)))
+	dec.reuseIndex = true
+	for i := 0; i < 10; i++ {
+		msg, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := msg.(*IndexUpdate).Files
+		if len(files) != 100 {
+			t.Fatalf("Got %d files, expected 100", len(files))
+		}
+		for j, f := range files {
+			if exp := fmt.Sprintf("file%d-%d", i, j); f.Name != exp || len(f.Blocks) != 1 {
+				t.Fatalf("Got file %q with %d blocks, expected %q with one", f.Name, len(f.Blocks), exp)
+			}
+		}
+		kept := files[:1]
+		putIndexFiles(files)
+		if kept[0].Name != "" {
+			t.Error("Released files were not cleared")
+		}
+	}
+}
+
+func TestIndexReuse(t *testing.T) {
+	received := make(chan []FileInfo, 2)
+	m0 := newTestModel()
+	m0.indexFn = func(_ DeviceID, _ string, files []FileInfo) {
+		// The slice is reused, so must be copied.
+		received <- append([]FileInfo(nil), files...)
+	}
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, []Option{WithIndexReuse()}, nil)
+
+	for _, name := range []string{"foo", "bar"} {
+		if err := c1.Index(context.Background(), "default", []FileInfo{{Name: name, Type: FileInfoTypeDirectory}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"foo", "bar"} {
+		select {
+		case files := <-received:
+			if len(files) != 1 || files[0].Name != name {
+				t.Errorf("Got files %v, expected just %q", files, name)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for index")
+		}
+	}
+}
+
+func BenchmarkDecodeIndexUpdates(b *testing.B) {
+	data := encodedIndexUpdates(b, 1, 1000)
+	for _, reuse := range []bool{false, true} {
+		b.Run(fmt.Sprintf("reuse=%v", reuse), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				dec := NewDecoder(bytes.NewReader(data))
+				dec.reuseIndex = reuse
+				msg, err := dec.Decode()
+				if err != nil {
+					b.Fatal(err)
+				}
+				if reuse {
+					putIndexFiles(msg.(*IndexUpdate).Files)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+// IndexSize returns how many bytes sending the files with Index or
+// IndexUpdate would take on the wire, without sending anything: the size
+// of the messages without compression, and with the compression currently
+// in effect for the connection. The messages are encoded as they would be,
+// blocks packed and split as configured, into a sink that only counts the
+// bytes. As compressing the messages is most of the work, this is about as
+// expensive as sending them. Before the handshake the estimate assumes a
+// peer without any capabilities, and a file too large for a message counts
+// as is. Framing on streams may add a few bytes per 64 KiB.
+func (c *rawConnection) IndexSize(folder string, idx []FileInfo) (uncompressed, estimatedCompressed int) {
+	if c.peerSupports(CapabilityPackedBlocks) {
+		idx = packBlocks(idx)
+	}
+	batches, err := splitIndex(folder, idx, c.maxIndexLen, c.MaxIndexFiles())
+	if err != nil {
+		batches = [][]FileInfo{idx}
+	}
+
+	var plain, compressed byteCounter
+	plainEnc := NewEncoder(&plain, CompressNever)
+	plainEnc.SetChecksums(c.checksums)
+	compressedEnc := NewEncoder(&compressed, c.Compression())
+	compressedEnc.SetChecksums(c.checksums)
+	for _, files := range batches {
+		msg := &IndexUpdate{Folder: folder, Files: files}
+		// Writing to a byteCounter doesn't fail, and neither does
+		// marshalling an index.
+		_ = plainEnc.Encode(msg)
+		_ = compressedEnc.Encode(msg)
+	}
+	return int(plain), int(compressed)
+}
+
+// A byteCounter is a writer that discards what is written, keeping count of
+// the bytes.
+type byteCounter int
+
+func (b *byteCounter) Write(bs []byte) (int, error) {
+	*b += byteCounter(len(bs))
+	return len(bs), nil
+}
@@ -0,0 +1,52 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestIndexSize(t *testing.T) {
+	var r0 tapRecorder
+	c0, _ := newTestConnections(t, newTestModel(), newTestModel(), CompressAlways, []Option{WithTap(r0.tap)}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c0.WaitHandshake(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var files []FileInfo
+	for i := 0; i < 200; i++ {
+		files = append(files, FileInfo{
+			Name:    fmt.Sprintf("some/directory/file%d", i),
+			Type:    FileInfoTypeFile,
+			Size:    1234,
+			Version: Vector{}.Update(1),
+			Blocks:  []BlockInfo{{Size: 1234, Hash: make([]byte, 32)}},
+		})
+	}
+
+	uncompressed, compressed := c0.IndexSize("default", files)
+	if min := (&IndexUpdate{Folder: "default", Files: files}).ProtoSize(); uncompressed < min {
+		t.Errorf("Uncompressed size %d is less than the message size %d", uncompressed, min)
+	}
+	if compressed >= uncompressed {
+		t.Errorf("Compressed size %d is not less than uncompressed size %d", compressed, uncompressed)
+	}
+
+	if err := c0.IndexUpdate(ctx, "default", files); err != nil {
+		t.Fatal(err)
+	}
+	rec, ok := r0.find(DirectionOut, messageTypeIndexUpdate)
+	if !ok {
+		t.Fatal("Index update not tapped when written")
+	}
+	// Frames on streams have a slightly larger header.
+	if diff := rec.size - compressed; diff < 0 || diff > 16 {
+		t.Errorf("Sent %d bytes, estimated %d", rec.size, compressed)
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+// splitIndex returns the files in batches that each fit in an index
+// message of at most maxLen bytes and, unless it is zero, maxFiles files,
+// or ErrIndexTooLarge if a single file doesn't. Index and IndexUpdate
+// messages have the same layout, so the size is the same for both.
+func splitIndex(folder string, files []FileInfo, maxLen, maxFiles int) ([][]FileInfo, error) {
+	base := (&IndexUpdate{Folder: folder}).ProtoSize()
+	var batches [][]FileInfo
+	start, size := 0, base
+	for i := range files {
+		l := files[i].ProtoSize()
+		l += 1 + sovBep(uint64(l))
+		if base+l > maxLen {
+			return nil, ErrIndexTooLarge
+		}
+		if size+l > maxLen || (maxFiles > 0 && i-start == maxFiles) {
+			batches = append(batches, files[start:i])
+			start, size = i, base
+		}
+		size += l
+	}
+	return append(batches, files[start:]), nil
+}
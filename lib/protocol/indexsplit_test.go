@@ -0,0 +1,134 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSplitIndex(t *testing.T) {
+	var files []FileInfo
+	for i := 0; i < 100; i++ {
+		files = append(files, FileInfo{Name: fmt.Sprintf("file%d", i), Type: FileInfoTypeDirectory})
+	}
+	const maxLen = 200
+
+	batches, err := splitIndex("default", files, maxLen, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) < 2 {
+		t.Fatalf("Got %d batches, expected several", len(batches))
+	}
+	var n int
+	for _, batch := range batches {
+		if size := (&IndexUpdate{Folder: "default", Files: batch}).ProtoSize(); size > maxLen {
+			t.Errorf("Batch of %d bytes exceeds %d", size, maxLen)
+		}
+		for _, f := range batch {
+			if f.Name != files[n].Name {
+				t.Fatalf("Got file %q at %d, expected %q", f.Name, n, files[n].Name)
+			}
+			n++
+		}
+	}
+	if n != len(files) {
+		t.Errorf("Got %d files in batches, expected %d", n, len(files))
+	}
+
+	large := []FileInfo{{Name: string(make([]byte, maxLen))}}
+	if _, err := splitIndex("default", large, maxLen, 0); err != ErrIndexTooLarge {
+		t.Errorf("Splitting a file too large for a message returned %v", err)
+	}
+}
+
+func TestIndexTooLarge(t *testing.T) {
+	var files []FileInfo
+	for i := 0; i < 100; i++ {
+		files = append(files, FileInfo{Name: fmt.Sprintf("file%d", i), Type: FileInfoTypeDirectory})
+	}
+
+	for _, split := range []bool{false, true} {
+		t.Run(fmt.Sprintf("split=%v", split), func(t *testing.T) {
+			var mut sync.Mutex
+			var indexes, updates int
+			var received []string
+			record := func(files []FileInfo) {
+				for _, f := range files {
+					received = append(received, f.Name)
+				}
+			}
+			m1 := newTestModel()
+			m1.indexFn = func(_ DeviceID, _ string, files []FileInfo) {
+				mut.Lock()
+				defer mut.Unlock()
+				indexes++
+				record(files)
+			}
+			m1.indexUpdateFn = func(_ DeviceID, _ string, files []FileInfo) {
+				mut.Lock()
+				defer mut.Unlock()
+				updates++
+				record(files)
+			}
+
+			var opts []Option
+			if split {
+				opts = append(opts, WithIndexSplitting())
+			}
+			c0, _ := newTestConnections(t, newTestModel(), m1, CompressNever, opts, nil)
+			c0.maxIndexLen = 200
+
+			err := c0.Index(context.Background(), "default", files)
+			if !split {
+				if err != ErrIndexTooLarge {
+					t.Fatalf("Index returned %v, expected ErrIndexTooLarge", err)
+				}
+				// Something that fits is still sent.
+				if err := c0.Index(context.Background(), "default", files[:1]); err != nil {
+					t.Fatal(err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := files
+			if !split {
+				expected = files[:1]
+			}
+			deadline := time.Now().Add(time.Second)
+			for {
+				mut.Lock()
+				n := len(received)
+				mut.Unlock()
+				if n >= len(expected) || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			mut.Lock()
+			defer mut.Unlock()
+			if len(received) != len(expected) {
+				t.Fatalf("Received %d files, expected %d", len(received), len(expected))
+			}
+			for i, name := range received {
+				if name != expected[i].Name {
+					t.Errorf("Received %q at %d, expected %q", name, i, expected[i].Name)
+				}
+			}
+			if indexes != 1 {
+				t.Errorf("Received %d indexes, expected 1", indexes)
+			}
+			if split && updates == 0 {
+				t.Error("Received no index updates for the rest of the files")
+			} else if !split && updates != 0 {
+				t.Errorf("Received %d index updates, expected none", updates)
+			}
+		})
+	}
+}
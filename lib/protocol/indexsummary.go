@@ -0,0 +1,116 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"path/filepath"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// An IndexSummaryModel receives summaries of what the other side already
+// knows about our files, which it sends after connecting to save us from
+// sending a full index. A Model passed to NewConnection that also
+// implements IndexSummaryModel makes the connection advertise
+// CapabilityIndexSummary.
+//
+// The model should then send the files returned by summary.Filter in an
+// IndexUpdate, instead of sending everything in an Index. Note that it must
+// not be an Index, as that would replace everything the other side knows
+// with just the files that differ. Falling back to a full Index is always
+// correct, e.g. when the summary arrives after the index has been sent, and
+// is what happens when the other side doesn't send a summary at all.
+type IndexSummaryModel interface {
+	// The peer device summarized what it knows about our files in the
+	// folder
+	IndexSummary(deviceID DeviceID, folder string, summary *IndexSummary) error
+}
+
+// SummaryHash returns a hash of the name and version of the file, as used
+// in an IndexSummary. The name is hashed in wire format, i.e. with forward
+// slashes and NFC normalized, so the hash is the same on both sides.
+func (f FileInfo) SummaryHash() uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(norm.NFC.String(filepath.ToSlash(f.Name))))
+	var buf [17]byte
+	for _, c := range f.Version.Counters {
+		binary.BigEndian.PutUint64(buf[0:], uint64(c.ID))
+		binary.BigEndian.PutUint64(buf[8:], c.Value)
+		h.Write(buf[:16])
+	}
+	// Deletion and invalidity are part of the version on the wire, but
+	// don't always bump the version vector.
+	buf[16] = 0
+	if f.Deleted {
+		buf[16] |= 1
+	}
+	if f.RawInvalid {
+		buf[16] |= 2
+	}
+	h.Write(buf[16:])
+	return h.Sum64()
+}
+
+// NewIndexSummary returns a summary of the given files, which are what we
+// know about the files of the other side in the folder.
+func NewIndexSummary(folder string, files []FileInfo) *IndexSummary {
+	s := &IndexSummary{
+		Folder: folder,
+		Hashes: make([]uint64, len(files)),
+	}
+	for i, f := range files {
+		s.Hashes[i] = f.SummaryHash()
+	}
+	return s
+}
+
+// Filter returns the files that the other side doesn't already know about,
+// according to the summary.
+func (s *IndexSummary) Filter(files []FileInfo) []FileInfo {
+	known := make(map[uint64]struct{}, len(s.Hashes))
+	for _, h := range s.Hashes {
+		known[h] = struct{}{}
+	}
+	var res []FileInfo
+	for _, f := range files {
+		if _, ok := known[f.SummaryHash()]; !ok {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// IndexSummary sends a summary of the given files, which are what we know
+// about the files of the other side in the folder, so that it can send
+// only what differs instead of its full index. It waits for the handshake
+// to complete, and returns ErrUnsupported if the other side doesn't accept
+// summaries; it will then send its full index as usual.
+func (c *rawConnection) IndexSummary(ctx context.Context, folder string, files []FileInfo) error {
+	if err := c.WaitHandshake(ctx); err != nil {
+		return err
+	}
+	if !c.peerCapabilities.Has(CapabilityIndexSummary) {
+		return ErrUnsupported
+	}
+	c.idxMut.Lock()
+	defer c.idxMut.Unlock()
+	if c.isQuiescing() {
+		return ErrQuiescing
+	}
+	if !c.send(ctx, NewIndexSummary(folder, files), nil) {
+		return ErrClosed
+	}
+	return nil
+}
+
+func (c *rawConnection) handleIndexSummary(summary IndexSummary) error {
+	l.Debugf("IndexSummary(%v, %v, %d files)", c.id, summary.Folder, len(summary.Hashes))
+	if c.summaryModel == nil {
+		// We didn't ask for it; the full index is sent regardless.
+		return nil
+	}
+	return c.summaryModel.IndexSummary(c.id, summary.Folder, &summary)
+}
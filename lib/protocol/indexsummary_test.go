@@ -0,0 +1,82 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type testSummaryModel struct {
+	*TestModel
+	summaries chan *IndexSummary
+}
+
+func (t *testSummaryModel) IndexSummary(deviceID DeviceID, folder string, summary *IndexSummary) error {
+	t.summaries <- summary
+	return nil
+}
+
+func TestIndexSummaryFilter(t *testing.T) {
+	v1 := Vector{}.Update(1)
+	v2 := v1.Update(2)
+	known := []FileInfo{
+		{Name: "same", Version: v1},
+		{Name: "changed", Version: v1},
+		{Name: "deleted", Version: v1},
+		{Name: "gone", Version: v1},
+	}
+	files := []FileInfo{
+		{Name: "same", Version: v1},
+		{Name: "changed", Version: v2},
+		{Name: "deleted", Version: v1, Deleted: true},
+		{Name: "new", Version: v1},
+	}
+
+	var names []string
+	for _, f := range NewIndexSummary("default", known).Filter(files) {
+		names = append(names, f.Name)
+	}
+	if len(names) != 3 || names[0] != "changed" || names[1] != "deleted" || names[2] != "new" {
+		t.Errorf("Filtered files %v, expected changed, deleted and new", names)
+	}
+}
+
+func TestSummaryHashNormalized(t *testing.T) {
+	nfc := FileInfo{Name: "a/\u00e5", Version: Vector{}.Update(1)}
+	nfd := FileInfo{Name: "a/a\u030a", Version: nfc.Version}
+	if nfc.SummaryHash() != nfd.SummaryHash() {
+		t.Error("Hash differs between NFC and NFD names")
+	}
+	other := FileInfo{Name: "a/b", Version: nfc.Version}
+	if nfc.SummaryHash() == other.SummaryHash() {
+		t.Error("Hash is the same for different names")
+	}
+}
+
+func TestIndexSummary(t *testing.T) {
+	m0 := &testSummaryModel{newTestModel(), make(chan *IndexSummary, 1)}
+	c0, c1 := newTestConnections(t, m0, newTestModel(), CompressAlways, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	files := []FileInfo{{Name: "foo", Version: Vector{}.Update(1)}}
+	if err := c1.IndexSummary(ctx, "default", files); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	select {
+	case summary := <-m0.summaries:
+		if summary.Folder != "default" || len(summary.Hashes) != 1 || summary.Hashes[0] != files[0].SummaryHash() {
+			t.Errorf("Unexpected summary %v", summary)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for summary")
+	}
+
+	// The other way around isn't supported.
+	if err := c0.IndexSummary(ctx, "default", files); err != ErrUnsupported {
+		t.Errorf("Unexpected error %v, expected %v", err, ErrUnsupported)
+	}
+}
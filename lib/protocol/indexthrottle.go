@@ -0,0 +1,97 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// An indexThrottle holds back index messages sent within the minimum
+// interval after the previous one, per folder, and sends the accumulated
+// state once the interval has passed.
+type indexThrottle struct {
+	interval time.Duration
+	mut      sync.Mutex // also held while sending, to keep messages in order
+	folders  map[string]*throttledIndex
+}
+
+type throttledIndex struct {
+	lastSent time.Time
+	timer    *time.Timer // non-nil while files are pending
+	full     bool        // the pending files are a full index
+	files    []FileInfo
+	names    map[string]int // index in files, by name
+}
+
+func newIndexThrottle(interval time.Duration) *indexThrottle {
+	return &indexThrottle{
+		interval: interval,
+		folders:  make(map[string]*throttledIndex),
+	}
+}
+
+// throttledIndex sends the files right away if nothing was sent for the
+// folder within the interval, or otherwise merges them into what is
+// pending. A full index replaces whatever is pending; an update replaces
+// pending files of the same name. Only errors from sending right away are
+// returned; those of pending files sent later are logged.
+func (c *rawConnection) throttledIndex(ctx context.Context, folder string, files []FileInfo, full bool) error {
+	t := c.indexThrottle
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	ti, ok := t.folders[folder]
+	if !ok {
+		ti = &throttledIndex{names: make(map[string]int)}
+		t.folders[folder] = ti
+	}
+
+	now := time.Now()
+	if ti.timer == nil && now.Sub(ti.lastSent) >= t.interval {
+		ti.lastSent = now
+		return c.sendIndex(ctx, folder, files, full)
+	}
+
+	if full {
+		ti.full = true
+		ti.files = ti.files[:0]
+		for name := range ti.names {
+			delete(ti.names, name)
+		}
+	}
+	for _, f := range files {
+		if i, ok := ti.names[f.Name]; ok {
+			ti.files[i] = f
+			continue
+		}
+		ti.names[f.Name] = len(ti.files)
+		ti.files = append(ti.files, f)
+	}
+
+	if ti.timer == nil {
+		ti.timer = time.AfterFunc(ti.lastSent.Add(t.interval).Sub(now), func() {
+			c.flushThrottledIndex(folder)
+		})
+	}
+	return nil
+}
+
+// flushThrottledIndex sends what is pending for the folder.
+func (c *rawConnection) flushThrottledIndex(folder string) {
+	t := c.indexThrottle
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	ti := t.folders[folder]
+	files, full := ti.files, ti.full
+	ti.files, ti.full, ti.timer = nil, false, nil
+	for name := range ti.names {
+		delete(ti.names, name)
+	}
+	ti.lastSent = time.Now()
+	if err := c.sendIndex(context.Background(), folder, files, full); err != nil && err != ErrQuiescing {
+		l.Warnf("Dropping throttled index for folder %q to %v: %v", folder, c.id, err)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIndexThrottle(t *testing.T) {
+	var mut sync.Mutex
+	var indexes, updates int
+	latest := make(map[string]FileInfo)
+	record := func(files []FileInfo) {
+		for _, f := range files {
+			latest[f.Name] = f
+		}
+	}
+	m1 := newTestModel()
+	m1.indexFn = func(_ DeviceID, _ string, files []FileInfo) {
+		mut.Lock()
+		defer mut.Unlock()
+		indexes++
+		latest = make(map[string]FileInfo)
+		record(files)
+	}
+	m1.indexUpdateFn = func(_ DeviceID, _ string, files []FileInfo) {
+		mut.Lock()
+		defer mut.Unlock()
+		updates++
+		record(files)
+	}
+
+	const interval = 100 * time.Millisecond
+	c0, _ := newTestConnections(t, newTestModel(), m1, CompressNever, []Option{WithIndexThrottle(interval)}, nil)
+
+	file := func(name string, version uint64) FileInfo {
+		return FileInfo{
+			Name:    name,
+			Type:    FileInfoTypeDirectory,
+			Version: Vector{}.Update(ShortID(version)),
+		}
+	}
+
+	ctx := context.Background()
+	t0 := time.Now()
+	if err := c0.Index(ctx, "default", []FileInfo{file("a", 1), file("b", 1)}); err != nil {
+		t.Fatal(err)
+	}
+	const calls = 100
+	for i := 2; i <= calls; i++ {
+		files := []FileInfo{file("a", uint64(i))}
+		if i%10 == 0 {
+			files = append(files, file(fmt.Sprintf("c%d", i), uint64(i)))
+		}
+		if err := c0.IndexUpdate(ctx, "default", files); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if time.Since(t0) > interval {
+		t.Skip("Too slow to test throttling")
+	}
+
+	// The first index goes out right away, all updates as one message at
+	// the end of the interval.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mut.Lock()
+		done := updates > 0
+		mut.Unlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for index update")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(2 * interval)
+
+	mut.Lock()
+	defer mut.Unlock()
+	if indexes != 1 || updates != 1 {
+		t.Errorf("Expected one index and one update, got %d and %d", indexes, updates)
+	}
+	if len(latest) != 12 {
+		t.Errorf("Expected 12 files, got %d", len(latest))
+	}
+	if !latest["a"].Version.Equal(file("a", calls).Version) {
+		t.Errorf("Expected the latest version of a, got %v", latest["a"].Version)
+	}
+	if !latest["b"].Version.Equal(file("b", 1).Version) {
+		t.Errorf("Expected b to be unchanged, got %v", latest["b"].Version)
+	}
+}
+
+func TestIndexThrottleFullIndexReplacesPending(t *testing.T) {
+	c := &rawConnection{indexThrottle: newIndexThrottle(time.Hour)}
+	c.indexThrottle.folders["default"] = &throttledIndex{
+		lastSent: time.Now(),
+		names:    make(map[string]int),
+	}
+
+	ctx := context.Background()
+	c.throttledIndex(ctx, "default", []FileInfo{{Name: "a"}, {Name: "b"}}, false)
+	c.throttledIndex(ctx, "default", []FileInfo{{Name: "c"}}, true)
+	c.throttledIndex(ctx, "default", []FileInfo{{Name: "c", Size: 1}, {Name: "d"}}, false)
+
+	ti := c.indexThrottle.folders["default"]
+	ti.timer.Stop()
+	if !ti.full {
+		t.Error("Expected a pending full index")
+	}
+	if len(ti.files) != 2 || ti.files[0].Name != "c" || ti.files[0].Size != 1 || ti.files[1].Name != "d" {
+		t.Errorf("Unexpected pending files %v", ti.files)
+	}
+}
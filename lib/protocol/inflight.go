@@ -0,0 +1,50 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"sort"
+	"time"
+)
+
+// An awaitingResponse is a message sent to the other side that we expect a
+// response to.
+type awaitingResponse struct {
+	ch      chan asyncResult
+	sent    time.Time
+	msgType MessageType
+	maxLen  int // most data accepted in the response to a request
+}
+
+// RequestStat describes a message we are waiting for the other side to
+// respond to, as returned by InFlight.
+type RequestStat struct {
+	ID   int32
+	Type MessageType // e.g. REQUEST, PING or PUSH
+	Age  time.Duration
+}
+
+// InFlight returns the messages sent to the other side that haven't been
+// responded to yet, oldest first. The age of a message counts from when it
+// was queued for sending.
+func (c *rawConnection) InFlight() []RequestStat {
+	now := time.Now()
+	c.awaitingMut.Lock()
+	stats := make([]RequestStat, 0, len(c.awaiting))
+	for id, ar := range c.awaiting {
+		stats = append(stats, RequestStat{
+			ID:   id,
+			Type: ar.msgType,
+			Age:  now.Sub(ar.sent),
+		})
+	}
+	c.awaitingMut.Unlock()
+
+	sort.Slice(stats, func(a, b int) bool {
+		if stats[a].Age != stats[b].Age {
+			return stats[a].Age > stats[b].Age
+		}
+		return stats[a].ID < stats[b].ID
+	})
+	return stats
+}
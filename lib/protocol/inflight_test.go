@@ -0,0 +1,97 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/testutils"
+)
+
+func TestInFlight(t *testing.T) {
+	release := make(chan struct{})
+	m0 := newTestModel()
+	m0.requestFn = func(string, string, int32, int64) (RequestResponse, error) {
+		<-release
+		return nil, ErrNoSuchFile
+	}
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+	if stats := c1.InFlight(); len(stats) != 0 {
+		t.Fatalf("Expected nothing in flight, got %v", stats)
+	}
+
+	done := make(chan struct{}, 2)
+	request := func() {
+		c1.Request(context.Background(), "default", "foo", 0, 10, nil, 0, false)
+		done <- struct{}{}
+	}
+	go request()
+	time.Sleep(10 * time.Millisecond)
+	go request()
+
+	var stats []RequestStat
+	deadline := time.Now().Add(time.Second)
+	for len(stats) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for requests in flight, got %v", stats)
+		}
+		time.Sleep(time.Millisecond)
+		stats = c1.InFlight()
+	}
+	for _, s := range stats {
+		if s.Type != messageTypeRequest {
+			t.Errorf("Unexpected type %v", s.Type)
+		}
+	}
+	if stats[0].Age < stats[1].Age || stats[0].Age < 10*time.Millisecond {
+		t.Errorf("Expected the oldest first, got %v", stats)
+	}
+
+	close(release)
+	<-done
+	<-done
+	if stats := c1.InFlight(); len(stats) != 0 {
+		t.Errorf("Expected nothing in flight after responses, got %v", stats)
+	}
+}
+
+func TestInFlightGivenUp(t *testing.T) {
+	t.Run("waiting for response", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		m0 := newTestModel()
+		m0.requestFn = func(string, string, int32, int64) (RequestResponse, error) {
+			<-release
+			return nil, ErrNoSuchFile
+		}
+		_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := c1.Request(ctx, "default", "foo", 0, 10, nil, 0, false); err != context.DeadlineExceeded {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if stats := c1.InFlight(); len(stats) != 0 {
+			t.Errorf("Expected nothing in flight after giving up, got %v", stats)
+		}
+	})
+
+	t.Run("sending", func(t *testing.T) {
+		// Nothing gets written, so the request is never sent.
+		c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, newTestModel(), "name", CompressNever, WithoutPinger()).(wireFormatConnection)
+		c.Start()
+		c.ClusterConfig(ClusterConfig{})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := c.Request(ctx, "default", "foo", 0, 10, nil, 0, false); err != ErrClosed {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if stats := c.InFlight(); len(stats) != 0 {
+			t.Errorf("Expected nothing in flight after failing to send, got %v", stats)
+		}
+	})
+}
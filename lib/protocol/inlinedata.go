@@ -0,0 +1,56 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"crypto/sha256"
+)
+
+// MaxInlineDataSize is the size up to which a file may carry its content
+// inline in index messages, see FileInfo.InlineData. It is small enough that
+// inline data doesn't noticeably bloat index messages, and large enough for
+// the many tiny files, such as configuration and source files, whose
+// transfer would otherwise be dominated by the round trip of a request.
+const MaxInlineDataSize = 4 << KiB
+
+// stripInlineData returns the files without inline data, for a peer that
+// doesn't support it, or otherwise without inline data that isn't valid for
+// its file, as the other side would refuse it. The given files are not
+// modified.
+func stripInlineData(fs []FileInfo, supported bool) []FileInfo {
+	var out []FileInfo
+	for i := range fs {
+		if len(fs[i].InlineData) == 0 || supported && validInlineData(fs[i]) {
+			continue
+		}
+		if supported {
+			l.Debugf("Not sending invalid inline data of %q", fs[i].Name)
+		}
+		if out == nil {
+			out = make([]FileInfo, len(fs))
+			copy(out, fs)
+		}
+		out[i].InlineData = nil
+	}
+	if out == nil {
+		return fs
+	}
+	return out
+}
+
+// validInlineData returns true if the inline data of the file is its entire
+// content, i.e. the data of its single block.
+func validInlineData(f FileInfo) bool {
+	if f.Type != FileInfoTypeFile || f.Deleted || f.IsInvalid() {
+		return false
+	}
+	if len(f.InlineData) > MaxInlineDataSize || int64(len(f.InlineData)) != f.Size {
+		return false
+	}
+	if len(f.Blocks) != 1 || f.Blocks[0].Offset != 0 || int(f.Blocks[0].Size) != len(f.InlineData) {
+		return false
+	}
+	hash := sha256.Sum256(f.InlineData)
+	return bytes.Equal(hash[:], f.Blocks[0].Hash)
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"crypto/sha256"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func fileWithInlineData(name string, data []byte) FileInfo {
+	hash := sha256.Sum256(data)
+	return FileInfo{
+		Name:         name,
+		Size:         int64(len(data)),
+		RawBlockSize: MinBlockSize,
+		Blocks:       []BlockInfo{{Size: int32(len(data)), Hash: hash[:]}},
+		InlineData:   data,
+	}
+}
+
+func TestStripInlineData(t *testing.T) {
+	valid := fileWithInlineData("valid", []byte("hello"))
+	wrongHash := fileWithInlineData("wrong hash", []byte("hello"))
+	wrongHash.InlineData = []byte("jello")
+	tooLarge := fileWithInlineData("too large", make([]byte, MaxInlineDataSize+1))
+	deleted := fileWithInlineData("deleted", []byte("hello"))
+	deleted.Deleted = true
+	deleted.Blocks = nil
+	files := []FileInfo{valid, wrongHash, tooLarge, deleted, {Name: "dir", Type: FileInfoTypeDirectory}}
+	orig := make([]FileInfo, len(files))
+	copy(orig, files)
+
+	stripped := stripInlineData(files, true)
+	if !reflect.DeepEqual(files, orig) {
+		t.Fatal("Stripping modified the given files")
+	}
+	if string(stripped[0].InlineData) != "hello" {
+		t.Error("Valid inline data was stripped")
+	}
+	for _, f := range stripped[1:] {
+		if len(f.InlineData) != 0 {
+			t.Errorf("%s: invalid inline data was kept", f.Name)
+		}
+		if err := checkFileInfoConsistency(f); err != nil {
+			t.Errorf("%s: %v", f.Name, err)
+		}
+	}
+	for _, f := range orig[1:4] {
+		if err := checkFileInfoConsistency(f); err != errInvalidInlineData {
+			t.Errorf("%s: consistency check returned %v, expected errInvalidInlineData", f.Name, err)
+		}
+	}
+
+	for _, f := range stripInlineData(files, false) {
+		if len(f.InlineData) != 0 {
+			t.Errorf("%s: inline data was kept for a peer without support", f.Name)
+		}
+	}
+	if plain := []FileInfo{{Name: "plain"}}; &stripInlineData(plain, true)[0] != &plain[0] {
+		t.Error("Files without inline data were copied")
+	}
+}
+
+func TestInlineDataIndex(t *testing.T) {
+	received := make(chan []FileInfo, 1)
+	m0 := newTestModel()
+	m0.indexFn = func(_ DeviceID, _ string, files []FileInfo) {
+		received <- files
+	}
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c1.WaitHandshake(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The blocks of the file are packed on the way, and the inline data
+	// checked against them once unpacked.
+	files := []FileInfo{fileWithInlineData("foo", []byte("hello"))}
+	if err := c1.Index(ctx, "default", files); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-received:
+		if !reflect.DeepEqual(got, files) {
+			t.Errorf("Received files differ from the sent ones")
+		}
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for index")
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// A LatencySummary describes the request latencies observed on a
+// connection, i.e. the time from sending a request until the response is
+// received. Percentiles are approximate, see latencyHistogram.
+type LatencySummary struct {
+	Count int64
+	Min   time.Duration
+	Max   time.Duration
+	P50   time.Duration
+	P99   time.Duration
+}
+
+// Each power of two (in microseconds) is split into this many buckets,
+// giving a worst case relative error of 25% for the percentiles.
+const latencySubBuckets = 4
+
+// latencyHistogram counts latencies in exponentially sized buckets. This
+// gives approximate percentiles for a fixed, small cost per observation
+// and no allocations.
+type latencyHistogram struct {
+	mut     sync.Mutex
+	count   int64
+	min     time.Duration
+	max     time.Duration
+	buckets [64 * latencySubBuckets]int64
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.mut.Lock()
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.buckets[latencyBucket(d)]++
+	h.mut.Unlock()
+}
+
+func (h *latencyHistogram) summary() LatencySummary {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return LatencySummary{
+		Count: h.count,
+		Min:   h.min,
+		Max:   h.max,
+		P50:   h.percentileLocked(0.50),
+		P99:   h.percentileLocked(0.99),
+	}
+}
+
+// percentileLocked returns the upper bound of the bucket containing the
+// given percentile, clamped to the observed min and max.
+func (h *latencyHistogram) percentileLocked(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	target := int64(p*float64(h.count) + 0.5)
+	if target < 1 {
+		target = 1
+	}
+	var seen int64
+	for i, n := range h.buckets {
+		seen += n
+		if seen >= target {
+			d := latencyBucketLimit(i)
+			if d < h.min {
+				return h.min
+			}
+			if d > h.max {
+				return h.max
+			}
+			return d
+		}
+	}
+	return h.max
+}
+
+// latencyBucket returns the bucket index for the given duration. The
+// first buckets are one microsecond wide, after that each power of two is
+// split into latencySubBuckets buckets.
+func latencyBucket(d time.Duration) int {
+	us := uint64(d / time.Microsecond)
+	if us < latencySubBuckets {
+		return int(us)
+	}
+	exp := bits.Len64(us) - 1
+	sub := (us >> uint(exp-2)) & (latencySubBuckets - 1)
+	return (exp-1)*latencySubBuckets + int(sub)
+}
+
+// latencyBucketLimit returns the exclusive upper bound of the given bucket.
+func latencyBucketLimit(i int) time.Duration {
+	if i < latencySubBuckets {
+		return time.Duration(i+1) * time.Microsecond
+	}
+	exp := uint(i/latencySubBuckets + 1)
+	sub := uint64(i % latencySubBuckets)
+	us := (latencySubBuckets + sub + 1) << (exp - 2)
+	return time.Duration(us) * time.Microsecond
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLatencyBuckets(t *testing.T) {
+	prev := -1
+	for us := 0; us < 1<<16; us++ {
+		d := time.Duration(us) * time.Microsecond
+		b := latencyBucket(d)
+		if b < prev {
+			t.Fatalf("bucket for %v is %d, lower than previous %d", d, b, prev)
+		}
+		prev = b
+		if limit := latencyBucketLimit(b); d >= limit {
+			t.Fatalf("%v is in bucket %d, but the bucket limit is %v", d, b, limit)
+		}
+		if b > 0 {
+			if limit := latencyBucketLimit(b - 1); d < limit {
+				t.Fatalf("%v is in bucket %d, but fits in the previous one (limit %v)", d, b, limit)
+			}
+		}
+	}
+}
+
+func TestLatencySummary(t *testing.T) {
+	var h latencyHistogram
+	if s := h.summary(); s != (LatencySummary{}) {
+		t.Errorf("expected empty summary, got %+v", s)
+	}
+
+	for i := 1; i <= 100; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+
+	s := h.summary()
+	if s.Count != 100 {
+		t.Errorf("Count %d != 100", s.Count)
+	}
+	if s.Min != time.Millisecond || s.Max != 100*time.Millisecond {
+		t.Errorf("Min/Max %v/%v, expected 1ms/100ms", s.Min, s.Max)
+	}
+	if s.P50 < 50*time.Millisecond || s.P50 > 63*time.Millisecond {
+		t.Errorf("P50 %v not within expected bucket error of 50ms", s.P50)
+	}
+	if s.P99 < 99*time.Millisecond || s.P99 > 100*time.Millisecond {
+		t.Errorf("P99 %v not within expected range", s.P99)
+	}
+}
+
+func TestRequestLatencyTracking(t *testing.T) {
+	c0, c1 := newTestConnections(t, newTestModel(), newTestModel(), CompressNever, nil, []Option{WithRequestLatencyTracking()})
+
+	for i := 0; i < 10; i++ {
+		if _, err := c1.Request(context.Background(), "default", "foo", 0, 0, nil, 0, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if s := c1.RequestLatency(); s.Count != 10 || s.Max < s.Min || s.P99 < s.P50 {
+		t.Errorf("unexpected summary %+v", s)
+	}
+	if s := c0.RequestLatency(); s.Count != 0 {
+		t.Errorf("unexpected summary without tracking enabled, %+v", s)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultListingLimit is the number of entries asked for per page when
+	// no limit is given, and MaxListingLimit the most that are sent per
+	// page.
+	DefaultListingLimit = 1000
+	MaxListingLimit     = 10000
+)
+
+// A ListingModel lists directories for the other side. A Model passed to
+// NewConnection that also implements ListingModel makes the connection
+// advertise CapabilityListing.
+type ListingModel interface {
+	// The peer device asked for the entries of the directory at path in
+	// the folder, the empty path being the root. Entries must be returned
+	// in order of name, starting with the first one after the given name
+	// (from the start when empty), and at most limit of them. Returning
+	// fewer than limit entries means there are no more.
+	Listing(deviceID DeviceID, folder, path, after string, limit int) ([]ListingEntry, error)
+}
+
+// ModTime returns the modification time of the entry.
+func (e ListingEntry) ModTime() time.Time {
+	return time.Unix(e.ModifiedS, int64(e.ModifiedNs))
+}
+
+// Listing asks the other side for a page of the entries of the directory at
+// path in the folder, the empty path being the root. The page starts after
+// the given name, or from the start when empty, and holds up to limit
+// entries (DefaultListingLimit when zero). The returned next is the name to
+// pass as after to get the next page, or empty when there are no more
+// entries. Listing waits for the handshake to complete, and returns
+// ErrUnsupported if the other side doesn't serve listings.
+func (c *rawConnection) Listing(ctx context.Context, folder, path, after string, limit int) (entries []ListingEntry, next string, err error) {
+	if path != "" {
+		if err := checkFilename(path); err != nil {
+			return nil, "", err
+		}
+	}
+	if limit <= 0 {
+		limit = DefaultListingLimit
+	}
+	if err := c.WaitHandshake(ctx); err != nil {
+		return nil, "", err
+	}
+	if !c.peerCapabilities.Has(CapabilityListing) {
+		return nil, "", ErrUnsupported
+	}
+
+	id, rc, err := c.newAwaiting(messageTypeListingRequest)
+	if err != nil {
+		return nil, "", err
+	}
+	ok := c.send(ctx, &ListingRequest{
+		ID:     id,
+		Folder: folder,
+		Path:   path,
+		After:  after,
+		Limit:  int32(limit),
+	}, nil)
+	if !ok {
+		c.forgetAwaiting(id)
+		return nil, "", ErrClosed
+	}
+
+	select {
+	case res, ok := <-rc:
+		if !ok {
+			return nil, "", ErrClosed
+		}
+		if res.err != nil {
+			return nil, "", res.err
+		}
+		resp, ok := res.msg.(*ListingResponse)
+		if !ok {
+			// The other side answered with the wrong kind of message
+			return nil, "", ErrGeneric
+		}
+		if err := checkListing(resp.Entries, after); err != nil {
+			return nil, "", err
+		}
+		return resp.Entries, resp.Next, nil
+	case <-ctx.Done():
+		c.forgetAwaiting(id)
+		return nil, "", ctx.Err()
+	}
+}
+
+// checkListing verifies that the entries are single, valid path
+// components, in order of name after the given one.
+func checkListing(entries []ListingEntry, after string) error {
+	prev := after
+	for i, e := range entries {
+		if strings.Contains(e.Name, "/") || checkFilename(e.Name) != nil {
+			return fmt.Errorf("protocol error: listing: invalid name %q", e.Name)
+		}
+		if (i > 0 || after != "") && e.Name <= prev {
+			return fmt.Errorf("protocol error: listing: %q out of order", e.Name)
+		}
+		prev = e.Name
+	}
+	return nil
+}
+
+func (c *rawConnection) handleListingRequest(req ListingRequest) {
+	resp := &ListingResponse{ID: req.ID}
+	err := ErrUnsupported
+	if c.listingModel != nil {
+		limit := int(req.Limit)
+		if limit <= 0 || limit > MaxListingLimit {
+			limit = MaxListingLimit
+		}
+		// One more than asked for, to tell whether there is a next page.
+		resp.Entries, err = c.listingModel.Listing(c.id, req.Folder, req.Path, req.After, limit+1)
+		if len(resp.Entries) > limit {
+			resp.Entries = resp.Entries[:limit]
+			resp.Next = resp.Entries[limit-1].Name
+		}
+	}
+	if err != nil {
+		resp.Entries, resp.Next = nil, ""
+	}
+	resp.Code = errorToCode(err)
+	c.send(context.Background(), resp, nil)
+}
+
+func (c *rawConnection) handleListingResponse(resp ListingResponse) error {
+	return c.resolveAwaiting(resp.ID, messageTypeListingResponse, asyncResult{err: codeToError(resp.Code), msg: &resp})
+}
@@ -0,0 +1,138 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+type listingTestModel struct {
+	*TestModel
+	dirs map[string][]ListingEntry // sorted by name
+}
+
+func (m *listingTestModel) Listing(_ DeviceID, _, path, after string, limit int) ([]ListingEntry, error) {
+	entries, ok := m.dirs[path]
+	if !ok {
+		return nil, ErrNoSuchFile
+	}
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].Name > after })
+	entries = entries[i:]
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+func TestListingEmpty(t *testing.T) {
+	_, c := newTestConnections(t, &listingTestModel{newTestModel(), map[string][]ListingEntry{"": nil}}, newTestModel(), CompressAlways, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	entries, next, err := c.Listing(ctx, "default", "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 || next != "" {
+		t.Errorf("Expected an empty listing, got %v, %q", entries, next)
+	}
+
+	if _, _, err := c.Listing(ctx, "default", "missing", "", 0); err != ErrNoSuchFile {
+		t.Errorf("Expected %v for a missing directory, got %v", ErrNoSuchFile, err)
+	}
+}
+
+func TestListingLarge(t *testing.T) {
+	const total = 25000
+	all := make([]ListingEntry, total)
+	for i := range all {
+		all[i] = ListingEntry{Name: fmt.Sprintf("file%06d", i), Type: FileInfoTypeFile, Size: int64(i), ModifiedS: 1}
+	}
+	_, c := newTestConnections(t, &listingTestModel{newTestModel(), map[string][]ListingEntry{"dir/sub": all}}, newTestModel(), CompressAlways, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, tc := range []struct {
+		limit, pageSize int
+	}{
+		{0, DefaultListingLimit},
+		{7000, 7000},
+		{total * 2, MaxListingLimit},
+	} {
+		var got []ListingEntry
+		pages := 0
+		after := ""
+		for {
+			entries, next, err := c.Listing(ctx, "default", "dir/sub", after, tc.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages++
+			if len(entries) > tc.pageSize {
+				t.Fatalf("Limit %d: page of %d entries, expected at most %d", tc.limit, len(entries), tc.pageSize)
+			}
+			got = append(got, entries...)
+			if next == "" {
+				break
+			}
+			after = next
+		}
+
+		if expPages := (total + tc.pageSize - 1) / tc.pageSize; pages != expPages {
+			t.Errorf("Limit %d: %d pages, expected %d", tc.limit, pages, expPages)
+		}
+		if len(got) != total {
+			t.Fatalf("Limit %d: got %d entries, expected %d", tc.limit, len(got), total)
+		}
+		for i := range got {
+			if got[i] != all[i] {
+				t.Fatalf("Limit %d: entry %d is %v, expected %v", tc.limit, i, got[i], all[i])
+			}
+		}
+	}
+}
+
+func TestListingUnsupported(t *testing.T) {
+	_, c1 := newTestConnections(t, newTestModel(), newTestModel(), CompressAlways, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, _, err := c1.Listing(ctx, "default", "", "", 0); err != ErrUnsupported {
+		t.Errorf("Expected %v, got %v", ErrUnsupported, err)
+	}
+}
+
+func TestCheckListing(t *testing.T) {
+	entries := func(names ...string) []ListingEntry {
+		var es []ListingEntry
+		for _, name := range names {
+			es = append(es, ListingEntry{Name: name})
+		}
+		return es
+	}
+	cases := []struct {
+		entries []ListingEntry
+		after   string
+		ok      bool
+	}{
+		{nil, "", true},
+		{entries("a", "b", "c"), "", true},
+		{entries("b", "c"), "a", true},
+		{entries("a", "b"), "a", false},
+		{entries("b", "a"), "", false},
+		{entries("a", "a"), "", false},
+		{entries("a/b"), "", false},
+		{entries(".."), "", false},
+		{entries(""), "", false},
+	}
+	for _, tc := range cases {
+		if err := checkListing(tc.entries, tc.after); (err == nil) != tc.ok {
+			t.Errorf("checkListing(%v, %q) = %v, expected ok %v", tc.entries, tc.after, err, tc.ok)
+		}
+	}
+}
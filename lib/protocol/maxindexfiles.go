@@ -0,0 +1,67 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// MaxIndexFiles returns the most files in an index message, in either
+// direction, as agreed on with the other side during the handshake, or
+// zero if there is no such limit. See WithMaxIndexFiles.
+func (c *rawConnection) MaxIndexFiles() int {
+	select {
+	case <-c.handshakeDone:
+		return c.agreedMaxIndexFiles
+	default:
+		return 0
+	}
+}
+
+// agreeMaxIndexFiles returns the lower of both limits, where zero means no
+// limit.
+func agreeMaxIndexFiles(ours, theirs int) int {
+	if theirs < 0 {
+		theirs = 0
+	}
+	switch {
+	case ours == 0:
+		return theirs
+	case theirs == 0:
+		return ours
+	case theirs < ours:
+		return theirs
+	default:
+		return ours
+	}
+}
+
+// checkIndexFiles returns ErrIndexTooLarge if the marshalled Index or
+// IndexUpdate holds more than max files. It only walks the top level
+// fields, so that an index claiming an absurd number of files is refused
+// before they are allocated. A malformed message is left for Unmarshal to
+// complain about.
+func checkIndexFiles(data []byte, max int) error {
+	const filesField = 2 // in both Index and IndexUpdate
+	n := 0
+	for len(data) > 0 {
+		key, l := binary.Uvarint(data)
+		if l <= 0 {
+			return nil
+		}
+		if key == filesField<<3|2 {
+			n++
+			if n > max {
+				return errors.Wrapf(ErrIndexTooLarge, "index with more than %d files", max)
+			}
+		}
+		skip, err := skipBep(data)
+		if err != nil {
+			return nil
+		}
+		data = data[skip:]
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAgreeMaxIndexFiles(t *testing.T) {
+	cases := []struct {
+		ours, theirs, agreed int
+	}{
+		{0, 0, 0},
+		{100, 0, 100},
+		{0, 100, 100},
+		{100, 10, 10},
+		{10, 100, 10},
+		{100, -1, 100},
+	}
+	for _, tc := range cases {
+		if agreed := agreeMaxIndexFiles(tc.ours, tc.theirs); agreed != tc.agreed {
+			t.Errorf("agreeMaxIndexFiles(%d, %d) = %d, expected %d", tc.ours, tc.theirs, agreed, tc.agreed)
+		}
+	}
+}
+
+// absurdIndex returns a marshalled Index with n empty files, which takes
+// two bytes per file on the wire and a lot more once unmarshalled.
+func absurdIndex(t *testing.T, n int) []byte {
+	t.Helper()
+	buf, err := (&Index{Folder: "default"}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		buf = append(buf, 2<<3|2, 0)
+	}
+	return buf
+}
+
+func TestCheckIndexFiles(t *testing.T) {
+	idx := &Index{Folder: "default", Files: []FileInfo{{Name: "a"}, {Name: "b"}, {Name: "c"}}}
+	buf, err := idx.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkIndexFiles(buf, 3); err != nil {
+		t.Errorf("Index with 3 files refused for a limit of 3: %v", err)
+	}
+	if err := checkIndexFiles(buf, 2); !errors.Is(err, ErrIndexTooLarge) {
+		t.Errorf("Index with 3 files for a limit of 2 returned %v, expected ErrIndexTooLarge", err)
+	}
+
+	buf = absurdIndex(t, 1<<20)
+	allocs := testing.AllocsPerRun(10, func() {
+		if err := checkIndexFiles(buf, 1000); !errors.Is(err, ErrIndexTooLarge) {
+			t.Fatalf("Absurd index returned %v, expected ErrIndexTooLarge", err)
+		}
+	})
+	if allocs > 10 {
+		t.Errorf("Refusing an absurd index took %v allocations", allocs)
+	}
+}
+
+func TestMaxIndexFilesAbsurd(t *testing.T) {
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	defer br.Close()
+
+	m := newTestModel()
+	indexes := make(chan int, 1)
+	m.indexFn = func(_ DeviceID, _ string, files []FileInfo) {
+		indexes <- len(files)
+	}
+	c := NewConnection(c0ID, ar, bw, m, "name", CompressNever, WithMaxIndexFiles(1000), WithoutPinger())
+	c.Start()
+	c.ClusterConfig(ClusterConfig{})
+	go io.Copy(ioutil.Discard, br)
+	if err := NewEncoder(aw, CompressNever).Encode(&ClusterConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An index claiming a million files, framed by hand as it would be
+	// too large to build as an Index.
+	hdr, err := (&Header{Type: messageTypeIndex}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := absurdIndex(t, 1<<20)
+	var frame bytes.Buffer
+	binary.Write(&frame, binary.BigEndian, uint16(len(hdr)))
+	frame.Write(hdr)
+	binary.Write(&frame, binary.BigEndian, uint32(len(msg)))
+	frame.Write(msg)
+	go aw.Write(frame.Bytes())
+
+	if err := m.closedError(); !errors.Is(err, ErrIndexTooLarge) {
+		t.Errorf("Connection closed with %v, expected ErrIndexTooLarge", err)
+	}
+	select {
+	case n := <-indexes:
+		t.Errorf("Index with %d files was passed to the model", n)
+	default:
+	}
+}
+
+func TestMaxIndexFilesAgreed(t *testing.T) {
+	var files []FileInfo
+	for i := 0; i < 12; i++ {
+		files = append(files, FileInfo{Name: fmt.Sprintf("file%d", i), Type: FileInfoTypeDirectory})
+	}
+
+	for _, split := range []bool{false, true} {
+		t.Run(fmt.Sprintf("split=%v", split), func(t *testing.T) {
+			var mut sync.Mutex
+			var batches []int
+			m1 := newTestModel()
+			m1.indexFn = func(_ DeviceID, _ string, files []FileInfo) {
+				mut.Lock()
+				batches = append(batches, len(files))
+				mut.Unlock()
+			}
+			m1.indexUpdateFn = m1.indexFn
+
+			opts := []Option{WithoutPinger()}
+			if split {
+				opts = append(opts, WithIndexSplitting())
+			}
+			c0, _ := newTestConnections(t, newTestModel(), m1, CompressNever, opts, []Option{WithMaxIndexFiles(5)})
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := c0.WaitHandshake(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if max := c0.MaxIndexFiles(); max != 5 {
+				t.Errorf("Agreed on %d files, expected 5", max)
+			}
+
+			err := c0.Index(ctx, "default", files)
+			if !split {
+				if err != ErrIndexTooLarge {
+					t.Fatalf("Index returned %v, expected ErrIndexTooLarge", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for {
+				mut.Lock()
+				n := len(batches)
+				mut.Unlock()
+				if n == 3 {
+					break
+				}
+				if ctx.Err() != nil {
+					t.Fatalf("Received %d batches, expected 3", n)
+				}
+				time.Sleep(time.Millisecond)
+			}
+			mut.Lock()
+			defer mut.Unlock()
+			if fmt.Sprint(batches) != "[5 5 2]" {
+				t.Errorf("Received batches of %v files, expected [5 5 2]", batches)
+			}
+		})
+	}
+}
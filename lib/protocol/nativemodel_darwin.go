@@ -30,3 +30,8 @@ func (m nativeModel) Request(deviceID DeviceID, folder, name string, size int32,
 	name = norm.NFD.String(name)
 	return m.Model.Request(deviceID, folder, name, size, offset, hash, weakHash, fromTemporary)
 }
+
+func (m nativeModel) Push(deviceID DeviceID, folder, name string, offset int64, data []byte) error {
+	name = norm.NFD.String(name)
+	return m.Model.(PushModel).Push(deviceID, folder, name, offset, data)
+}
@@ -9,3 +9,7 @@ package protocol
 type nativeModel struct {
 	Model
 }
+
+func (m nativeModel) Push(deviceID DeviceID, folder, name string, offset int64, data []byte) error {
+	return m.Model.(PushModel).Push(deviceID, folder, name, offset, data)
+}
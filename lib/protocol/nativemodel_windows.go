@@ -35,6 +35,16 @@ func (m nativeModel) Request(deviceID DeviceID, folder, name string, size int32,
 	return m.Model.Request(deviceID, folder, name, size, offset, hash, weakHash, fromTemporary)
 }
 
+func (m nativeModel) Push(deviceID DeviceID, folder, name string, offset int64, data []byte) error {
+	if strings.Contains(name, `\`) {
+		l.Warnf("Dropping push for %s, contains invalid path separator", name)
+		return ErrNoSuchFile
+	}
+
+	name = filepath.FromSlash(name)
+	return m.Model.(PushModel).Push(deviceID, folder, name, offset, data)
+}
+
 func fixupFiles(files []FileInfo) []FileInfo {
 	var out []FileInfo
 	for i := range files {
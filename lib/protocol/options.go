@@ -0,0 +1,490 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"net"
+	"time"
+)
+
+// An Option can be passed to NewConnection to change the default behavior
+// of the connection.
+type Option func(*rawConnection)
+
+// WithMaxRequestSize sets the largest request, in bytes, that will be
+// served to the other side. Larger requests are answered with
+// ErrRequestTooLarge without being passed to the model. The default is
+// MaxBlockSize.
+func WithMaxRequestSize(size int) Option {
+	return func(c *rawConnection) {
+		if size > 0 {
+			c.maxRequestSize = size
+		}
+	}
+}
+
+// WithChecksums makes the connection add a CRC32 checksum of the
+// uncompressed message to the header of every outgoing message. Checksums
+// on incoming messages are always verified when present, and a mismatch
+// closes the connection with ErrChecksumMismatch. This catches corruption
+// that would otherwise go undetected, at some CPU cost.
+func WithChecksums() Option {
+	return func(c *rawConnection) {
+		c.checksums = true
+	}
+}
+
+// A RequestAuthorizer decides whether a request from the given device may
+// be served. A non-nil error denies the request.
+type RequestAuthorizer func(deviceID DeviceID, folder, name string, offset int64, size int) error
+
+// WithRequestAuthorizer makes the connection check every incoming request
+// with the given authorizer before passing it to the model. Denied requests
+// are answered with ErrUnauthorized without consulting the model. The
+// authorizer is called synchronously from the message dispatcher and must
+// not block.
+func WithRequestAuthorizer(auth RequestAuthorizer) Option {
+	return func(c *rawConnection) {
+		c.authorizer = auth
+	}
+}
+
+// WithResponseCache makes the connection answer requests from the given
+// cache when it has the data, without asking the model, and cache the data
+// the model returns otherwise. Requests answered from the cache are still
+// observed by a RequestObserver. See ResponseCache for what is cached.
+func WithResponseCache(cache *ResponseCache) Option {
+	return func(c *rawConnection) {
+		c.responseCache = cache
+	}
+}
+
+// WithMaxPendingResponseBytes limits the total size of the responses we
+// are waiting for to the given number of bytes, counted by requested size.
+// Requests beyond that wait (or fail, when the context is done) until
+// earlier requests complete. This bounds the memory held by responses that
+// are yet to be consumed, at the cost of throughput on connections with a
+// high bandwidth-delay product. A request larger than the limit is allowed
+// when there are no other requests outstanding. Response data is always
+// copied out of the read buffer when the message is unmarshalled, so a
+// slow consumer never pins read buffers.
+func WithMaxPendingResponseBytes(bytes int) Option {
+	return func(c *rawConnection) {
+		if bytes > 0 {
+			c.responseBytes = newByteSemaphore(bytes)
+		}
+	}
+}
+
+// WithMaxPendingPushBytes limits the amount of data sent by Push that
+// has not yet been acknowledged by the other side. The default is twice
+// MaxBlockSize.
+func WithMaxPendingPushBytes(bytes int) Option {
+	return func(c *rawConnection) {
+		if bytes > 0 {
+			c.pushBytes = newByteSemaphore(bytes)
+		}
+	}
+}
+
+// WithMaxConcurrentRequests limits the number of requests from the other
+// side that are handled concurrently. Further requests are queued and
+// handled in order of priority, as set by the requester with
+// ContextWithRequestPriority. By default there is no limit. The limit can
+// be changed later with SetMaxConcurrentRequests.
+func WithMaxConcurrentRequests(n int) Option {
+	return func(c *rawConnection) {
+		if n > 0 {
+			c.requests.max = n
+		}
+	}
+}
+
+// WithoutPinger disables sending pings to keep the connection alive or to
+// measure latency, and closing it when nothing has been received for a
+// while. Detecting a dead connection then becomes the responsibility of the
+// caller, e.g. by TCP keepalives or calling Ping.
+func WithoutPinger() Option {
+	return func(c *rawConnection) {
+		c.noPinger = true
+	}
+}
+
+// WithPingRetries makes the connection try up to the given number of
+// pings in total, with exponentially increasing waits starting at backoff,
+// before closing the connection when nothing has been received within
+// ReceiveTimeout. Anything received in the meantime resets this. The
+// default of one attempt closes the connection right away. A backoff of
+// zero keeps the default of five seconds.
+func WithPingRetries(attempts int, backoff time.Duration) Option {
+	return func(c *rawConnection) {
+		if attempts > 0 {
+			c.pingAttempts = attempts
+		}
+		if backoff > 0 {
+			c.pingBackoff = backoff
+		}
+	}
+}
+
+// WithIdlePingInterval sets the longest time between pings we accept while
+// either side is in idle mode, see SetIdle. The interval used is the
+// shorter of both sides' and the receive timeout grows along with it, so
+// a longer interval saves traffic at the cost of detecting a dead
+// connection later. Intervals shorter than PingSendInterval are raised to
+// it. The default is DefaultIdlePingInterval.
+func WithIdlePingInterval(d time.Duration) Option {
+	return func(c *rawConnection) {
+		if d > 0 {
+			if d < PingSendInterval {
+				d = PingSendInterval
+			}
+			c.idlePingInterval = d
+		}
+	}
+}
+
+// WithRequestRetries makes the connection try requests from the other side
+// up to the given number of times in total when the model fails them with a
+// temporary error (see TemporaryError), with exponentially increasing waits
+// starting at backoff, before answering with the error. Retries stop early
+// when the requester's timeout would pass. The default of one attempt
+// answers right away. A backoff of zero keeps the default of 100 ms.
+func WithRequestRetries(attempts int, backoff time.Duration) Option {
+	return func(c *rawConnection) {
+		if attempts > 0 {
+			c.requestAttempts = attempts
+		}
+		if backoff > 0 {
+			c.requestBackoff = backoff
+		}
+	}
+}
+
+// WithoutPanicRecovery lets a panic in the model while handling a request
+// crash the program. By default such a panic is logged and the request is
+// answered with ErrGeneric, keeping the connection alive.
+func WithoutPanicRecovery() Option {
+	return func(c *rawConnection) {
+		c.noPanicRecovery = true
+	}
+}
+
+// WithHealthWeights sets the weights of the components of HealthScore,
+// instead of DefaultHealthWeights. Weights that are negative, or all zero,
+// are ignored.
+func WithHealthWeights(w HealthWeights) Option {
+	return func(c *rawConnection) {
+		if w.Latency < 0 || w.Throughput < 0 || w.Errors < 0 || w.Idle < 0 {
+			return
+		}
+		if w.Latency+w.Throughput+w.Errors+w.Idle > 0 {
+			c.healthWeights = w
+		}
+	}
+}
+
+// WithHandshakeTimeout closes the connection with ErrHandshakeTimeout if
+// the cluster config from the other side hasn't been received within the
+// given time after starting the connection. This keeps peers that connect
+// but never complete the handshake from tying up resources. By default
+// there is no timeout.
+func WithHandshakeTimeout(d time.Duration) Option {
+	return func(c *rawConnection) {
+		if d > 0 {
+			c.handshakeTimeout = d
+		}
+	}
+}
+
+// WithLowLatency guarantees that every message is handed to the
+// underlying writer, and flushed if the writer supports it, as soon as it
+// has been marshalled, without being held back to be coalesced with other
+// messages. This minimizes the latency of each message at the cost of
+// throughput, as many small messages result in more writes and more, smaller
+// packets on the wire.
+func WithLowLatency() Option {
+	return func(c *rawConnection) {
+		c.lowLatency = true
+	}
+}
+
+// WithReadBufferSize makes the connection read from the underlying reader
+// through a buffer of the given size, so that many small messages or the
+// headers of large ones need fewer reads. By default reads are unbuffered.
+func WithReadBufferSize(size int) Option {
+	return func(c *rawConnection) {
+		if size > 0 {
+			c.readBufferSize = size
+		}
+	}
+}
+
+// WithWriteBufferSize makes the connection write to the underlying writer
+// through a buffer of the given size. The buffer is flushed when there is
+// nothing more to send for the moment, or after every message when
+// WithLowLatency is also given. This results in fewer, larger writes when
+// many messages are sent back to back. As the buffer is flushed whenever
+// the connection runs out of messages to send, it doesn't delay a lone
+// message; the cost is the memory of the buffer, held for the lifetime of
+// the connection, and an extra copy of each message. The benefit depends
+// on how many messages are ready to be sent at once: many concurrent
+// requests and responses gain a lot, while index updates, which are sent
+// one at a time, gain little. By default writes are unbuffered.
+func WithWriteBufferSize(size int) Option {
+	return func(c *rawConnection) {
+		if size > 0 {
+			c.writeBufferSize = size
+		}
+	}
+}
+
+// WithSmallMessageSize sets the size up to which messages, such as the
+// responses to small requests for metadata, are encoded and decoded in
+// buffers kept by the connection rather than ones from the BufferPool. This
+// saves allocations and pool operations at the cost of holding the buffers
+// for the lifetime of the connection. The default is
+// DefaultSmallMessageSize, zero disables it and sizes above 64 KiB are
+// reduced to that.
+func WithSmallMessageSize(size int) Option {
+	return func(c *rawConnection) {
+		if size > maxSmallMessageSize {
+			size = maxSmallMessageSize
+		}
+		if size >= 0 {
+			c.smallMessageSize = size
+		}
+	}
+}
+
+// WithIndexRejection makes the connection carry on when the model fails to
+// apply an Index or IndexUpdate, telling the other side, if it implements
+// IndexRejectModel, instead of closing the connection. The rejected files
+// are not acknowledged, nor is anything after them in the same folder. By
+// default, and with other sides that don't want to know, an error from the
+// model closes the connection.
+func WithIndexRejection() Option {
+	return func(c *rawConnection) {
+		c.indexRejection = true
+	}
+}
+
+// WithIndexReuse makes the connection reuse the slices that the files of
+// incoming Index and IndexUpdate messages are unmarshalled into, to reduce
+// garbage when receiving many index updates. The files slice passed to
+// Model.Index and Model.IndexUpdate is then only valid until the call
+// returns; a model that keeps it must keep a copy instead, e.g.
+// append([]FileInfo(nil), files...). The FileInfos themselves, including
+// their blocks, are never reused and may be kept.
+func WithIndexReuse() Option {
+	return func(c *rawConnection) {
+		c.reuseIndex = true
+	}
+}
+
+// WithIndexOrderCheck makes Index and IndexUpdate refuse files that aren't
+// sorted by name, in wire format, or that hold a name twice, returning an
+// error wrapping ErrIndexOrder, to catch bugs in how the caller builds its
+// index. Index messages from the other side are held to the same order;
+// one that isn't sorted is a protocol error that closes the connection.
+// Only use it with peers known to send sorted indexes, which Syncthing
+// itself doesn't. It is off by default, as the check costs a comparison
+// per file.
+func WithIndexOrderCheck() Option {
+	return func(c *rawConnection) {
+		c.checkIndexOrder = true
+	}
+}
+
+// WithIndexSplitting makes Index and IndexUpdate send files that don't fit
+// in a single message of MaxMessageLen bytes, or of MaxIndexFiles files
+// when there is such a limit, as several messages: the
+// first is an Index or IndexUpdate as called for, the others are
+// IndexUpdates. Without it, such files are refused with ErrIndexTooLarge
+// and nothing is sent, as the other side would reject the message. A
+// single file that doesn't fit is always refused.
+func WithIndexSplitting() Option {
+	return func(c *rawConnection) {
+		c.splitIndex = true
+	}
+}
+
+// WithMaxIndexFiles limits index messages from the other side to the
+// given number of files. The limit is told to the other side, and the
+// lower of both limits, as returned by MaxIndexFiles, applies to index
+// messages in both directions: a larger index from the other side closes
+// the connection with ErrIndexTooLarge, before the files are unmarshalled,
+// while a larger one of ours is refused or split as for WithIndexSplitting.
+// By default there is no limit other than MaxMessageLen.
+func WithMaxIndexFiles(n int) Option {
+	return func(c *rawConnection) {
+		if n > 0 {
+			c.maxIndexFiles = n
+		}
+	}
+}
+
+// WithIndexThrottle limits Index and IndexUpdate messages to one per
+// folder per the given interval. Calls within the interval after the last
+// message return right away, and what they would have sent is merged and
+// sent once the interval has passed: the latest full index, if any, with
+// the files of later updates replacing those of the same name. The latest
+// state is thus always sent eventually, but may be delayed by up to the
+// interval. By default every call sends a message.
+func WithIndexThrottle(interval time.Duration) Option {
+	return func(c *rawConnection) {
+		if interval > 0 {
+			c.indexThrottle = newIndexThrottle(interval)
+		}
+	}
+}
+
+// WithPreferredBlockSize makes the connection tell the other side that we
+// prefer the given block size, which must be one of BlockSizes. The block
+// size agreed on, as returned by BlockSize, is the smaller of both
+// preferences, or the preference of the side that has one. By default we
+// have no preference.
+func WithPreferredBlockSize(size int) Option {
+	return func(c *rawConnection) {
+		if isValidBlockSize(size) {
+			c.preferredBlockSize = size
+		}
+	}
+}
+
+// WithSocketOptions calls the given function once, when the connection is
+// created, with the underlying net.Conn if the reader or the writer passed
+// to NewConnection is one, e.g. to set TCP_NODELAY, keepalives or buffer
+// sizes. It is a no-op for other transports. If the function returns an
+// error, the connection closes with that error when started.
+func WithSocketOptions(fn func(net.Conn) error) Option {
+	return func(c *rawConnection) {
+		c.socketTuner = fn
+	}
+}
+
+// WithMetadata attaches the given labels to the connection, for the caller
+// to retrieve later with Metadata, e.g. to tell which folders or priority
+// class a connection is for without keeping a map of connections on the
+// side. The connection itself doesn't use them, and they aren't sent to
+// the other side. The map is copied.
+func WithMetadata(md map[string]string) Option {
+	return func(c *rawConnection) {
+		if len(md) == 0 {
+			return
+		}
+		c.metadata = make(map[string]string, len(md))
+		for k, v := range md {
+			c.metadata[k] = v
+		}
+	}
+}
+
+// WithTap makes the connection call the given tap for every message read
+// or written, e.g. for debugging or exporting metrics. The tap runs inline
+// with reading and writing; see Tap.
+func WithTap(tap Tap) Option {
+	return func(c *rawConnection) {
+		c.tap = tap
+	}
+}
+
+// WithPresharedKey encrypts and authenticates everything sent and received
+// with the given key, for transports that aren't already secured by TLS.
+// Both sides must use the same key, which must be at least
+// MinPresharedKeyLen bytes of high entropy, not a password. If it is
+// shorter, nothing is sent and the connection closes when started. Data
+// that fails to decrypt, including data recorded from an earlier
+// connection, closes the connection with ErrDecryptionFailed. Close ends
+// the stream in a way the other side can verify; a stream that ends
+// otherwise closes the connection with an error wrapping
+// io.ErrUnexpectedEOF. Statistics count the bytes before encryption.
+func WithPresharedKey(key []byte) Option {
+	return func(c *rawConnection) {
+		c.presharedKey = append([]byte{}, key...)
+	}
+}
+
+// WithCompressionAutoTuning makes the connection periodically adjust the
+// compression of outgoing messages to the speed of the link: compressing
+// everything on slow links, metadata on medium ones and nothing on fast
+// ones. The compression given to NewConnection is used until there is
+// enough traffic to judge by. Calling SetCompression pins the compression
+// and ends auto tuning. Compression returns the current choice.
+func WithCompressionAutoTuning() Option {
+	return func(c *rawConnection) {
+		c.compressionTuner = new(compressionTuner)
+	}
+}
+
+// WithRequestLatencyTracking enables keeping track of the time from sending
+// a request until the response is received, as returned by
+// RequestLatency.
+func WithRequestLatencyTracking() Option {
+	return func(c *rawConnection) {
+		c.latencies = new(latencyHistogram)
+	}
+}
+
+// WithFolderStatistics enables keeping track of traffic per folder, as
+// returned in the Folders field of Statistics.
+func WithFolderStatistics() Option {
+	return func(c *rawConnection) {
+		c.folderStats = newFolderStatistics()
+	}
+}
+
+// WithCompressionStatistics enables keeping track of how well the messages
+// we send compress, by type of message, as returned in the Compression
+// field of Statistics. This tells e.g. whether compressing responses is
+// worth it for the data at hand.
+func WithCompressionStatistics() Option {
+	return func(c *rawConnection) {
+		c.compressionStats = newCompressionStatistics()
+	}
+}
+
+// WithThroughputSmoothing enables keeping an exponentially weighted moving
+// average of the incoming and outgoing byte rates, as returned in the
+// SmoothedInBytesPerSec and SmoothedOutBytesPerSec fields of Statistics.
+// The average is updated every second, with alpha (between zero and one)
+// being the weight of the newest sample. Lower values give smoother rates
+// that are slower to react to changes.
+func WithThroughputSmoothing(alpha float64) Option {
+	return func(c *rawConnection) {
+		if alpha > 0 && alpha <= 1 {
+			c.throughput = &throughputMeter{alpha: alpha}
+		}
+	}
+}
+
+// WithStatisticsBaseline makes the totals returned by Statistics start at
+// those of the given statistics, e.g. as saved from an earlier connection
+// to the same device, to keep lifetime totals. Folder statistics are
+// carried over when enabled. Measurements such as latencies and rates
+// are not.
+func WithStatisticsBaseline(stats Statistics) Option {
+	return func(c *rawConnection) {
+		c.baseline = &stats
+	}
+}
+
+// WithRequestCoalescing makes concurrent, identical requests (same folder,
+// name, offset, size and hashes) share a single request on the wire. Every
+// caller receives its own copy of the response data.
+func WithRequestCoalescing() Option {
+	return func(c *rawConnection) {
+		c.sharedRequests = make(map[requestKey]*sharedRequest)
+	}
+}
+
+// WithUnknownMessages sets whether a message of a type we don't know, e.g.
+// one introduced by a newer version of the protocol, is skipped, letting
+// the connection carry on with the messages after it, or closes the
+// connection as a protocol error. Closing is the default; skipping allows
+// newer peers to send new message types that older ones can ignore.
+func WithUnknownMessages(skip bool) Option {
+	return func(c *rawConnection) {
+		c.skipUnknown = skip
+	}
+}
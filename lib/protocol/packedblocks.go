@@ -0,0 +1,101 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// packBlocks returns the files with the blocks of those that allow it sent
+// as packed hashes, i.e. without the per-block offset and size that follow
+// from the block size. The given files are not modified.
+func packBlocks(fs []FileInfo) []FileInfo {
+	var out []FileInfo
+	for i := range fs {
+		if !canPackBlocks(fs[i]) {
+			continue
+		}
+		if out == nil {
+			// Most indexes contain some file that can be packed, but we
+			// still avoid the copy when none can.
+			out = make([]FileInfo, len(fs))
+			copy(out, fs)
+		}
+		f := &out[i]
+		f.PackedHashes = make([]byte, 0, len(f.Blocks)*sha256.Size)
+		var weak []uint32
+		for j, b := range f.Blocks {
+			f.PackedHashes = append(f.PackedHashes, b.Hash...)
+			if b.WeakHash != 0 && weak == nil {
+				weak = make([]uint32, len(f.Blocks))
+			}
+			if weak != nil {
+				weak[j] = b.WeakHash
+			}
+		}
+		f.PackedWeakHashes = weak
+		f.Blocks = nil
+	}
+	if out == nil {
+		return fs
+	}
+	return out
+}
+
+func canPackBlocks(f FileInfo) bool {
+	if len(f.Blocks) == 0 || f.RawBlockSize <= 0 || len(f.PackedHashes) > 0 {
+		return false
+	}
+	bs := int64(f.RawBlockSize)
+	for i, b := range f.Blocks {
+		if len(b.Hash) != sha256.Size || b.Offset != int64(i)*bs {
+			return false
+		}
+		if i < len(f.Blocks)-1 && int64(b.Size) != bs {
+			return false
+		}
+	}
+	last := f.Blocks[len(f.Blocks)-1]
+	return last.Size > 0 && int64(last.Size) <= bs && last.Offset+int64(last.Size) == f.Size
+}
+
+// unpackBlocks restores the blocks of files sent with packed hashes, in
+// place.
+func unpackBlocks(fs []FileInfo) error {
+	for i := range fs {
+		f := &fs[i]
+		if len(f.PackedHashes) == 0 {
+			continue
+		}
+		if len(f.Blocks) > 0 {
+			return fmt.Errorf("%q: both blocks and packed hashes", f.Name)
+		}
+		if len(f.PackedHashes)%sha256.Size != 0 {
+			return fmt.Errorf("%q: packed hashes length %d not a multiple of %d", f.Name, len(f.PackedHashes), sha256.Size)
+		}
+		n := len(f.PackedHashes) / sha256.Size
+		if len(f.PackedWeakHashes) != 0 && len(f.PackedWeakHashes) != n {
+			return fmt.Errorf("%q: %d weak hashes for %d blocks", f.Name, len(f.PackedWeakHashes), n)
+		}
+		bs := int64(f.RawBlockSize)
+		if bs <= 0 || f.Size <= int64(n-1)*bs || f.Size > int64(n)*bs {
+			return fmt.Errorf("%q: %d packed blocks of size %d for file size %d", f.Name, n, bs, f.Size)
+		}
+
+		f.Blocks = make([]BlockInfo, n)
+		for j := range f.Blocks {
+			b := &f.Blocks[j]
+			b.Offset = int64(j) * bs
+			b.Size = int32(bs)
+			b.Hash = f.PackedHashes[j*sha256.Size : (j+1)*sha256.Size : (j+1)*sha256.Size]
+			if len(f.PackedWeakHashes) > 0 {
+				b.WeakHash = f.PackedWeakHashes[j]
+			}
+		}
+		f.Blocks[n-1].Size = int32(f.Size - f.Blocks[n-1].Offset)
+		f.PackedHashes = nil
+		f.PackedWeakHashes = nil
+	}
+	return nil
+}
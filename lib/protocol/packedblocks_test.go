@@ -0,0 +1,124 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func fileWithBlocks(name string, size int64, blockSize int, weak bool) FileInfo {
+	f := FileInfo{Name: name, Size: size, RawBlockSize: int32(blockSize)}
+	for offset := int64(0); offset < size; offset += int64(blockSize) {
+		b := BlockInfo{Offset: offset, Size: int32(blockSize), Hash: make([]byte, 32)}
+		if rest := size - offset; rest < int64(blockSize) {
+			b.Size = int32(rest)
+		}
+		rand.Read(b.Hash)
+		if weak {
+			b.WeakHash = uint32(offset) + 1
+		}
+		f.Blocks = append(f.Blocks, b)
+	}
+	return f
+}
+
+func TestPackUnpackBlocks(t *testing.T) {
+	irregular := fileWithBlocks("irregular", 3*MinBlockSize, MinBlockSize, false)
+	irregular.Blocks[1].Size--
+	files := []FileInfo{
+		fileWithBlocks("partial", 10*MinBlockSize-100, MinBlockSize, true),
+		fileWithBlocks("exact", 10*MinBlockSize, MinBlockSize, false),
+		fileWithBlocks("single", 100, MinBlockSize, true),
+		irregular,
+		{Name: "dir", Type: FileInfoTypeDirectory},
+	}
+	orig := make([]FileInfo, len(files))
+	copy(orig, files)
+
+	packed := packBlocks(files)
+	if !reflect.DeepEqual(files, orig) {
+		t.Fatal("Packing modified the given files")
+	}
+	for i, f := range packed[:3] {
+		if len(f.Blocks) != 0 || len(f.PackedHashes) != 32*len(orig[i].Blocks) {
+			t.Errorf("%s: not packed", f.Name)
+		}
+	}
+	if len(packed[3].PackedHashes) != 0 || len(packed[3].Blocks) != 3 {
+		t.Error("Irregular blocks should not have been packed")
+	}
+
+	if err := unpackBlocks(packed); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(packed, orig) {
+		t.Errorf("Unpacked files differ from the original")
+	}
+}
+
+func TestUnpackBlocksInvalid(t *testing.T) {
+	cases := []FileInfo{
+		{Name: "no block size", Size: 100, PackedHashes: make([]byte, 32)},
+		{Name: "short hashes", Size: 100, RawBlockSize: MinBlockSize, PackedHashes: make([]byte, 31)},
+		{Name: "too few blocks", Size: MinBlockSize + 1, RawBlockSize: MinBlockSize, PackedHashes: make([]byte, 32)},
+		{Name: "too many blocks", Size: MinBlockSize, RawBlockSize: MinBlockSize, PackedHashes: make([]byte, 64)},
+		{Name: "weak hashes", Size: 100, RawBlockSize: MinBlockSize, PackedHashes: make([]byte, 32), PackedWeakHashes: []uint32{1, 2}},
+		{Name: "both", Size: 100, RawBlockSize: MinBlockSize, PackedHashes: make([]byte, 32), Blocks: []BlockInfo{{Size: 100}}},
+	}
+	for _, f := range cases {
+		if err := unpackBlocks([]FileInfo{f}); err == nil {
+			t.Errorf("%s: expected an error", f.Name)
+		}
+	}
+}
+
+func TestPackedBlocksIndex(t *testing.T) {
+	received := make(chan []FileInfo, 1)
+	m0 := newTestModel()
+	m0.indexFn = func(_ DeviceID, _ string, files []FileInfo) {
+		received <- files
+	}
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c1.WaitHandshake(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []FileInfo{fileWithBlocks("foo", 10*MinBlockSize-100, MinBlockSize, true)}
+	if err := c1.Index(ctx, "default", files); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-received:
+		if !reflect.DeepEqual(got, files) {
+			t.Errorf("Received files differ from the sent ones")
+		}
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for index")
+	}
+}
+
+func BenchmarkPackedBlocksIndexSize(b *testing.B) {
+	// The size of the index entry for a 10 GB file, with the usual and the
+	// smallest block size.
+	const size = 10 << 30
+	for _, blockSize := range []int{BlockSize(size), MinBlockSize} {
+		f := fileWithBlocks("file", size, blockSize, true)
+		b.Run(fmt.Sprintf("blocksize=%d", blockSize), func(b *testing.B) {
+			var plain, packed int
+			for i := 0; i < b.N; i++ {
+				plain = (&Index{Files: []FileInfo{f}}).ProtoSize()
+				packed = (&Index{Files: packBlocks([]FileInfo{f})}).ProtoSize()
+			}
+			b.ReportMetric(float64(plain), "plain-bytes")
+			b.ReportMetric(float64(packed), "packed-bytes")
+		})
+	}
+}
@@ -3,17 +3,19 @@
 package protocol
 
 import (
+	"bufio"
 	"context"
 	"crypto/sha256"
-	"encoding/binary"
 	"fmt"
 	"io"
+	"net"
 	"path"
+	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	lz4 "github.com/bkaradzic/go-lz4"
 	"github.com/pkg/errors"
 )
 
@@ -101,12 +103,20 @@ const (
 var (
 	ErrClosed             = errors.New("connection closed")
 	ErrTimeout            = errors.New("read timeout")
-	errUnknownMessage     = errors.New("unknown message")
+	ErrChecksumMismatch   = errors.New("message checksum mismatch")
+	ErrUnsupported        = errors.New("not supported by the other side")
+	ErrHandshakeTimeout   = errors.New("handshake timeout")
+	ErrQuiescing          = errors.New("connection is quiescing")
+	ErrIndexTooLarge      = errors.New("index too large for a message")
+	ErrIndexOrder         = errors.New("index files not sorted by name")
+	ErrResponseTooLong    = errors.New("response longer than requested")
+	ErrBufferUnavailable  = errors.New("no buffer of the requested size")
 	errInvalidFilename    = errors.New("filename is invalid")
 	errUncleanFilename    = errors.New("filename not in canonical format")
 	errDeletedHasBlocks   = errors.New("deleted file with non-empty block list")
 	errDirectoryHasBlocks = errors.New("directory with non-empty block list")
 	errFileHasNoBlocks    = errors.New("file with empty block list")
+	errInvalidInlineData  = errors.New("inline data doesn't match file")
 )
 
 type Model interface {
@@ -114,7 +124,9 @@ type Model interface {
 	Index(deviceID DeviceID, folder string, files []FileInfo) error
 	// An index update was received from the peer device
 	IndexUpdate(deviceID DeviceID, folder string, files []FileInfo) error
-	// A request was made by the peer device
+	// A request was made by the peer device. An error with a Temporary
+	// method returning true, such as a TemporaryError, may be retried; see
+	// WithRequestRetries.
 	Request(deviceID DeviceID, folder, name string, size int32, offset int64, hash []byte, weakHash uint32, fromTemporary bool) (RequestResponse, error)
 	// A cluster configuration message was received
 	ClusterConfig(deviceID DeviceID, config ClusterConfig) error
@@ -124,12 +136,29 @@ type Model interface {
 	DownloadProgress(deviceID DeviceID, folder string, updates []FileDownloadProgressUpdate) error
 }
 
+// A RequestObserver is told when we start and finish serving a request
+// from the other side, e.g. to keep track of the number of requests being
+// served, or to limit it across connections by blocking in RequestStarted.
+// A Model passed to NewConnection that also implements RequestObserver is
+// used as such. The calls are made synchronously from the goroutine
+// serving the request, before the model is asked for the data and after
+// the response has been sent, respectively. Requests refused before being
+// passed to the model are not observed. Names are in wire format.
+type RequestObserver interface {
+	RequestStarted(deviceID DeviceID, folder, name string)
+	RequestFinished(deviceID DeviceID, folder, name string)
+}
+
 type RequestResponse interface {
 	Data() []byte
 	Close() // Must always be called once the byte slice is no longer in use
 	Wait()  // Blocks until Close is called
 }
 
+// A Connection is what the model needs of a connection to another device.
+// The connection returned by NewConnection also has the methods for
+// features beyond that, e.g. Push, RequestRanges or HealthScore, which are
+// reached by asserting it to an interface with the method.
 type Connection interface {
 	Start()
 	Close(err error)
@@ -145,47 +174,135 @@ type Connection interface {
 }
 
 type rawConnection struct {
+	pingsSuspendedUntil int64 // unix nanos (atomic, must remain 64-bit aligned)
+	latency             int64 // nanoseconds (atomic, must remain 64-bit aligned)
+	peerLatency         int64 // nanoseconds (atomic, must remain 64-bit aligned)
+	clockSkew           int64 // nanoseconds (atomic, must remain 64-bit aligned)
+	oneWayLatency       int64 // nanoseconds (atomic, must remain 64-bit aligned)
+	discardedMessages   int64 // read but not dispatched as closed (atomic, must remain 64-bit aligned)
+	idle                int32 // we are in idle mode (atomic)
+	peerIdle            int32 // the other side is in idle mode (atomic)
+	pendingWriteBytes   int64 // in the write buffer (atomic, must remain 64-bit aligned)
+	clockSkewKnown      int32 // atomic
+	clockSkewWarned     int32 // atomic
+	closeReason         int32 // CloseReason (atomic)
+
 	id       DeviceID
 	name     string
+	metadata map[string]string // nil unless set by option, never modified
 	receiver Model
 
-	cr *countingReader
-	cw *countingWriter
+	cr  *countingReader
+	cw  *countingWriter
+	enc *Encoder
+	dec *Decoder
 
-	awaiting    map[int32]chan asyncResult
+	awaiting    map[int32]awaitingResponse
+	quiescing   bool          // protected by awaitingMut
+	drained     chan struct{} // protected by awaitingMut; see Quiesce
 	awaitingMut sync.Mutex
 
 	idxMut sync.Mutex // ensures serialization of Index calls
 
 	nextID    int32
 	nextIDMut sync.Mutex
+	idSource  func() int32 // nil unless set by tests, replaces nextID
 
-	inbox                 chan message
-	outbox                chan asyncMessage
+	inbox                 chan Message
+	outboxes              [numStreams]chan asyncMessage // see streamOf
 	closeBox              chan asyncMessage
 	clusterConfigBox      chan *ClusterConfig
+	handshakeDone         chan struct{} // closed when the other side's cluster config has been processed
 	dispatcherLoopStopped chan struct{}
 	closed                chan struct{}
 	closeOnce             sync.Once
 	sendCloseOnce         sync.Once
-	compression           Compression
+	compression           int32 // Compression (atomic)
+
+	maxRequestSize   int
+	maxIndexLen      int  // the largest index message we send
+	splitIndex       bool // split indexes larger than maxIndexLen
+	checkIndexOrder  bool // require index files sorted by name without duplicates
+	checksums        bool
+	lowLatency       bool
+	noPinger         bool
+	noPanicRecovery  bool
+	skipUnknown      bool // skip messages of unknown types instead of closing
+	pingAttempts     int
+	pingBackoff      time.Duration
+	requestAttempts  int
+	requestBackoff   time.Duration
+	authorizer       RequestAuthorizer
+	responseCache    *ResponseCache    // nil unless responses are cached
+	responseBytes    *byteSemaphore    // nil unless response bytes are limited
+	latencies        *latencyHistogram // nil unless latency tracking is enabled
+	errorRate        errorRate
+	healthWeights    HealthWeights
+	folderStats      *folderStatistics      // nil unless folder statistics are enabled
+	compressionStats *compressionStatistics // nil unless compression statistics are enabled
+	throughput       *throughputMeter       // nil unless throughput smoothing is enabled
+	indexThrottle    *indexThrottle         // nil unless index throttling is enabled
+	requests         requestScheduler
+	quotaModel       QuotaModel        // nil unless the model reports quotas
+	summaryModel     IndexSummaryModel // nil unless the model accepts index summaries
+	listingModel     ListingModel      // nil unless the model serves listings
+	fileInfoModel    FileInfoModel     // nil unless the model serves file infos
+	indexAckModel    IndexAckModel     // nil unless the model wants index acks
+	indexRejectModel IndexRejectModel  // nil unless the model wants index rejections
+	indexRejection   bool
+	rejectedFolders  map[string]bool // folders no longer acked, only used by the dispatcher
+	indexAcks        indexAcks
+	requestObserver  RequestObserver        // nil unless the model observes requests
+	closeReasonModel CloseReasonModel       // nil unless the model wants close reasons
+	abandonedModel   AbandonedRequestsModel // nil unless the model counts abandoned requests
+	idleModel        IdleModel              // nil unless the model wants to know when the other side is idle
+	pushBytes        *byteSemaphore
+	baseline         *Statistics // only used during construction
+	handshakeTimeout time.Duration
+	readBufferSize   int
+	writeBufferSize  int
+	smallMessageSize int
+	writeBuf         *bufio.Writer // nil unless writes are buffered
+	underlying       flusher       // the writer below the write buffer, nil unless it can be flushed
+	reuseIndex       bool
+	socketTuner      func(net.Conn) error
+	socketTunerErr   error
+	tap              Tap               // nil unless tapping messages
+	compressionTuner *compressionTuner // nil unless auto tuning compression
+	presharedKey     []byte            // nil unless encrypting with a pre-shared key
+	encrypted        *encryptedWriter  // nil unless encrypting with a pre-shared key
+
+	capabilities     Capabilities // what we advertise
+	peerCapabilities Capabilities // what the other side advertises, set before handshakeDone is closed
+
+	preferredBlockSize int // zero unless set by option
+	agreedBlockSize    int // set before handshakeDone is closed
+
+	maxIndexFiles       int // zero unless set by option
+	agreedMaxIndexFiles int // set before handshakeDone is closed
+
+	idlePingInterval       time.Duration // what we advertise
+	idleMut                sync.Mutex    // serializes entering and leaving idle mode
+	agreedIdlePingInterval time.Duration // zero unless both support idle mode, set before handshakeDone is closed
+
+	sharedRequests    map[requestKey]*sharedRequest // nil unless request coalescing is enabled
+	sharedRequestsMut sync.Mutex
+}
+
+type flusher interface {
+	Flush() error
 }
 
 type asyncResult struct {
 	val []byte
 	err error
-}
-
-type message interface {
-	ProtoSize() int
-	Marshal() ([]byte, error)
-	MarshalTo([]byte) (int, error)
-	Unmarshal([]byte) error
+	msg Message // the response message, when there is more to it than val
 }
 
 type asyncMessage struct {
-	msg  message
-	done chan struct{} // done closes when we're done sending the message
+	msg            Message
+	done           chan struct{} // done closes when we're done sending the message
+	incompressible bool          // send uncompressed whatever the compression setting
 }
 
 const (
@@ -195,14 +312,24 @@ const (
 	// ReceiveTimeout is the longest we'll wait for a message from the other
 	// side before closing the connection.
 	ReceiveTimeout = 300 * time.Second
+	// defaultPingBackoff is the time to wait for an answer to the first
+	// ping retry, when retries are enabled.
+	defaultPingBackoff = 5 * time.Second
+	// defaultRequestBackoff is the time to wait before retrying a request
+	// the model failed with a temporary error, when retries are enabled.
+	defaultRequestBackoff = 100 * time.Millisecond
 )
 
+// ClockSkewWarning is the clock skew between us and the other side above
+// which a warning is logged.
+var ClockSkewWarning = time.Minute
+
 // CloseTimeout is the longest we'll wait when trying to send the close
 // message before just closing the connection.
 // Should not be modified in production code, just for testing.
 var CloseTimeout = 10 * time.Second
 
-func NewConnection(deviceID DeviceID, reader io.Reader, writer io.Writer, receiver Model, name string, compress Compression) Connection {
+func NewConnection(deviceID DeviceID, reader io.Reader, writer io.Writer, receiver Model, name string, compress Compression, opts ...Option) Connection {
 	cr := &countingReader{Reader: reader}
 	cw := &countingWriter{Writer: writer}
 
@@ -212,14 +339,102 @@ func NewConnection(deviceID DeviceID, reader io.Reader, writer io.Writer, receiv
 		receiver:              nativeModel{receiver},
 		cr:                    cr,
 		cw:                    cw,
-		awaiting:              make(map[int32]chan asyncResult),
-		inbox:                 make(chan message),
-		outbox:                make(chan asyncMessage),
+		awaiting:              make(map[int32]awaitingResponse),
+		inbox:                 make(chan Message),
 		closeBox:              make(chan asyncMessage),
 		clusterConfigBox:      make(chan *ClusterConfig),
+		handshakeDone:         make(chan struct{}),
 		dispatcherLoopStopped: make(chan struct{}),
 		closed:                make(chan struct{}),
-		compression:           compress,
+		compression:           int32(compress),
+		maxRequestSize:        MaxBlockSize,
+		maxIndexLen:           MaxMessageLen,
+		healthWeights:         DefaultHealthWeights,
+		pingAttempts:          1,
+		pingBackoff:           defaultPingBackoff,
+		requestAttempts:       1,
+		requestBackoff:        defaultRequestBackoff,
+		smallMessageSize:      DefaultSmallMessageSize,
+		idlePingInterval:      DefaultIdlePingInterval,
+		pushBytes:             newByteSemaphore(defaultMaxPendingPushBytes),
+		capabilities:          CapabilityPong | CapabilityRequestPriority | CapabilityConditionalRequest | CapabilityPackedBlocks | CapabilityStreams | CapabilityRangeRequest | CapabilityInlineData | CapabilityIdleMode,
+	}
+	if _, ok := receiver.(PushModel); ok {
+		c.capabilities |= CapabilityPush
+	}
+	if qm, ok := receiver.(QuotaModel); ok {
+		c.quotaModel = qm
+		c.capabilities |= CapabilityQuota
+	}
+	if sm, ok := receiver.(IndexSummaryModel); ok {
+		c.summaryModel = sm
+		c.capabilities |= CapabilityIndexSummary
+	}
+	if ro, ok := receiver.(RequestObserver); ok {
+		c.requestObserver = ro
+	}
+	if lm, ok := receiver.(ListingModel); ok {
+		c.listingModel = lm
+		c.capabilities |= CapabilityListing
+	}
+	if fm, ok := receiver.(FileInfoModel); ok {
+		c.fileInfoModel = fm
+		c.capabilities |= CapabilityFileInfo
+	}
+	if am, ok := receiver.(IndexAckModel); ok {
+		c.indexAckModel = am
+		c.capabilities |= CapabilityIndexAck
+	}
+	if rm, ok := receiver.(IndexRejectModel); ok {
+		c.indexRejectModel = rm
+		c.capabilities |= CapabilityIndexReject
+	}
+	if crm, ok := receiver.(CloseReasonModel); ok {
+		c.closeReasonModel = crm
+	}
+	if am, ok := receiver.(AbandonedRequestsModel); ok {
+		c.abandonedModel = am
+	}
+	if im, ok := receiver.(IdleModel); ok {
+		c.idleModel = im
+	}
+	for i := range c.outboxes {
+		c.outboxes[i] = make(chan asyncMessage)
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	c.tuneConn(reader, writer)
+	if c.presharedKey != nil {
+		reader, c.encrypted = newEncryptedStreams(c.presharedKey, reader, writer)
+		writer = c.encrypted
+		cr.Reader, cw.Writer = reader, writer
+	}
+	if c.compressionTuner != nil {
+		writer = &timedWriter{Writer: writer, busy: &c.compressionTuner.busy}
+		cw.Writer = writer
+	}
+	if c.readBufferSize > 0 {
+		cr.Reader = bufio.NewReaderSize(reader, c.readBufferSize)
+	}
+	if f, ok := writer.(flusher); ok {
+		c.underlying = f
+	}
+	if c.writeBufferSize > 0 {
+		c.writeBuf = bufio.NewWriterSize(writer, c.writeBufferSize)
+		cw.Writer = c.writeBuf
+	}
+	c.enc = NewEncoder(cw, compress)
+	c.enc.SetChecksums(c.checksums)
+	c.enc.SetSmallMessageSize(c.smallMessageSize)
+	c.dec = NewDecoder(cr)
+	c.dec.SetSmallMessageSize(c.smallMessageSize)
+	c.dec.SetStreams(c.capabilities.Has(CapabilityStreams))
+	c.dec.reuseIndex = c.reuseIndex
+	c.dec.maxIndexFiles = c.maxIndexFiles
+	if c.baseline != nil {
+		c.applyBaseline(*c.baseline)
+		c.baseline = nil
 	}
 
 	return wireFormatConnection{&c}
@@ -231,11 +446,29 @@ func (c *rawConnection) Start() {
 	go c.readerLoop()
 	go func() {
 		err := c.dispatcherLoop()
-		c.internalClose(err)
+		reason := CloseReasonProtocolError
+		if cre, ok := err.(*closeReasonError); ok {
+			reason, err = cre.reason, cre.err
+		}
+		c.internalClose(reason, err)
 	}()
 	go c.writerLoop()
-	go c.pingSender()
-	go c.pingReceiver()
+	if !c.noPinger {
+		go c.pingSender()
+		go c.pingReceiver()
+	}
+	if c.handshakeTimeout > 0 {
+		go c.handshakeTimer()
+	}
+	if c.throughput != nil {
+		go c.throughputUpdater()
+	}
+	if c.compressionTuner != nil {
+		go c.compressionTuning()
+	}
+	if c.socketTunerErr != nil {
+		go c.internalClose(CloseReasonLocalClose, c.socketTunerErr)
+	}
 }
 
 func (c *rawConnection) ID() DeviceID {
@@ -246,6 +479,12 @@ func (c *rawConnection) Name() string {
 	return c.name
 }
 
+// Metadata returns the metadata given to WithMetadata, or nil. The map must
+// not be modified.
+func (c *rawConnection) Metadata() map[string]string {
+	return c.metadata
+}
+
 // Index writes the list of file information to the connected peer device
 func (c *rawConnection) Index(ctx context.Context, folder string, idx []FileInfo) error {
 	select {
@@ -253,13 +492,18 @@ func (c *rawConnection) Index(ctx context.Context, folder string, idx []FileInfo
 		return ErrClosed
 	default:
 	}
-	c.idxMut.Lock()
-	c.send(ctx, &Index{
-		Folder: folder,
-		Files:  idx,
-	}, nil)
-	c.idxMut.Unlock()
-	return nil
+	if c.isQuiescing() {
+		return ErrQuiescing
+	}
+	if c.checkIndexOrder {
+		if err := checkIndexOrder(idx); err != nil {
+			return err
+		}
+	}
+	if c.indexThrottle != nil {
+		return c.throttledIndex(ctx, folder, idx, true)
+	}
+	return c.sendIndex(ctx, folder, idx, true)
 }
 
 // IndexUpdate writes the list of file information to the connected peer device as an update
@@ -269,29 +513,116 @@ func (c *rawConnection) IndexUpdate(ctx context.Context, folder string, idx []Fi
 		return ErrClosed
 	default:
 	}
+	if c.isQuiescing() {
+		return ErrQuiescing
+	}
+	if c.checkIndexOrder {
+		if err := checkIndexOrder(idx); err != nil {
+			return err
+		}
+	}
+	if c.indexThrottle != nil {
+		return c.throttledIndex(ctx, folder, idx, false)
+	}
+	return c.sendIndex(ctx, folder, idx, false)
+}
+
+// sendIndex sends the files as an Index message when full is set, or as an
+// IndexUpdate otherwise. Files that don't fit in one message are sent as
+// several with WithIndexSplitting, the rest as IndexUpdates, and are
+// otherwise refused with ErrIndexTooLarge.
+func (c *rawConnection) sendIndex(ctx context.Context, folder string, idx []FileInfo, full bool) error {
+	idx = stripInlineData(idx, c.peerSupports(CapabilityInlineData))
+	if c.peerSupports(CapabilityPackedBlocks) {
+		idx = packBlocks(idx)
+	}
+	batches, err := splitIndex(folder, idx, c.maxIndexLen, c.MaxIndexFiles())
+	if err != nil {
+		return err
+	}
+	if len(batches) > 1 && !c.splitIndex {
+		return ErrIndexTooLarge
+	}
+
 	c.idxMut.Lock()
-	c.send(ctx, &IndexUpdate{
-		Folder: folder,
-		Files:  idx,
-	}, nil)
-	c.idxMut.Unlock()
+	defer c.idxMut.Unlock()
+	// Quiesce waits for the batches once we have started on them, so the
+	// other side doesn't end up with a part of the index.
+	if c.isQuiescing() {
+		return ErrQuiescing
+	}
+	for _, files := range batches {
+		var msg Message = &IndexUpdate{
+			Folder: folder,
+			Files:  files,
+		}
+		if full {
+			msg = &Index{
+				Folder: folder,
+				Files:  files,
+			}
+			full = false
+		}
+		if !c.send(ctx, msg, nil) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return ErrClosed
+		}
+	}
 	return nil
 }
 
 // Request returns the bytes for the specified block after fetching them from the connected peer.
+// The returned slice is non-nil, though possibly empty, whenever the error is nil.
 func (c *rawConnection) Request(ctx context.Context, folder string, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
-	c.nextIDMut.Lock()
-	id := c.nextID
-	c.nextID++
-	c.nextIDMut.Unlock()
+	if c.sharedRequests != nil {
+		return c.sharedRequest(ctx, folder, name, offset, size, hash, weakHash, fromTemporary)
+	}
+	return c.request(ctx, folder, name, offset, size, hash, weakHash, fromTemporary)
+}
 
-	c.awaitingMut.Lock()
-	if _, ok := c.awaiting[id]; ok {
-		panic("id taken")
+func (c *rawConnection) request(ctx context.Context, folder string, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
+	if size > MaxBlockSize {
+		// The response wouldn't be accepted.
+		return nil, ErrRequestTooLarge
+	}
+	if c.responseBytes != nil {
+		if err := c.responseBytes.take(ctx, size); err != nil {
+			return nil, err
+		}
+		defer c.responseBytes.give(size)
+	}
+
+	var prio RequestPriority
+	if c.peerSupports(CapabilityRequestPriority) {
+		prio = requestPriority(ctx)
+	}
+	var present []byte
+	if c.peerSupports(CapabilityConditionalRequest) {
+		present = presentHash(ctx)
+	}
+
+	var timeoutMs int64
+	if deadline, ok := ctx.Deadline(); ok {
+		// The other side counts from when it receives the request, so our
+		// clocks needn't agree.
+		timeoutMs = int64(time.Until(deadline) / time.Millisecond)
+		if timeoutMs < 1 {
+			timeoutMs = 1
+		}
+	}
+
+	id, rc, err := c.newAwaiting(messageTypeRequest)
+	if err != nil {
+		return nil, err
+	}
+	c.limitResponse(id, size)
+
+	var sent time.Time
+	if c.latencies != nil {
+		sent = time.Now()
 	}
-	rc := make(chan asyncResult, 1)
-	c.awaiting[id] = rc
-	c.awaitingMut.Unlock()
 
 	ok := c.send(ctx, &Request{
 		ID:            id,
@@ -302,8 +633,12 @@ func (c *rawConnection) Request(ctx context.Context, folder string, name string,
 		Hash:          hash,
 		WeakHash:      weakHash,
 		FromTemporary: fromTemporary,
+		Priority:      prio,
+		PresentHash:   present,
+		TimeoutMs:     timeoutMs,
 	}, nil)
 	if !ok {
+		c.forgetAwaiting(id)
 		return nil, ErrClosed
 	}
 
@@ -312,15 +647,99 @@ func (c *rawConnection) Request(ctx context.Context, folder string, name string,
 		if !ok {
 			return nil, ErrClosed
 		}
+		if c.latencies != nil {
+			c.latencies.record(time.Since(sent))
+		}
+		if c.folderStats != nil {
+			c.folderStats.inResponse(folder, len(res.val))
+		}
+		c.errorRate.record(res.err)
 		return res.val, res.err
 	case <-ctx.Done():
+		// A late response is then ignored.
+		c.forgetAwaiting(id)
 		return nil, ctx.Err()
 	}
 }
 
+// newAwaiting allocates a message ID and a channel to receive the response
+// to the message of the given type with that ID on. It returns
+// ErrQuiescing for anything but pings after Quiesce.
+func (c *rawConnection) newAwaiting(msgType MessageType) (int32, chan asyncResult, error) {
+	c.awaitingMut.Lock()
+	defer c.awaitingMut.Unlock()
+	if c.quiescing && msgType != messageTypePing {
+		return 0, nil, ErrQuiescing
+	}
+	id := c.allocateID()
+	for {
+		if _, ok := c.awaiting[id]; !ok {
+			break
+		}
+		// Still waiting for an answer to a message with the same ID, e.g.
+		// after the IDs wrapped around.
+		l.Debugln(c.id, "message ID", id, "taken")
+		id = c.allocateID()
+	}
+	rc := make(chan asyncResult, 1)
+	c.awaiting[id] = awaitingResponse{ch: rc, sent: time.Now(), msgType: msgType}
+
+	return id, rc, nil
+}
+
+// allocateID returns the next message ID, which may be one still awaiting
+// an answer.
+func (c *rawConnection) allocateID() int32 {
+	if c.idSource != nil {
+		return c.idSource()
+	}
+	c.nextIDMut.Lock()
+	id := c.nextID
+	c.nextID++
+	c.nextIDMut.Unlock()
+	return id
+}
+
+// limitResponse sets the most data accepted in the response to the request
+// with the given ID.
+func (c *rawConnection) limitResponse(id int32, size int) {
+	c.awaitingMut.Lock()
+	defer c.awaitingMut.Unlock()
+	if ar, ok := c.awaiting[id]; ok {
+		ar.maxLen = size
+		c.awaiting[id] = ar
+	}
+}
+
+// responseLimit returns the most data accepted in the response to the
+// request with the given ID, if it is a request we are waiting for.
+func (c *rawConnection) responseLimit(id int32) (int, bool) {
+	c.awaitingMut.Lock()
+	defer c.awaitingMut.Unlock()
+	ar, ok := c.awaiting[id]
+	if !ok || ar.msgType != messageTypeRequest {
+		return 0, false
+	}
+	return ar.maxLen, true
+}
+
+// forgetAwaiting drops interest in the response to the message with the
+// given ID.
+func (c *rawConnection) forgetAwaiting(id int32) {
+	c.awaitingMut.Lock()
+	delete(c.awaiting, id)
+	c.checkDrainedLocked()
+	c.awaitingMut.Unlock()
+}
+
 // ClusterConfig sends the cluster configuration message to the peer.
-// It must be called just once (as per BEP), otherwise it will panic.
+// It must be called just once (as per BEP), otherwise it will panic. The
+// capabilities are set by the connection, as given by the model.
 func (c *rawConnection) ClusterConfig(config ClusterConfig) {
+	config.Capabilities = c.capabilities
+	config.PreferredBlockSize = int32(c.preferredBlockSize)
+	config.MaxIndexFiles = int32(c.maxIndexFiles)
+	config.IdlePingIntervalS = int32(c.idlePingInterval / time.Second)
 	select {
 	case c.clusterConfigBox <- &config:
 		close(c.clusterConfigBox)
@@ -337,8 +756,78 @@ func (c *rawConnection) Closed() bool {
 	}
 }
 
+// WaitHandshake blocks until the cluster config from the other side has
+// been received and processed by the model, returning nil, or until the
+// context is done or the connection is closed, returning the respective
+// error. Messages may be sent before the handshake is complete; they are
+// queued until our own cluster config has been sent.
+func (c *rawConnection) WaitHandshake(ctx context.Context) error {
+	select {
+	case <-c.handshakeDone:
+		return nil
+	case <-c.closed:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SetCompression changes how messages sent from now on are compressed. It
+// takes effect with the next message written; as each message says how it
+// is compressed, the other side needs no notice. This pins the compression,
+// ending auto tuning if enabled by WithCompressionAutoTuning.
+func (c *rawConnection) SetCompression(compress Compression) {
+	if t := c.compressionTuner; t != nil {
+		t.mut.Lock()
+		defer t.mut.Unlock()
+		t.pinned = true
+	}
+	atomic.StoreInt32(&c.compression, int32(compress))
+}
+
+// Compression returns how messages sent from now on are compressed, as
+// given to NewConnection or SetCompression, or as last chosen by auto
+// tuning.
+func (c *rawConnection) Compression() Compression {
+	return Compression(atomic.LoadInt32(&c.compression))
+}
+
+// Verify checks that the connection works end to end: it waits for the
+// handshake to complete, then for a ping to be answered. With an older
+// device that doesn't answer pings, the received cluster config is the only
+// proof and Verify returns once the ping has been sent. The error is that
+// of the failing step, e.g. ErrClosed or that of the context.
+func (c *rawConnection) Verify(ctx context.Context) error {
+	if err := c.WaitHandshake(ctx); err != nil {
+		return err
+	}
+	if _, err := c.Ping(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+// handshakeTimer closes the connection if the handshake isn't completed
+// within the handshake timeout.
+func (c *rawConnection) handshakeTimer() {
+	t := time.NewTimer(c.handshakeTimeout)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		l.Debugln(c.id, "handshake timeout")
+		c.internalClose(CloseReasonHandshakeFailure, ErrHandshakeTimeout)
+	case <-c.handshakeDone:
+	case <-c.closed:
+	}
+}
+
 // DownloadProgress sends the progress updates for the files that are currently being downloaded.
 func (c *rawConnection) DownloadProgress(ctx context.Context, folder string, updates []FileDownloadProgressUpdate) {
+	if c.PeerIdle() {
+		// Nothing it can't do without while it is idle.
+		l.Debugln(c.id, "download progress dropped while idle")
+		return
+	}
 	c.send(ctx, &DownloadProgress{
 		Folder:  folder,
 		Updates: updates,
@@ -346,24 +835,128 @@ func (c *rawConnection) DownloadProgress(ctx context.Context, folder string, upd
 }
 
 func (c *rawConnection) ping() bool {
-	return c.send(context.Background(), &Ping{}, nil)
+	return c.send(context.Background(), &Ping{TimeNs: time.Now().UnixNano()}, nil)
+}
+
+// Ping sends a ping to the other side. If the other side supports it, Ping
+// waits for the answering pong and returns the round trip time. Otherwise
+// it returns zero as soon as the ping has been sent.
+func (c *rawConnection) Ping(ctx context.Context) (time.Duration, error) {
+	if c.peerSupports(CapabilityPong) {
+		return c.measureLatency(ctx)
+	}
+	if !c.send(ctx, &Ping{TimeNs: time.Now().UnixNano()}, nil) {
+		return 0, ErrClosed
+	}
+	return 0, nil
+}
+
+// measureLatency sends a ping asking for a pong, and returns the time until
+// the pong is received. The result is also kept for reporting in
+// Statistics and to the other side.
+func (c *rawConnection) measureLatency(ctx context.Context) (time.Duration, error) {
+	id, rc, _ := c.newAwaiting(messageTypePing)
+	for id == 0 {
+		// Zero means no pong is wanted.
+		c.forgetAwaiting(id)
+		id, rc, _ = c.newAwaiting(messageTypePing)
+	}
+	t0 := time.Now()
+	if !c.send(ctx, &Ping{ID: id, TimeNs: t0.UnixNano()}, nil) {
+		c.forgetAwaiting(id)
+		return 0, ErrClosed
+	}
+
+	select {
+	case res, ok := <-rc:
+		if !ok {
+			return 0, ErrClosed
+		}
+		d := time.Since(t0)
+		atomic.StoreInt64(&c.latency, int64(d))
+		if pong, ok := res.msg.(*Pong); ok && pong.TimeNs != 0 {
+			// Assume the pong was sent halfway through the round trip.
+			c.setClockSkew(time.Unix(0, pong.TimeNs).Sub(t0.Add(d / 2)))
+		}
+		return d, nil
+	case <-ctx.Done():
+		c.forgetAwaiting(id)
+		return 0, ctx.Err()
+	}
+}
+
+// ClockSkew returns how far ahead of ours the clock on the other side is,
+// as measured by the latest ping, or zero if unknown.
+func (c *rawConnection) ClockSkew() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.clockSkew))
+}
+
+func (c *rawConnection) setClockSkew(skew time.Duration) {
+	atomic.StoreInt64(&c.clockSkew, int64(skew))
+	atomic.StoreInt32(&c.clockSkewKnown, 1)
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+	if abs > ClockSkewWarning && atomic.CompareAndSwapInt32(&c.clockSkewWarned, 0, 1) {
+		l.Warnf("The clock on device %v (%s) differs from ours by %v; this may cause sync conflicts to be resolved incorrectly", c.id, c.name, skew)
+	}
+}
+
+// measureOneWayLatency records the time since sent, a time on the clock
+// of the other side, once the clock skew is known.
+func (c *rawConnection) measureOneWayLatency(sent time.Time) {
+	if atomic.LoadInt32(&c.clockSkewKnown) == 0 {
+		return
+	}
+	d := time.Since(sent.Add(-c.ClockSkew()))
+	if d < 0 {
+		// Clocks aren't that precise
+		d = 0
+	}
+	atomic.StoreInt64(&c.oneWayLatency, int64(d))
+}
+
+// peerSupports returns true if the handshake is complete and the other
+// side advertised the given capabilities.
+func (c *rawConnection) peerSupports(caps Capabilities) bool {
+	select {
+	case <-c.handshakeDone:
+		return c.peerCapabilities.Has(caps)
+	default:
+		return false
+	}
 }
 
 func (c *rawConnection) readerLoop() {
-	fourByteBuf := make([]byte, 4)
 	for {
-		msg, err := c.readMessage(fourByteBuf)
+		hdr, err := c.dec.DecodeHeader()
+		var msg Message
+		if err == nil {
+			msg, err = c.dec.DecodeMessage(hdr)
+		}
+		if c.tap != nil && (err == nil || err == ErrUnknownMessage) {
+			c.tap(DirectionIn, hdr.Type, messageID(msg), c.dec.size)
+		}
 		if err != nil {
-			if err == errUnknownMessage {
-				// Unknown message types are skipped, for future extensibility.
-				continue
+			if err == ErrUnknownMessage {
+				if c.skipUnknown {
+					l.Debugf("%s: skipping message of unknown type %v", c.id, hdr.Type)
+					continue
+				}
+				c.internalClose(CloseReasonProtocolError, errors.Wrapf(err, "type %v", hdr.Type))
+				return
 			}
-			c.internalClose(err)
+			c.internalClose(CloseReasonReadError, err)
 			return
 		}
+		if c.folderStats != nil {
+			c.folderStats.inMessage(msg)
+		}
 		select {
 		case c.inbox <- msg:
 		case <-c.closed:
+			atomic.AddInt64(&c.discardedMessages, 1)
 			return
 		}
 
@@ -372,7 +965,7 @@ func (c *rawConnection) readerLoop() {
 
 func (c *rawConnection) dispatcherLoop() (err error) {
 	defer close(c.dispatcherLoopStopped)
-	var msg message
+	var msg Message
 	state := stateInitial
 	for {
 		select {
@@ -380,6 +973,14 @@ func (c *rawConnection) dispatcherLoop() (err error) {
 		case <-c.closed:
 			return ErrClosed
 		}
+		select {
+		case <-c.closed:
+			// Both were ready; once closed nothing more is dispatched, so
+			// that the model sees the same regardless of which was picked.
+			atomic.AddInt64(&c.discardedMessages, 1)
+			return ErrClosed
+		default:
+		}
 		switch msg := msg.(type) {
 		case *ClusterConfig:
 			l.Debugln("read ClusterConfig message")
@@ -387,20 +988,55 @@ func (c *rawConnection) dispatcherLoop() (err error) {
 				return fmt.Errorf("protocol error: cluster config message in state %d", state)
 			}
 			if err := c.receiver.ClusterConfig(c.id, *msg); err != nil {
-				return errors.Wrap(err, "receiver error")
+				return &closeReasonError{CloseReasonHandshakeFailure, errors.Wrap(err, "receiver error")}
 			}
 			state = stateReady
+			c.peerCapabilities = msg.Capabilities
+			c.agreedBlockSize = agreeBlockSize(c.preferredBlockSize, int(msg.PreferredBlockSize))
+			c.agreedMaxIndexFiles = agreeMaxIndexFiles(c.maxIndexFiles, int(msg.MaxIndexFiles))
+			if c.peerCapabilities.Has(CapabilityIdleMode) {
+				c.agreedIdlePingInterval = agreeIdlePingInterval(c.idlePingInterval, time.Duration(msg.IdlePingIntervalS)*time.Second)
+			}
+			close(c.handshakeDone)
+			if !c.noPinger && c.peerCapabilities.Has(CapabilityPong) {
+				// Get an early idea of latency and clock skew.
+				go func() {
+					ctx, cancel := context.WithTimeout(context.Background(), ReceiveTimeout)
+					defer cancel()
+					_, _ = c.measureLatency(ctx)
+				}()
+			}
 
 		case *Index:
 			l.Debugln("read Index message")
 			if state != stateReady {
 				return fmt.Errorf("protocol error: index message in state %d", state)
 			}
+			if max := c.agreedMaxIndexFiles; max > 0 && len(msg.Files) > max {
+				return errors.Wrapf(ErrIndexTooLarge, "protocol error: index with %d files, more than %d", len(msg.Files), max)
+			}
+			if err := unpackBlocks(msg.Files); err != nil {
+				return errors.Wrap(err, "protocol error: index")
+			}
 			if err := checkIndexConsistency(msg.Files); err != nil {
 				return errors.Wrap(err, "protocol error: index")
 			}
-			if err := c.handleIndex(*msg); err != nil {
-				return errors.Wrap(err, "receiver error")
+			if c.checkIndexOrder {
+				if err := checkIndexOrder(msg.Files); err != nil {
+					return errors.Wrap(err, "protocol error: index")
+				}
+			}
+			err := c.handleIndex(*msg)
+			if err == nil {
+				c.ackIndex(msg.Folder, msg.Files)
+			} else if c.rejectIndex(msg.Folder, msg.Files, err) {
+				err = nil
+			}
+			if c.reuseIndex {
+				putIndexFiles(msg.Files)
+			}
+			if err != nil {
+				return &closeReasonError{CloseReasonLocalClose, errors.Wrap(err, "receiver error")}
 			}
 			state = stateReady
 
@@ -409,14 +1045,50 @@ func (c *rawConnection) dispatcherLoop() (err error) {
 			if state != stateReady {
 				return fmt.Errorf("protocol error: index update message in state %d", state)
 			}
+			if max := c.agreedMaxIndexFiles; max > 0 && len(msg.Files) > max {
+				return errors.Wrapf(ErrIndexTooLarge, "protocol error: index update with %d files, more than %d", len(msg.Files), max)
+			}
+			if err := unpackBlocks(msg.Files); err != nil {
+				return errors.Wrap(err, "protocol error: index update")
+			}
 			if err := checkIndexConsistency(msg.Files); err != nil {
 				return errors.Wrap(err, "protocol error: index update")
 			}
-			if err := c.handleIndexUpdate(*msg); err != nil {
-				return errors.Wrap(err, "receiver error")
+			if c.checkIndexOrder {
+				if err := checkIndexOrder(msg.Files); err != nil {
+					return errors.Wrap(err, "protocol error: index update")
+				}
+			}
+			err := c.handleIndexUpdate(*msg)
+			if err == nil {
+				c.ackIndex(msg.Folder, msg.Files)
+			} else if c.rejectIndex(msg.Folder, msg.Files, err) {
+				err = nil
+			}
+			if c.reuseIndex {
+				putIndexFiles(msg.Files)
+			}
+			if err != nil {
+				return &closeReasonError{CloseReasonLocalClose, errors.Wrap(err, "receiver error")}
 			}
 			state = stateReady
 
+		case *IndexAck:
+			l.Debugln("read IndexAck message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: index ack message in state %d", state)
+			}
+			c.handleIndexAck(*msg)
+
+		case *IndexSummary:
+			l.Debugln("read IndexSummary message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: index summary message in state %d", state)
+			}
+			if err := c.handleIndexSummary(*msg); err != nil {
+				return &closeReasonError{CloseReasonLocalClose, errors.Wrap(err, "receiver error")}
+			}
+
 		case *Request:
 			l.Debugln("read Request message")
 			if state != stateReady {
@@ -425,14 +1097,61 @@ func (c *rawConnection) dispatcherLoop() (err error) {
 			if err := checkFilename(msg.Name); err != nil {
 				return errors.Wrapf(err, "protocol error: request: %q", msg.Name)
 			}
-			go c.handleRequest(*msg)
+			if c.authorizer != nil {
+				if err := c.authorizer(c.id, msg.Folder, msg.Name, msg.Offset, int(msg.Size)); err != nil {
+					l.Debugf("Request(%v, %v, %q, %d, %d) not authorized: %v", c.id, msg.Folder, msg.Name, msg.Offset, msg.Size, err)
+					go c.send(context.Background(), &Response{
+						ID:   msg.ID,
+						Code: errorToCode(ErrUnauthorized),
+					}, nil)
+					continue
+				}
+			}
+			req := *msg
+			var deadline time.Time
+			if req.TimeoutMs > 0 {
+				deadline = time.Now().Add(time.Duration(req.TimeoutMs) * time.Millisecond)
+			}
+			c.requests.schedule(req.Priority, func() { c.handleRequest(req, deadline) })
+
+		case *RangeRequest:
+			l.Debugln("read RangeRequest message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: range request message in state %d", state)
+			}
+			if err := checkFilename(msg.Name); err != nil {
+				return errors.Wrapf(err, "protocol error: range request: %q", msg.Name)
+			}
+			req := *msg
+			c.requests.schedule(PriorityNormal, func() { c.handleRangeRequest(req) })
+
+		case *RangeResponse:
+			l.Debugln("read RangeResponse message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: range response message in state %d", state)
+			}
+			if err := c.handleRangeResponse(*msg); err != nil {
+				return err
+			}
 
 		case *Response:
 			l.Debugln("read Response message")
 			if state != stateReady {
 				return fmt.Errorf("protocol error: response message in state %d", state)
 			}
-			c.handleResponse(*msg)
+			if err := c.handleResponse(*msg); err != nil {
+				return err
+			}
+
+		case *Push:
+			l.Debugln("read Push message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: push message in state %d", state)
+			}
+			if err := checkFilename(msg.Name); err != nil {
+				return errors.Wrapf(err, "protocol error: push: %q", msg.Name)
+			}
+			go c.handlePush(*msg)
 
 		case *DownloadProgress:
 			l.Debugln("read DownloadProgress message")
@@ -440,7 +1159,7 @@ func (c *rawConnection) dispatcherLoop() (err error) {
 				return fmt.Errorf("protocol error: response message in state %d", state)
 			}
 			if err := c.receiver.DownloadProgress(c.id, msg.Folder, msg.Updates); err != nil {
-				return errors.Wrap(err, "receiver error")
+				return &closeReasonError{CloseReasonLocalClose, errors.Wrap(err, "receiver error")}
 			}
 
 		case *Ping:
@@ -448,105 +1167,99 @@ func (c *rawConnection) dispatcherLoop() (err error) {
 			if state != stateReady {
 				return fmt.Errorf("protocol error: ping message in state %d", state)
 			}
-			// Nothing
-
-		case *Close:
-			l.Debugln("read Close message")
-			return errors.New(msg.Reason)
+			if msg.TimeNs != 0 {
+				c.measureOneWayLatency(time.Unix(0, msg.TimeNs))
+			}
+			if msg.ID != 0 {
+				go c.send(context.Background(), &Pong{
+					ID:        msg.ID,
+					LatencyNs: atomic.LoadInt64(&c.latency),
+					TimeNs:    time.Now().UnixNano(),
+				}, nil)
+			}
 
-		default:
-			l.Debugf("read unknown message: %+T", msg)
-			return fmt.Errorf("protocol error: %s: unknown or empty message", c.id)
-		}
-	}
-}
+		case *QuotaRequest:
+			l.Debugln("read QuotaRequest message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: quota request message in state %d", state)
+			}
+			go c.handleQuotaRequest(*msg)
 
-func (c *rawConnection) readMessage(fourByteBuf []byte) (message, error) {
-	hdr, err := c.readHeader(fourByteBuf)
-	if err != nil {
-		return nil, err
-	}
+		case *QuotaResponse:
+			l.Debugln("read QuotaResponse message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: quota response message in state %d", state)
+			}
+			if err := c.handleQuotaResponse(*msg); err != nil {
+				return err
+			}
 
-	return c.readMessageAfterHeader(hdr, fourByteBuf)
-}
+		case *ListingRequest:
+			l.Debugln("read ListingRequest message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: listing request message in state %d", state)
+			}
+			if msg.Path != "" {
+				if err := checkFilename(msg.Path); err != nil {
+					return errors.Wrapf(err, "protocol error: listing request: %q", msg.Path)
+				}
+			}
+			go c.handleListingRequest(*msg)
 
-func (c *rawConnection) readMessageAfterHeader(hdr Header, fourByteBuf []byte) (message, error) {
-	// First comes a 4 byte message length
+		case *ListingResponse:
+			l.Debugln("read ListingResponse message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: listing response message in state %d", state)
+			}
+			if err := c.handleListingResponse(*msg); err != nil {
+				return err
+			}
 
-	if _, err := io.ReadFull(c.cr, fourByteBuf[:4]); err != nil {
-		return nil, errors.Wrap(err, "reading message length")
-	}
-	msgLen := int32(binary.BigEndian.Uint32(fourByteBuf))
-	if msgLen < 0 {
-		return nil, fmt.Errorf("negative message length %d", msgLen)
-	} else if msgLen > MaxMessageLen {
-		return nil, fmt.Errorf("message length %d exceeds maximum %d", msgLen, MaxMessageLen)
-	}
+		case *FileInfoRequest:
+			l.Debugln("read FileInfoRequest message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: file info request message in state %d", state)
+			}
+			if err := checkFilename(msg.Name); err != nil {
+				return errors.Wrapf(err, "protocol error: file info request: %q", msg.Name)
+			}
+			go c.handleFileInfoRequest(*msg)
 
-	// Then comes the message
+		case *FileInfoResponse:
+			l.Debugln("read FileInfoResponse message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: file info response message in state %d", state)
+			}
+			if err := c.handleFileInfoResponse(*msg); err != nil {
+				return err
+			}
 
-	buf := BufferPool.Get(int(msgLen))
-	if _, err := io.ReadFull(c.cr, buf); err != nil {
-		return nil, errors.Wrap(err, "reading message")
-	}
+		case *Pong:
+			l.Debugln("read Pong message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: pong message in state %d", state)
+			}
+			atomic.StoreInt64(&c.peerLatency, msg.LatencyNs)
+			if err := c.resolveAwaiting(msg.ID, messageTypePong, asyncResult{msg: msg}); err != nil {
+				return err
+			}
 
-	// ... which might be compressed
+		case *Idle:
+			l.Debugln("read Idle message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: idle message in state %d", state)
+			}
+			c.handleIdle(*msg)
 
-	switch hdr.Compression {
-	case MessageCompressionNone:
-		// Nothing
+		case *Close:
+			l.Debugln("read Close message")
+			return &closeReasonError{CloseReasonPeerClose, errors.New(msg.Reason)}
 
-	case MessageCompressionLZ4:
-		decomp, err := c.lz4Decompress(buf)
-		BufferPool.Put(buf)
-		if err != nil {
-			return nil, errors.Wrap(err, "decompressing message")
+		default:
+			l.Debugf("read unknown message: %+T", msg)
+			return fmt.Errorf("protocol error: %s: unknown or empty message", c.id)
 		}
-		buf = decomp
-
-	default:
-		return nil, fmt.Errorf("unknown message compression %d", hdr.Compression)
-	}
-
-	// ... and is then unmarshalled
-
-	msg, err := c.newMessage(hdr.Type)
-	if err != nil {
-		return nil, err
-	}
-	if err := msg.Unmarshal(buf); err != nil {
-		return nil, errors.Wrap(err, "unmarshalling message")
-	}
-	BufferPool.Put(buf)
-
-	return msg, nil
-}
-
-func (c *rawConnection) readHeader(fourByteBuf []byte) (Header, error) {
-	// First comes a 2 byte header length
-
-	if _, err := io.ReadFull(c.cr, fourByteBuf[:2]); err != nil {
-		return Header{}, errors.Wrap(err, "reading length")
-	}
-	hdrLen := int16(binary.BigEndian.Uint16(fourByteBuf))
-	if hdrLen < 0 {
-		return Header{}, fmt.Errorf("negative header length %d", hdrLen)
-	}
-
-	// Then comes the header
-
-	buf := BufferPool.Get(int(hdrLen))
-	if _, err := io.ReadFull(c.cr, buf); err != nil {
-		return Header{}, errors.Wrap(err, "reading header")
-	}
-
-	var hdr Header
-	if err := hdr.Unmarshal(buf); err != nil {
-		return Header{}, errors.Wrap(err, "unmarshalling header")
 	}
-
-	BufferPool.Put(buf)
-	return hdr, nil
 }
 
 func (c *rawConnection) handleIndex(im Index) error {
@@ -570,6 +1283,17 @@ func checkIndexConsistency(fs []FileInfo) error {
 	return nil
 }
 
+// checkIndexOrder verifies that the files are sorted by name, without
+// duplicates.
+func checkIndexOrder(fs []FileInfo) error {
+	for i := 1; i < len(fs); i++ {
+		if fs[i].Name <= fs[i-1].Name {
+			return errors.Wrapf(ErrIndexOrder, "%q after %q", fs[i].Name, fs[i-1].Name)
+		}
+	}
+	return nil
+}
+
 // checkFileInfoConsistency verifies a number of invariants on the given FileInfo
 func checkFileInfoConsistency(f FileInfo) error {
 	if err := checkFilename(f.Name); err != nil {
@@ -585,9 +1309,14 @@ func checkFileInfoConsistency(f FileInfo) error {
 		// Directories should have no blocks
 		return errDirectoryHasBlocks
 
-	case !f.Deleted && !f.IsInvalid() && f.Type == FileInfoTypeFile && len(f.Blocks) == 0:
-		// Non-deleted, non-invalid files should have at least one block
+	case !f.Deleted && !f.IsInvalid() && f.Type == FileInfoTypeFile && len(f.Blocks) == 0 && f.Size != 0:
+		// Non-deleted, non-invalid files should have at least one block,
+		// unless they are empty
 		return errFileHasNoBlocks
+
+	case len(f.InlineData) != 0 && !validInlineData(f):
+		// Inline data must be the entire content of a small file
+		return errInvalidInlineData
 	}
 	return nil
 }
@@ -621,239 +1350,486 @@ func checkFilename(name string) error {
 	return nil
 }
 
-func (c *rawConnection) handleRequest(req Request) {
-	res, err := c.receiver.Request(c.id, req.Folder, req.Name, req.Size, req.Offset, req.Hash, req.WeakHash, req.FromTemporary)
-	if err != nil {
+// handleRequest serves the request, unless the deadline, if non-zero, has
+// passed or the connection has closed by the time we get to it.
+func (c *rawConnection) handleRequest(req Request, deadline time.Time) {
+	if int(req.Size) > c.maxRequestSize {
+		// Refuse to even ask the model about it, as that could result in
+		// an allocation of whatever size the other side asked for.
+		l.Debugf("Request(%v, %v, %q, %d) exceeds max request size %d", c.id, req.Folder, req.Name, req.Size, c.maxRequestSize)
 		c.send(context.Background(), &Response{
 			ID:   req.ID,
-			Code: errorToCode(err),
+			Code: errorToCode(ErrRequestTooLarge),
 		}, nil)
 		return
 	}
+
+	if !deadline.IsZero() && time.Now().After(deadline) {
+		l.Debugf("Request(%v, %v, %q, %d, %d) expired before being served", c.id, req.Folder, req.Name, req.Offset, req.Size)
+		c.send(context.Background(), errorResponse(req.ID, ErrExpired), nil)
+		return
+	}
+
+	if c.Closed() {
+		// There is nobody to answer anymore, e.g. for requests still queued
+		// when the connection closed, so don't bother the model.
+		return
+	}
+
+	if c.requestObserver != nil {
+		c.requestObserver.RequestStarted(c.id, req.Folder, req.Name)
+		defer c.requestObserver.RequestFinished(c.id, req.Folder, req.Name)
+	}
+
+	res, err := c.cachedModelRequest(req, deadline)
+	if err != nil {
+		c.send(context.Background(), errorResponse(req.ID, err), nil)
+		return
+	}
+	if len(req.PresentHash) > 0 && hashMatches(res.Data(), req.PresentHash) {
+		// The other side already has this data.
+		res.Close()
+		c.send(context.Background(), errorResponse(req.ID, ErrNotModified), nil)
+		return
+	}
 	done := make(chan struct{})
-	c.send(context.Background(), &Response{
-		ID:   req.ID,
-		Data: res.Data(),
-		Code: errorToCode(nil),
-	}, done)
+	c.sendMessage(context.Background(), asyncMessage{
+		msg: &Response{
+			ID:   req.ID,
+			Data: res.Data(),
+			Code: errorToCode(nil),
+		},
+		done:           done,
+		incompressible: isIncompressible(res),
+	})
 	<-done
+	if c.folderStats != nil {
+		c.folderStats.outResponse(req.Folder, len(res.Data()))
+	}
 	res.Close()
 }
 
-func (c *rawConnection) handleResponse(resp Response) {
+// modelRequest passes the request to the model. Unless disabled, a panic in
+// the model is logged and turned into an error.
+func (c *rawConnection) modelRequest(req Request) (res RequestResponse, err error) {
+	if !c.noPanicRecovery {
+		defer func() {
+			if r := recover(); r != nil {
+				l.Warnf("Panic handling request for %q in folder %q from %v: %v\n%s", req.Name, req.Folder, c.id, r, debug.Stack())
+				res, err = nil, ErrGeneric
+			}
+		}()
+	}
+	res, err = c.receiver.Request(c.id, req.Folder, req.Name, req.Size, req.Offset, req.Hash, req.WeakHash, req.FromTemporary)
+	if err == nil && (res == nil || len(res.Data()) != int(req.Size)) {
+		// The model couldn't get a buffer of the right size, which may
+		// well work out when retried.
+		if res != nil {
+			l.Debugf("Request(%v, %v, %q, %d, %d) returned %d bytes", c.id, req.Folder, req.Name, req.Offset, req.Size, len(res.Data()))
+			res.Close()
+		}
+		return nil, &TemporaryError{ErrBufferUnavailable}
+	}
+	return res, err
+}
+
+// modelRequestWithRetries passes the request to the model, retrying it with
+// exponentially increasing waits while the model fails with a temporary
+// error, up to the configured number of attempts in total. It gives up
+// early rather than wait past the deadline, if non-zero, or when the
+// connection closes.
+func (c *rawConnection) modelRequestWithRetries(req Request, deadline time.Time) (RequestResponse, error) {
+	backoff := c.requestBackoff
+	for attempt := 1; ; attempt++ {
+		res, err := c.modelRequest(req)
+		if err == nil || attempt >= c.requestAttempts || !isTemporary(err) {
+			return res, err
+		}
+		if !deadline.IsZero() && time.Now().Add(backoff).After(deadline) {
+			return nil, err
+		}
+		l.Debugf("Request(%v, %v, %q, %d, %d) attempt %d failed, retrying in %v: %v", c.id, req.Folder, req.Name, req.Offset, req.Size, attempt, backoff, err)
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-c.closed:
+			t.Stop()
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+func (c *rawConnection) handleResponse(resp Response) error {
+	if max, ok := c.responseLimit(resp.ID); ok && len(resp.Data) > max {
+		return errors.Wrapf(ErrResponseTooLong, "protocol error: response %d with %d bytes for a request of %d", resp.ID, len(resp.Data), max)
+	}
+	err := responseError(resp)
+	data := resp.Data
+	if err == nil && data == nil {
+		// Empty data isn't sent at all; the response code tells us this
+		// is an empty block and not a failure.
+		data = []byte{}
+	}
+	return c.resolveAwaiting(resp.ID, messageTypeResponse, asyncResult{val: data, err: err})
+}
+
+// resolveAwaiting hands the result to whoever is waiting for the response
+// to the message with the given ID. A response to a message nobody is
+// waiting for anymore is ignored; one of the wrong type is a protocol
+// error.
+func (c *rawConnection) resolveAwaiting(id int32, respType MessageType, res asyncResult) error {
 	c.awaitingMut.Lock()
-	if rc := c.awaiting[resp.ID]; rc != nil {
-		delete(c.awaiting, resp.ID)
-		rc <- asyncResult{resp.Data, codeToError(resp.Code)}
-		close(rc)
+	defer c.awaitingMut.Unlock()
+	ar := c.awaiting[id]
+	if ar.ch == nil {
+		return nil
 	}
-	c.awaitingMut.Unlock()
+	if !answers(respType, ar.msgType) {
+		return fmt.Errorf("protocol error: %v in response to %v %d", respType, ar.msgType, id)
+	}
+	delete(c.awaiting, id)
+	ar.ch <- res
+	close(ar.ch)
+	c.checkDrainedLocked()
+	return nil
+}
+
+// answers returns true if a message of type resp answers one of type req.
+func answers(resp, req MessageType) bool {
+	switch resp {
+	case messageTypeResponse:
+		return req == messageTypeRequest || req == messageTypePush
+	case messageTypePong:
+		return req == messageTypePing
+	case messageTypeQuotaResponse:
+		return req == messageTypeQuotaRequest
+	case messageTypeListingResponse:
+		return req == messageTypeListingRequest
+	case messageTypeFileInfoResponse:
+		return req == messageTypeFileInfoRequest
+	case messageTypeRangeResponse:
+		return req == messageTypeRangeRequest
+	}
+	return false
 }
 
-func (c *rawConnection) send(ctx context.Context, msg message, done chan struct{}) bool {
+// send queues the message for the writer, returning false if the context
+// is done or the connection closed first. Each stream has its own queue;
+// see streamOf.
+func (c *rawConnection) send(ctx context.Context, msg Message, done chan struct{}) bool {
+	return c.sendMessage(ctx, asyncMessage{msg: msg, done: done})
+}
+
+func (c *rawConnection) sendMessage(ctx context.Context, hm asyncMessage) bool {
+	c.leaveIdle(ctx, hm.msg)
 	select {
-	case c.outbox <- asyncMessage{msg, done}:
+	case c.outboxes[streamOf(hm.msg)] <- hm:
 		return true
 	case <-c.closed:
 	case <-ctx.Done():
 	}
-	if done != nil {
-		close(done)
+	if hm.done != nil {
+		close(hm.done)
 	}
 	return false
 }
 
+// An outgoingMessage is a message being written on a stream other than the
+// control stream.
+type outgoingMessage struct {
+	asyncMessage
+	stream int32
+	frames *frameWriter // nil if written whole
+	size   int          // bytes written so far
+}
+
 func (c *rawConnection) writerLoop() {
 	select {
 	case cc := <-c.clusterConfigBox:
-		err := c.writeMessage(cc)
+		err := c.writeMessage(asyncMessage{msg: cc})
 		if err != nil {
-			c.internalClose(err)
+			c.internalClose(CloseReasonWriteError, err)
 			return
 		}
 	case hm := <-c.closeBox:
-		_ = c.writeMessage(hm.msg)
-		close(hm.done)
+		c.writeCloseMessage(hm)
 		return
 	case <-c.closed:
 		return
 	}
-	for {
-		select {
-		case hm := <-c.outbox:
-			err := c.writeMessage(hm.msg)
+
+	// The message being written on each stream but the control stream,
+	// and the stream whose turn it is to write a frame.
+	var current [numStreams]*outgoingMessage
+	turn := streamControl + 1
+	defer func() {
+		for _, om := range current {
+			if om != nil {
+				om.abandon()
+			}
+		}
+	}()
+
+	// handle writes a control message right away, and starts writing any
+	// other message on its stream, which must be idle.
+	handle := func(hm asyncMessage) error {
+		stream := streamOf(hm.msg)
+		if stream == streamControl {
+			err := c.writeMessage(hm)
 			if hm.done != nil {
 				close(hm.done)
 			}
-			if err != nil {
-				c.internalClose(err)
+			return err
+		}
+		om, err := c.newOutgoingMessage(hm, stream)
+		if err != nil {
+			return err
+		}
+		current[stream] = om
+		return nil
+	}
+	// outbox returns the queue of the stream if it is idle, and nil (which
+	// blocks forever) otherwise.
+	outbox := func(stream int32) chan asyncMessage {
+		if current[stream] != nil {
+			return nil
+		}
+		return c.outboxes[stream]
+	}
+
+	for {
+		var err error
+		select {
+		case hm := <-c.outboxes[streamControl]:
+			err = handle(hm)
+		default:
+			select {
+			case hm := <-c.outboxes[streamControl]:
+				err = handle(hm)
+			case hm := <-outbox(streamIndex):
+				err = handle(hm)
+			case hm := <-outbox(streamData):
+				err = handle(hm)
+			case hm := <-c.closeBox:
+				c.writeCloseMessage(hm)
 				return
+			case <-c.closed:
+				return
+			default:
+				stream := nextStream(current[:], turn)
+				if stream >= 0 {
+					// One frame of the message on the stream whose turn it
+					// is, then the next stream gets a turn.
+					var finished bool
+					finished, err = c.writeOutgoingMessage(current[stream])
+					if finished {
+						if done := current[stream].done; done != nil {
+							close(done)
+						}
+						current[stream] = nil
+					}
+					turn = stream%(numStreams-1) + 1
+					break
+				}
+
+				// Nothing more to send right now, so whatever is buffered
+				// should go out before we wait for more.
+				if err := c.flushWriteBuffer(); err != nil {
+					c.internalClose(CloseReasonWriteError, err)
+					return
+				}
+				select {
+				case hm := <-c.outboxes[streamControl]:
+					err = handle(hm)
+				case hm := <-outbox(streamIndex):
+					err = handle(hm)
+				case hm := <-outbox(streamData):
+					err = handle(hm)
+				case hm := <-c.closeBox:
+					c.writeCloseMessage(hm)
+					return
+				case <-c.closed:
+					return
+				}
 			}
-
-		case hm := <-c.closeBox:
-			_ = c.writeMessage(hm.msg)
-			close(hm.done)
-			return
-
-		case <-c.closed:
+		}
+		if err != nil {
+			c.internalClose(CloseReasonWriteError, err)
 			return
 		}
 	}
 }
 
-func (c *rawConnection) writeMessage(msg message) error {
-	if c.shouldCompressMessage(msg) {
-		return c.writeCompressedMessage(msg)
+// nextStream returns the first stream from turn on, wrapping around past
+// the control stream, that has a message being written, or -1 if none has.
+func nextStream(current []*outgoingMessage, turn int32) int32 {
+	for i := int32(0); i < numStreams-1; i++ {
+		stream := (turn-1+i)%(numStreams-1) + 1
+		if current[stream] != nil {
+			return stream
+		}
 	}
-	return c.writeUncompressedMessage(msg)
+	return -1
 }
 
-func (c *rawConnection) writeCompressedMessage(msg message) error {
-	size := msg.ProtoSize()
-	buf := BufferPool.Get(size)
-	if _, err := msg.MarshalTo(buf); err != nil {
-		return errors.Wrap(err, "marshalling message")
+// newOutgoingMessage prepares the message for writing on the stream, in
+// frames if the other side supports streams.
+func (c *rawConnection) newOutgoingMessage(hm asyncMessage, stream int32) (*outgoingMessage, error) {
+	om := &outgoingMessage{asyncMessage: hm, stream: stream}
+	if c.peerSupports(CapabilityStreams) {
+		c.enc.SetCompression(c.compressionFor(hm))
+		frames, err := c.enc.frames(hm.msg, stream)
+		if err != nil {
+			if hm.done != nil {
+				close(hm.done)
+			}
+			return nil, err
+		}
+		om.frames = frames
 	}
+	return om, nil
+}
 
-	compressed, err := c.lz4Compress(buf)
+// writeOutgoingMessage writes the next frame of the message, or all of it
+// if it isn't split into frames, returning true when it is done.
+func (c *rawConnection) writeOutgoingMessage(om *outgoingMessage) (bool, error) {
+	if om.frames == nil {
+		return true, c.writeMessage(om.asyncMessage)
+	}
+	start := c.cw.Tot()
+	done, err := c.enc.writeFrame(om.frames)
+	c.notePendingWriteBytes()
+	om.size += int(c.cw.Tot() - start)
 	if err != nil {
-		return errors.Wrap(err, "compressing message")
+		om.frames.release()
+		return true, err
+	}
+	if done {
+		c.messageWritten(om.msg, om.size, om.frames.off)
 	}
+	return done, c.flushLowLatency()
+}
 
-	hdr := Header{
-		Type:        c.typeOf(msg),
-		Compression: MessageCompressionLZ4,
+// abandon gives up on writing the rest of the message.
+func (om *outgoingMessage) abandon() {
+	if om.frames != nil {
+		om.frames.release()
 	}
-	hdrSize := hdr.ProtoSize()
-	if hdrSize > 1<<16-1 {
-		panic("impossibly large header")
+	if om.done != nil {
+		close(om.done)
 	}
+}
 
-	totSize := 2 + hdrSize + 4 + len(compressed)
-	buf = BufferPool.Upgrade(buf, totSize)
-
-	// Header length
-	binary.BigEndian.PutUint16(buf, uint16(hdrSize))
-	// Header
-	if _, err := hdr.MarshalTo(buf[2:]); err != nil {
-		return errors.Wrap(err, "marshalling header")
+func (c *rawConnection) writeCloseMessage(hm asyncMessage) {
+	_ = c.writeMessage(hm)
+	_ = c.flushWriteBuffer()
+	if c.encrypted != nil {
+		// Ends the stream, so that the other side can tell a close from
+		// a cut connection.
+		_ = c.encrypted.Close()
 	}
-	// Message length
-	binary.BigEndian.PutUint32(buf[2+hdrSize:], uint32(len(compressed)))
-	// Message
-	copy(buf[2+hdrSize+4:], compressed)
-	BufferPool.Put(compressed)
-
-	n, err := c.cw.Write(buf)
-	BufferPool.Put(buf)
+	close(hm.done)
+}
 
-	l.Debugf("wrote %d bytes on the wire (2 bytes length, %d bytes header, 4 bytes message length, %d bytes message (%d uncompressed)), err=%v", n, hdrSize, len(compressed), size, err)
+// flushWriteBuffer flushes our own write buffer, if writes are buffered.
+func (c *rawConnection) flushWriteBuffer() error {
+	if c.writeBuf == nil {
+		return nil
+	}
+	err := c.writeBuf.Flush()
+	c.notePendingWriteBytes()
 	if err != nil {
-		return errors.Wrap(err, "writing message")
+		return errors.Wrap(err, "flushing write buffer")
 	}
 	return nil
 }
 
-func (c *rawConnection) writeUncompressedMessage(msg message) error {
-	size := msg.ProtoSize()
-
-	hdr := Header{
-		Type: c.typeOf(msg),
-	}
-	hdrSize := hdr.ProtoSize()
-	if hdrSize > 1<<16-1 {
-		panic("impossibly large header")
+// notePendingWriteBytes makes what is in the write buffer known to
+// PendingWriteBytes. The buffer itself is only touched by the writer loop.
+func (c *rawConnection) notePendingWriteBytes() {
+	if c.writeBuf != nil {
+		atomic.StoreInt64(&c.pendingWriteBytes, int64(c.writeBuf.Buffered()))
 	}
+}
 
-	totSize := 2 + hdrSize + 4 + size
-	buf := BufferPool.Get(totSize)
+// PendingWriteBytes returns the number of bytes in the write buffer, i.e.
+// written but not yet flushed to the underlying writer. It is always zero
+// unless writes are buffered, see WithWriteBufferSize. Bytes that stay
+// pending while nothing more is being sent point at a stuck writer.
+func (c *rawConnection) PendingWriteBytes() int {
+	return int(atomic.LoadInt64(&c.pendingWriteBytes))
+}
 
-	// Header length
-	binary.BigEndian.PutUint16(buf, uint16(hdrSize))
-	// Header
-	if _, err := hdr.MarshalTo(buf[2:]); err != nil {
-		return errors.Wrap(err, "marshalling header")
+func (c *rawConnection) writeMessage(hm asyncMessage) error {
+	start := c.cw.Tot()
+	c.enc.SetCompression(c.compressionFor(hm))
+	err := c.enc.Encode(hm.msg)
+	c.notePendingWriteBytes()
+	if err != nil {
+		return err
 	}
-	// Message length
-	binary.BigEndian.PutUint32(buf[2+hdrSize:], uint32(size))
-	// Message
-	if _, err := msg.MarshalTo(buf[2+hdrSize+4:]); err != nil {
-		return errors.Wrap(err, "marshalling message")
+	c.messageWritten(hm.msg, int(c.cw.Tot()-start), c.enc.payloadSize)
+	if _, ok := hm.msg.(*Pong); ok {
+		// The other side goes by pongs to tell whether we are alive, so
+		// they go out right away rather than with whatever follows.
+		return c.flush()
 	}
+	return c.flushLowLatency()
+}
 
-	n, err := c.cw.Write(buf[:totSize])
-	BufferPool.Put(buf)
-
-	l.Debugf("wrote %d bytes on the wire (2 bytes length, %d bytes header, 4 bytes message length, %d bytes message), err=%v", n, hdrSize, size, err)
-	if err != nil {
-		return errors.Wrap(err, "writing message")
+// flush makes sure that what has been written goes out now, through both
+// the write buffer and the underlying writer.
+func (c *rawConnection) flush() error {
+	if err := c.flushWriteBuffer(); err != nil {
+		return err
+	}
+	if c.underlying != nil {
+		if err := c.underlying.Flush(); err != nil {
+			return errors.Wrap(err, "flushing message")
+		}
 	}
 	return nil
 }
 
-func (c *rawConnection) typeOf(msg message) MessageType {
-	switch msg.(type) {
-	case *ClusterConfig:
-		return messageTypeClusterConfig
-	case *Index:
-		return messageTypeIndex
-	case *IndexUpdate:
-		return messageTypeIndexUpdate
-	case *Request:
-		return messageTypeRequest
-	case *Response:
-		return messageTypeResponse
-	case *DownloadProgress:
-		return messageTypeDownloadProgress
-	case *Ping:
-		return messageTypePing
-	case *Close:
-		return messageTypeClose
-	default:
-		panic("bug: unknown message type")
+// compressionFor returns the compression to write the message with.
+func (c *rawConnection) compressionFor(hm asyncMessage) Compression {
+	if hm.incompressible {
+		return CompressNever
 	}
+	return Compression(atomic.LoadInt32(&c.compression))
 }
 
-func (c *rawConnection) newMessage(t MessageType) (message, error) {
-	switch t {
-	case messageTypeClusterConfig:
-		return new(ClusterConfig), nil
-	case messageTypeIndex:
-		return new(Index), nil
-	case messageTypeIndexUpdate:
-		return new(IndexUpdate), nil
-	case messageTypeRequest:
-		return new(Request), nil
-	case messageTypeResponse:
-		return new(Response), nil
-	case messageTypeDownloadProgress:
-		return new(DownloadProgress), nil
-	case messageTypePing:
-		return new(Ping), nil
-	case messageTypeClose:
-		return new(Close), nil
-	default:
-		return nil, errUnknownMessage
+// messageWritten updates taps and statistics for a message that has been
+// written in full, taking size bytes on the wire, of which payload bytes
+// were the possibly compressed message itself.
+func (c *rawConnection) messageWritten(msg Message, size, payload int) {
+	if c.tap != nil {
+		c.tap(DirectionOut, typeOf(msg), messageID(msg), size)
+	}
+	if c.folderStats != nil {
+		c.folderStats.outMessage(msg)
+	}
+	if c.compressionStats != nil {
+		c.compressionStats.outMessage(typeOf(msg), msg.ProtoSize(), payload)
 	}
 }
 
-func (c *rawConnection) shouldCompressMessage(msg message) bool {
-	switch c.compression {
-	case CompressNever:
-		return false
-
-	case CompressAlways:
-		// Use compression for large enough messages
-		return msg.ProtoSize() >= compressionThreshold
-
-	case CompressMetadata:
-		_, isResponse := msg.(*Response)
-		// Compress if it's large enough and not a response message
-		return !isResponse && msg.ProtoSize() >= compressionThreshold
-
-	default:
-		panic("unknown compression setting")
+// flushLowLatency makes sure that what has been written goes out now, when
+// the connection is low latency.
+func (c *rawConnection) flushLowLatency() error {
+	if !c.lowLatency {
+		return nil
+	}
+	// The underlying writer may be buffered; make sure the message goes
+	// out now.
+	if f, ok := c.cw.Writer.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			return errors.Wrap(err, "flushing message")
+		}
 	}
+	return nil
 }
 
 // Close is called when the connection is regularely closed and thus the Close
@@ -864,7 +1840,7 @@ func (c *rawConnection) Close(err error) {
 		done := make(chan struct{})
 		timeout := time.NewTimer(CloseTimeout)
 		select {
-		case c.closeBox <- asyncMessage{&Close{err.Error()}, done}:
+		case c.closeBox <- asyncMessage{msg: &Close{err.Error()}, done: done}:
 			select {
 			case <-done:
 			case <-timeout.C:
@@ -879,20 +1855,25 @@ func (c *rawConnection) Close(err error) {
 	// dispatcherLoop, resulting in a deadlock.
 	// The sending above must happen before spawning the routine, to prevent
 	// the underlying connection from terminating before sending the close msg.
-	go c.internalClose(err)
+	go c.internalClose(CloseReasonLocalClose, err)
 }
 
 // internalClose is called if there is an unexpected error during normal operation.
-func (c *rawConnection) internalClose(err error) {
+func (c *rawConnection) internalClose(reason CloseReason, err error) {
 	c.closeOnce.Do(func() {
-		l.Debugln("close due to", err)
+		l.Debugln("close due to", reason, err)
+		atomic.StoreInt32(&c.closeReason, int32(reason))
 		close(c.closed)
 
+		abandoned := 0
 		c.awaitingMut.Lock()
-		for i, ch := range c.awaiting {
-			if ch != nil {
-				close(ch)
+		for i, ar := range c.awaiting {
+			if ar.ch != nil {
+				close(ar.ch)
 				delete(c.awaiting, i)
+				if ar.msgType != messageTypePing {
+					abandoned++
+				}
 			}
 		}
 		c.awaitingMut.Unlock()
@@ -900,6 +1881,12 @@ func (c *rawConnection) internalClose(err error) {
 		<-c.dispatcherLoopStopped
 
 		c.receiver.Closed(c, err)
+		if c.closeReasonModel != nil {
+			c.closeReasonModel.ClosedWithReason(c, reason, err)
+		}
+		if c.abandonedModel != nil {
+			c.abandonedModel.OnConnectionClosed(c, abandoned, err)
+		}
 	})
 }
 
@@ -915,14 +1902,27 @@ func (c *rawConnection) pingSender() {
 	for {
 		select {
 		case <-ticker.C:
+			if c.pingsSuspended() {
+				l.Debugln(c.id, "ping skipped while suspended")
+				continue
+			}
+
 			d := time.Since(c.cw.Last())
-			if d < PingSendInterval/2 {
+			if d < c.pingInterval()/2 {
 				l.Debugln(c.id, "ping skipped after wr", d)
 				continue
 			}
 
 			l.Debugln(c.id, "ping -> after", d)
-			c.ping()
+			if c.peerSupports(CapabilityPong) {
+				go func() {
+					ctx, cancel := context.WithTimeout(context.Background(), ReceiveTimeout)
+					defer cancel()
+					_, _ = c.measureLatency(ctx)
+				}()
+			} else {
+				c.ping()
+			}
 
 		case <-c.closed:
 			return
@@ -932,7 +1932,8 @@ func (c *rawConnection) pingSender() {
 
 // The pingReceiver checks that we've received a message (any message will do,
 // but we expect pings in the absence of other messages) within the last
-// ReceiveTimeout. If not, we close the connection with an ErrTimeout.
+// ReceiveTimeout. If not, and retrying pings doesn't get us a message
+// either, we close the connection with an ErrTimeout.
 func (c *rawConnection) pingReceiver() {
 	ticker := time.NewTicker(ReceiveTimeout / 2)
 	defer ticker.Stop()
@@ -940,10 +1941,14 @@ func (c *rawConnection) pingReceiver() {
 	for {
 		select {
 		case <-ticker.C:
-			d := time.Since(c.cr.Last())
-			if d > ReceiveTimeout {
+			d := c.sinceLastRead()
+			if d > c.receiveTimeout() {
 				l.Debugln(c.id, "ping timeout", d)
-				c.internalClose(ErrTimeout)
+				if !c.retryPings() {
+					c.internalClose(CloseReasonPingTimeout, ErrTimeout)
+					return
+				}
+				continue
 			}
 
 			l.Debugln(c.id, "last read within", d)
@@ -954,46 +1959,208 @@ func (c *rawConnection) pingReceiver() {
 	}
 }
 
+// retryPings sends pings with exponentially increasing intervals, for up to
+// the configured number of attempts in total, returning true as soon as
+// something is received or the connection is closed.
+func (c *rawConnection) retryPings() bool {
+	last := c.cr.Last()
+	backoff := c.pingBackoff
+	for attempt := 1; attempt < c.pingAttempts; attempt++ {
+		l.Debugln(c.id, "ping retry", attempt, "waiting", backoff)
+		go c.ping()
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-c.closed:
+			t.Stop()
+			return true
+		}
+		if c.cr.Last().After(last) {
+			return true
+		}
+		backoff *= 2
+	}
+	return false
+}
+
+// SuspendPings stops sending pings, and stops expecting to receive
+// anything from the other side, for the given duration or until
+// ResumePings is called. This lets an otherwise idle device keep its radio
+// off, e.g. while sleeping. Note that the other side still expects our
+// pings and may close the connection if the suspension exceeds its receive
+// timeout.
+func (c *rawConnection) SuspendPings(d time.Duration) {
+	atomic.StoreInt64(&c.pingsSuspendedUntil, time.Now().Add(d).UnixNano())
+}
+
+// ResumePings ends a suspension started by SuspendPings. The receive
+// timeout starts counting from now.
+func (c *rawConnection) ResumePings() {
+	for {
+		until := atomic.LoadInt64(&c.pingsSuspendedUntil)
+		now := time.Now().UnixNano()
+		if until <= now || atomic.CompareAndSwapInt64(&c.pingsSuspendedUntil, until, now) {
+			return
+		}
+	}
+}
+
+func (c *rawConnection) pingsSuspended() bool {
+	return time.Now().UnixNano() < atomic.LoadInt64(&c.pingsSuspendedUntil)
+}
+
+// sinceLastRead returns the time since we last read anything, or since the
+// end of the last ping suspension if that is more recent. It is negative
+// while pings are suspended.
+func (c *rawConnection) sinceLastRead() time.Duration {
+	last := c.cr.Last()
+	if until := time.Unix(0, atomic.LoadInt64(&c.pingsSuspendedUntil)); until.After(last) {
+		last = until
+	}
+	return time.Since(last)
+}
+
+// Statistics describes the traffic on a connection. It can be marshalled
+// to and from JSON, with durations in nanoseconds, and be used as a
+// baseline for a new connection with WithStatisticsBaseline.
 type Statistics struct {
 	At            time.Time
 	InBytesTotal  int64
 	OutBytesTotal int64
+	Folders       map[string]FolderStatistics // nil unless folder statistics are enabled
+
+	// Compression tells how well the messages we sent compressed, by type
+	// of message. It is nil unless compression statistics are enabled.
+	Compression map[MessageType]CompressionStatistics
+
+	// Latency is the latest measured round trip time to the other side,
+	// PeerReportedLatency the latest one measured by the other side. They
+	// are zero until measured, or when the other side doesn't support
+	// measuring latency.
+	Latency             time.Duration
+	PeerReportedLatency time.Duration
+
+	// OneWayLatency is the latest estimate of the time a message takes
+	// from the other side to us, from the send time carried in its pings
+	// adjusted for the clock skew. It is zero until both are known. As the
+	// skew is measured assuming equal delays both ways, the estimate is
+	// off by half the difference in delay between the directions, plus
+	// the imprecision of both clocks, so it is only a rough indication of
+	// asymmetry on links where that difference is small compared to the
+	// round trip time. It includes time the ping spent queued behind other
+	// messages on both sides.
+	OneWayLatency time.Duration
+
+	// DiscardedMessages is the number of messages that were read from the
+	// other side but not passed on because the connection closed first.
+	// Once the connection is closed no further messages are passed to the
+	// model, while a call to the model already in progress, such as for an
+	// Index, runs to completion before Model.Closed is called.
+	DiscardedMessages int64
+
+	// The smoothed byte rates are updated every second, independently of
+	// calls to Statistics. They are zero unless throughput smoothing is
+	// enabled.
+	SmoothedInBytesPerSec  float64
+	SmoothedOutBytesPerSec float64
 }
 
 func (c *rawConnection) Statistics() Statistics {
-	return Statistics{
+	stats := Statistics{
 		At:            time.Now(),
 		InBytesTotal:  c.cr.Tot(),
 		OutBytesTotal: c.cw.Tot(),
+
+		Latency:             time.Duration(atomic.LoadInt64(&c.latency)),
+		PeerReportedLatency: time.Duration(atomic.LoadInt64(&c.peerLatency)),
+		OneWayLatency:       time.Duration(atomic.LoadInt64(&c.oneWayLatency)),
+		DiscardedMessages:   atomic.LoadInt64(&c.discardedMessages),
+	}
+	if c.folderStats != nil {
+		stats.Folders = c.folderStats.snapshot()
+	}
+	if c.compressionStats != nil {
+		stats.Compression = c.compressionStats.snapshot()
 	}
+	if c.throughput != nil {
+		stats.SmoothedInBytesPerSec, stats.SmoothedOutBytesPerSec = c.throughput.rates()
+	}
+	return stats
 }
 
-func (c *rawConnection) lz4Compress(src []byte) ([]byte, error) {
-	var err error
-	buf := BufferPool.Get(lz4.CompressBound(len(src)))
-	compressed, err := lz4.Encode(buf, src)
-	if err != nil {
-		return nil, err
+// Health is a snapshot of the state of a connection, as returned by
+// HealthCheck.
+type Health struct {
+	// At is when the snapshot was taken.
+	At time.Time
+	// Alive is true when the connection is open and something was received
+	// from the other side within ReceiveTimeout, or pings are suspended.
+	Alive bool
+	// LastReceive is when something was last received from the other side.
+	LastReceive time.Time
+	// Latency is the latest measured round trip time, or zero if unknown.
+	Latency time.Duration
+	// OutstandingRequests is the number of requests, pings and other
+	// messages we are waiting for the other side to respond to.
+	OutstandingRequests int
+	Closed              bool
+	// BytesPerSec is the smoothed incoming plus outgoing byte rate, or
+	// zero unless throughput smoothing is enabled.
+	BytesPerSec float64
+	// ErrorRate is the smoothed fraction of our requests that failed,
+	// between zero and one.
+	ErrorRate float64
+}
+
+// HealthCheck returns the current health of the connection. Unlike
+// Statistics it computes nothing, so it is cheap enough to call often.
+func (c *rawConnection) HealthCheck() Health {
+	c.awaitingMut.Lock()
+	defer c.awaitingMut.Unlock()
+	closed := c.Closed()
+	var bytesPerSec float64
+	if c.throughput != nil {
+		in, out := c.throughput.rates()
+		bytesPerSec = in + out
 	}
-	if &compressed[0] != &buf[0] {
-		panic("bug: lz4.Compress allocated, which it must not (should use buffer pool)")
+	return Health{
+		At:                  time.Now(),
+		Alive:               !closed && c.sinceLastRead() < ReceiveTimeout,
+		LastReceive:         c.cr.Last(),
+		Latency:             time.Duration(atomic.LoadInt64(&c.latency)),
+		OutstandingRequests: len(c.awaiting),
+		Closed:              closed,
+		BytesPerSec:         bytesPerSec,
+		ErrorRate:           c.errorRate.get(),
 	}
-
-	binary.BigEndian.PutUint32(compressed, binary.LittleEndian.Uint32(compressed))
-	return compressed, nil
 }
 
-func (c *rawConnection) lz4Decompress(src []byte) ([]byte, error) {
-	size := binary.BigEndian.Uint32(src)
-	binary.LittleEndian.PutUint32(src, size)
-	var err error
-	buf := BufferPool.Get(int(size))
-	decoded, err := lz4.Decode(buf, src)
-	if err != nil {
-		return nil, err
+// applyBaseline makes the byte counters start at the totals of the given
+// statistics, which must be done before the connection is started.
+func (c *rawConnection) applyBaseline(stats Statistics) {
+	c.cr.tot = stats.InBytesTotal
+	c.cw.tot = stats.OutBytesTotal
+	c.discardedMessages = stats.DiscardedMessages
+	if c.folderStats != nil {
+		for folder, fs := range stats.Folders {
+			fs := fs
+			c.folderStats.folders[folder] = &fs
+		}
 	}
-	if &decoded[0] != &buf[0] {
-		panic("bug: lz4.Decode allocated, which it must not (should use buffer pool)")
+	if c.compressionStats != nil {
+		for msgType, cs := range stats.Compression {
+			cs := cs
+			c.compressionStats.types[msgType] = &cs
+		}
+	}
+}
+
+// RequestLatency returns a summary of the request latencies seen on this
+// connection. It is empty unless latency tracking is enabled.
+func (c *rawConnection) RequestLatency() LatencySummary {
+	if c.latencies == nil {
+		return LatencySummary{}
 	}
-	return decoded, nil
+	return c.latencies.summary()
 }
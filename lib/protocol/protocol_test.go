@@ -3,16 +3,21 @@
 package protocol
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/quick"
 	"time"
@@ -31,9 +36,9 @@ func TestPing(t *testing.T) {
 	ar, aw := io.Pipe()
 	br, bw := io.Pipe()
 
-	c0 := NewConnection(c0ID, ar, bw, newTestModel(), "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c0 := NewConnection(c0ID, ar, bw, newTestModel(), "name", CompressAlways).(wireFormatConnection).rawConnection
 	c0.Start()
-	c1 := NewConnection(c1ID, br, aw, newTestModel(), "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c1 := NewConnection(c1ID, br, aw, newTestModel(), "name", CompressAlways).(wireFormatConnection).rawConnection
 	c1.Start()
 	c0.ClusterConfig(ClusterConfig{})
 	c1.ClusterConfig(ClusterConfig{})
@@ -46,6 +51,39 @@ func TestPing(t *testing.T) {
 	}
 }
 
+func TestManualPing(t *testing.T) {
+	c0, _ := newTestConnections(t, newTestModel(), newTestModel(), CompressAlways, nil, nil)
+
+	if !c0.noPinger {
+		t.Fatal("Pinger should be disabled")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c0.WaitHandshake(ctx); err != nil {
+		t.Fatal(err)
+	}
+	d, err := c0.Ping(ctx)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if d <= 0 {
+		t.Errorf("Unexpected round trip time %v", d)
+	}
+
+	// Without the other side answering, the ping is just sent.
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, newTestModel(), "name", CompressAlways).(wireFormatConnection)
+	c.Start()
+	c.ClusterConfig(ClusterConfig{})
+	if d, err := c.Ping(ctx); err != nil || d != 0 {
+		t.Errorf("Unexpected result %v, %v", d, err)
+	}
+	c.Close(errManual)
+	if _, err := c.Ping(ctx); err != ErrClosed {
+		t.Errorf("Unexpected error %v, expected %v", err, ErrClosed)
+	}
+}
+
 var errManual = errors.New("manual close")
 
 func TestClose(t *testing.T) {
@@ -55,14 +93,14 @@ func TestClose(t *testing.T) {
 	ar, aw := io.Pipe()
 	br, bw := io.Pipe()
 
-	c0 := NewConnection(c0ID, ar, bw, m0, "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c0 := NewConnection(c0ID, ar, bw, m0, "name", CompressAlways).(wireFormatConnection).rawConnection
 	c0.Start()
 	c1 := NewConnection(c1ID, br, aw, m1, "name", CompressAlways)
 	c1.Start()
 	c0.ClusterConfig(ClusterConfig{})
 	c1.ClusterConfig(ClusterConfig{})
 
-	c0.internalClose(errManual)
+	c0.internalClose(CloseReasonLocalClose, errManual)
 
 	<-c0.closed
 	if err := m0.closedError(); err != errManual {
@@ -97,7 +135,7 @@ func TestCloseOnBlockingSend(t *testing.T) {
 
 	m := newTestModel()
 
-	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, m, "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, m, "name", CompressAlways).(wireFormatConnection).rawConnection
 	c.Start()
 
 	wg := sync.WaitGroup{}
@@ -117,7 +155,7 @@ func TestCloseOnBlockingSend(t *testing.T) {
 	// This simulates an error from ping timeout
 	wg.Add(1)
 	go func() {
-		c.internalClose(ErrTimeout)
+		c.internalClose(CloseReasonPingTimeout, ErrTimeout)
 		wg.Done()
 	}()
 
@@ -147,7 +185,7 @@ func TestCloseRace(t *testing.T) {
 	ar, aw := io.Pipe()
 	br, bw := io.Pipe()
 
-	c0 := NewConnection(c0ID, ar, bw, m0, "c0", CompressNever).(wireFormatConnection).Connection.(*rawConnection)
+	c0 := NewConnection(c0ID, ar, bw, m0, "c0", CompressNever).(wireFormatConnection).rawConnection
 	c0.Start()
 	c1 := NewConnection(c1ID, br, aw, m1, "c1", CompressNever)
 	c1.Start()
@@ -161,7 +199,7 @@ func TestCloseRace(t *testing.T) {
 		t.Fatal("timed out before receiving index")
 	}
 
-	go c0.internalClose(errManual)
+	go c0.internalClose(CloseReasonLocalClose, errManual)
 	select {
 	case <-c0.closed:
 	case <-time.After(time.Second):
@@ -184,11 +222,11 @@ func TestCloseRace(t *testing.T) {
 func TestClusterConfigFirst(t *testing.T) {
 	m := newTestModel()
 
-	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, m, "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, m, "name", CompressAlways).(wireFormatConnection).rawConnection
 	c.Start()
 
 	select {
-	case c.outbox <- asyncMessage{&Ping{}, nil}:
+	case c.outboxes[streamControl] <- asyncMessage{msg: &Ping{}}:
 		t.Fatal("able to send ping before cluster config")
 	case <-time.After(100 * time.Millisecond):
 		// Allow some time for c.writerLoop to setup after c.Start
@@ -208,7 +246,7 @@ func TestClusterConfigFirst(t *testing.T) {
 
 	done = make(chan struct{})
 	go func() {
-		c.internalClose(errManual)
+		c.internalClose(CloseReasonLocalClose, errManual)
 		close(done)
 	}()
 
@@ -234,7 +272,7 @@ func TestCloseTimeout(t *testing.T) {
 
 	m := newTestModel()
 
-	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, m, "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, m, "name", CompressAlways).(wireFormatConnection).rawConnection
 	c.Start()
 
 	done := make(chan struct{})
@@ -250,6 +288,75 @@ func TestCloseTimeout(t *testing.T) {
 	}
 }
 
+func TestRequestTooLarge(t *testing.T) {
+	m0 := newTestModel()
+	m0.data = make([]byte, 1024)
+	m1 := newTestModel()
+
+	_, c1 := newTestConnections(t, m0, m1, CompressNever, []Option{WithMaxRequestSize(1024)}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := c1.Request(ctx, "default", "foo", 0, 2048, nil, 0, false); err != ErrRequestTooLarge {
+		t.Fatalf("Unexpected error %v, expected %v", err, ErrRequestTooLarge)
+	}
+	if m0.name != "" {
+		t.Error("Model should not have been asked about the oversized request")
+	}
+
+	if _, err := c1.Request(ctx, "default", "foo", 0, 1024, nil, 0, false); err != nil {
+		t.Fatal("Unexpected error for request at max size:", err)
+	}
+	if m0.name != "foo" {
+		t.Error("Model should have been asked about the request")
+	}
+}
+
+func TestChecksums(t *testing.T) {
+	for _, comp := range []Compression{CompressNever, CompressAlways} {
+		m0 := newTestModel()
+		m0.data = make([]byte, 1024)
+		m1 := newTestModel()
+
+		_, c1 := newTestConnections(t, m0, m1, comp, []Option{WithChecksums()}, []Option{WithChecksums()})
+
+		data, err := c1.Request(context.Background(), "default", "foo", 0, 1024, nil, 0, false)
+		if err != nil {
+			t.Fatalf("Unexpected error with compression %v: %v", comp, err)
+		}
+		if !bytes.Equal(data, m0.data) {
+			t.Errorf("Incorrect response data with compression %v", comp)
+		}
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	m := newTestModel()
+
+	rd, wr := io.Pipe()
+	c := NewConnection(c0ID, rd, &testutils.NoopRW{}, m, "name", CompressNever)
+	c.Start()
+
+	// An empty ping message, claiming a checksum that doesn't match.
+	hdr := Header{
+		Type:     messageTypePing,
+		Checksum: []byte{1, 2, 3, 4},
+	}
+	hdrBs, err := hdr.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 2+len(hdrBs)+4)
+	binary.BigEndian.PutUint16(buf, uint16(len(hdrBs)))
+	copy(buf[2:], hdrBs)
+	go wr.Write(buf)
+
+	if err := m.closedError(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Unexpected close error %v, expected %v", err, ErrChecksumMismatch)
+	}
+}
+
 func TestMarshalIndexMessage(t *testing.T) {
 	if testing.Short() {
 		quickCfg.MaxCount = 10
@@ -384,7 +491,7 @@ func TestUnmarshalFDPUv16v17(t *testing.T) {
 	}
 }
 
-func testMarshal(t *testing.T, prefix string, m1, m2 message) bool {
+func testMarshal(t *testing.T, prefix string, m1, m2 Message) bool {
 	buf, err := m1.Marshal()
 	if err != nil {
 		t.Fatal(err)
@@ -407,8 +514,6 @@ func testMarshal(t *testing.T, prefix string, m1, m2 message) bool {
 }
 
 func TestLZ4Compression(t *testing.T) {
-	c := new(rawConnection)
-
 	for i := 0; i < 10; i++ {
 		dataLen := 150 + rand.Intn(150)
 		data := make([]byte, dataLen)
@@ -416,13 +521,13 @@ func TestLZ4Compression(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		comp, err := c.lz4Compress(data)
+		comp, err := lz4Compress(data)
 		if err != nil {
 			t.Errorf("compressing %d bytes: %v", dataLen, err)
 			continue
 		}
 
-		res, err := c.lz4Decompress(comp)
+		res, err := lz4Decompress(comp)
 		if err != nil {
 			t.Errorf("decompressing %d bytes to %d: %v", len(comp), dataLen, err)
 			continue
@@ -438,7 +543,6 @@ func TestLZ4Compression(t *testing.T) {
 }
 
 func TestStressLZ4CompressGrows(t *testing.T) {
-	c := new(rawConnection)
 	success := 0
 	for i := 0; i < 100; i++ {
 		// Create a slize that is precisely one min block size, fill it with
@@ -449,7 +553,7 @@ func TestStressLZ4CompressGrows(t *testing.T) {
 			t.Fatal("randomness failure")
 		}
 
-		comp, err := c.lz4Compress(data)
+		comp, err := lz4Compress(data)
 		if err != nil {
 			t.Fatal("unexpected compression error: ", err)
 		}
@@ -538,9 +642,27 @@ func TestCheckConsistency(t *testing.T) {
 			fi: FileInfo{
 				Name: "foo",
 				Type: FileInfoTypeFile,
+				Size: 1234,
 			},
 			ok: false,
 		},
+		{
+			// empty file without blocks
+			fi: FileInfo{
+				Name: "foo",
+				Type: FileInfoTypeFile,
+			},
+			ok: true,
+		},
+		{
+			// empty file with an empty block
+			fi: FileInfo{
+				Name:   "foo",
+				Type:   FileInfoTypeFile,
+				Blocks: []BlockInfo{{Size: 0, Hash: sha256.New().Sum(nil)}},
+			},
+			ok: true,
+		},
 		{
 			// directory with blocks
 			fi: FileInfo{
@@ -563,6 +685,124 @@ func TestCheckConsistency(t *testing.T) {
 	}
 }
 
+func TestEmptyFiles(t *testing.T) {
+	emptyHash := sha256.New().Sum(nil)
+	files := []FileInfo{
+		{Name: "nil blocks", Type: FileInfoTypeFile},
+		{Name: "no blocks", Type: FileInfoTypeFile, Blocks: []BlockInfo{}},
+		{Name: "empty block", Type: FileInfoTypeFile, RawBlockSize: MinBlockSize, Blocks: []BlockInfo{{Size: 0, Hash: emptyHash}}},
+	}
+
+	t.Run("marshal", func(t *testing.T) {
+		for _, f := range files {
+			bs, err := f.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var f2 FileInfo
+			if err := f2.Unmarshal(bs); err != nil {
+				t.Fatal(err)
+			}
+			if len(f2.Blocks) != len(f.Blocks) {
+				t.Errorf("%s: %d blocks after round trip, expected %d", f.Name, len(f2.Blocks), len(f.Blocks))
+			}
+			if len(f.Blocks) == 1 && (f2.Blocks[0].Size != 0 || !bytes.Equal(f2.Blocks[0].Hash, emptyHash)) {
+				t.Errorf("%s: got block %v after round trip", f.Name, f2.Blocks[0])
+			}
+		}
+	})
+
+	t.Run("index", func(t *testing.T) {
+		received := make(chan []FileInfo, 1)
+		m0 := newTestModel()
+		m0.indexFn = func(_ DeviceID, _ string, files []FileInfo) {
+			received <- files
+		}
+		_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+		if err := c1.Index(context.Background(), "default", files); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case got := <-received:
+			for i, f := range got {
+				if f.Name != files[i].Name || len(f.Blocks) != len(files[i].Blocks) || f.Size != 0 {
+					t.Errorf("Received %v, expected %v", f, files[i])
+				}
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Empty files not received, closed with %v", m0.closedError())
+		}
+	})
+}
+
+func TestCheckIndexOrder(t *testing.T) {
+	files := func(names ...string) []FileInfo {
+		var fs []FileInfo
+		for _, name := range names {
+			fs = append(fs, FileInfo{Name: name})
+		}
+		return fs
+	}
+	cases := []struct {
+		files []FileInfo
+		ok    bool
+	}{
+		{nil, true},
+		{files("a"), true},
+		{files("a", "a/b", "b"), true},
+		{files("b", "a"), false},
+		{files("a", "b", "b"), false},
+	}
+	for _, tc := range cases {
+		err := checkIndexOrder(tc.files)
+		if tc.ok && err != nil {
+			t.Errorf("Unexpected error %v for %v", err, tc.files)
+		}
+		if !tc.ok && !errors.Is(err, ErrIndexOrder) {
+			t.Errorf("Unexpected error %v for %v, expected ErrIndexOrder", err, tc.files)
+		}
+	}
+}
+
+func TestIndexOrderCheck(t *testing.T) {
+	received := make(chan []FileInfo, 1)
+	m0 := newTestModel()
+	m0.indexFn = func(_ DeviceID, _ string, files []FileInfo) {
+		received <- files
+	}
+	c0, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, []Option{WithIndexOrderCheck()}, nil)
+
+	ctx := context.Background()
+	unsorted := []FileInfo{
+		{Name: "b", Type: FileInfoTypeDirectory},
+		{Name: "a", Type: FileInfoTypeDirectory},
+	}
+	if err := c0.Index(ctx, "default", unsorted); !errors.Is(err, ErrIndexOrder) {
+		t.Errorf("Index of unsorted files returned %v, expected ErrIndexOrder", err)
+	}
+
+	// Sorted files are accepted from the other side ...
+	sorted := []FileInfo{unsorted[1], unsorted[0]}
+	if err := c1.Index(ctx, "default", sorted); err != nil {
+		t.Fatal(err)
+	}
+	if files := <-received; len(files) != 2 {
+		t.Errorf("Received %d files, expected 2", len(files))
+	}
+
+	// ... while unsorted ones close the connection.
+	if err := c1.IndexUpdate(ctx, "default", unsorted); err != nil {
+		t.Fatal(err)
+	}
+	if err := m0.closedError(); !errors.Is(err, ErrIndexOrder) {
+		t.Errorf("Closed with %v, expected ErrIndexOrder", err)
+	}
+	if reason := c0.CloseReason(); reason != CloseReasonProtocolError {
+		t.Errorf("Closed with reason %v, expected %v", reason, CloseReasonProtocolError)
+	}
+}
+
 func TestBlockSize(t *testing.T) {
 	cases := []struct {
 		fileSize  int64
@@ -831,10 +1071,10 @@ func TestSha256OfEmptyBlock(t *testing.T) {
 func TestClusterConfigAfterClose(t *testing.T) {
 	m := newTestModel()
 
-	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, m, "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, m, "name", CompressAlways).(wireFormatConnection).rawConnection
 	c.Start()
 
-	c.internalClose(errManual)
+	c.internalClose(CloseReasonLocalClose, errManual)
 
 	done := make(chan struct{})
 	go func() {
@@ -849,11 +1089,39 @@ func TestClusterConfigAfterClose(t *testing.T) {
 	}
 }
 
+func TestIndexNotSent(t *testing.T) {
+	m := newTestModel()
+
+	// Without our cluster config nothing else is written.
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, m, "name", CompressAlways).(wireFormatConnection).rawConnection
+	c.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.Index(ctx, "default", nil); err != context.DeadlineExceeded {
+		t.Errorf("Index returned %v, expected %v", err, context.DeadlineExceeded)
+	}
+
+	indexed := make(chan error, 1)
+	go func() {
+		indexed <- c.IndexUpdate(context.Background(), "default", nil)
+	}()
+	c.internalClose(CloseReasonLocalClose, errManual)
+	select {
+	case err := <-indexed:
+		if err != ErrClosed {
+			t.Errorf("IndexUpdate returned %v, expected %v", err, ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out before IndexUpdate returned")
+	}
+}
+
 func TestDispatcherToCloseDeadlock(t *testing.T) {
 	// Verify that we don't deadlock when calling Close() from within one of
 	// the model callbacks (ClusterConfig).
 	m := newTestModel()
-	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, m, "name", CompressAlways).(wireFormatConnection).Connection.(*rawConnection)
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, m, "name", CompressAlways).(wireFormatConnection).rawConnection
 	m.ccFn = func(devID DeviceID, cc ClusterConfig) {
 		c.Close(errManual)
 	}
@@ -916,6 +1184,20 @@ func TestBlocksEqual(t *testing.T) {
 	}
 }
 
+func TestMetadata(t *testing.T) {
+	md := map[string]string{"class": "bulk", "folders": "default"}
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, newTestModel(), "name", CompressNever, WithMetadata(md)).(wireFormatConnection)
+	md["class"] = "changed"
+	if got := c.Metadata(); len(got) != 2 || got["class"] != "bulk" || got["folders"] != "default" {
+		t.Errorf("Unexpected metadata %v", got)
+	}
+
+	c = NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, newTestModel(), "name", CompressNever).(wireFormatConnection)
+	if got := c.Metadata(); got != nil {
+		t.Errorf("Unexpected metadata %v without the option", got)
+	}
+}
+
 func TestIndexIDString(t *testing.T) {
 	// Index ID is a 64 bit, zero padded hex integer.
 	var i IndexID = 42
@@ -923,3 +1205,1582 @@ func TestIndexIDString(t *testing.T) {
 		t.Error(i.String())
 	}
 }
+
+func TestReaderFaults(t *testing.T) {
+	// The first message sent by c1 is a checksummed cluster config; the
+	// header is 6 bytes long, making the message body start at offset 12.
+	cases := []struct {
+		name   string
+		fault  testutils.Fault
+		offset int64
+		err    error
+	}{
+		{"eof", testutils.FaultEOF, 0, io.EOF},
+		{"truncated header length", testutils.FaultTruncate, 1, io.ErrUnexpectedEOF},
+		{"truncated header", testutils.FaultTruncate, 4, io.ErrUnexpectedEOF},
+		{"truncated message", testutils.FaultTruncate, 13, io.ErrUnexpectedEOF},
+		{"corrupt message", testutils.FaultCorrupt, 12, ErrChecksumMismatch},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m0 := newTestModel()
+
+			ar, aw := io.Pipe()
+			br, bw := io.Pipe()
+			defer ar.Close()
+			defer br.Close()
+
+			rd := testutils.NewFaultyReader(br, tc.fault, tc.offset, 0)
+			c0 := NewConnection(c0ID, rd, aw, m0, "c0", CompressNever, WithChecksums())
+			c0.Start()
+			c1 := NewConnection(c1ID, ar, bw, newTestModel(), "c1", CompressNever, WithChecksums())
+			c1.Start()
+			c1.ClusterConfig(ClusterConfig{Folders: []Folder{{ID: "default"}}})
+
+			if err := m0.closedError(); !errors.Is(err, tc.err) {
+				t.Errorf("Unexpected close error %v, expected %v", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestReaderDelay(t *testing.T) {
+	received := make(chan ClusterConfig, 1)
+	m0 := newTestModel()
+	m0.ccFn = func(_ DeviceID, cc ClusterConfig) {
+		received <- cc
+	}
+
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+
+	rd := testutils.NewFaultyReader(br, testutils.FaultDelay, 8, 100*time.Millisecond)
+	c0 := NewConnection(c0ID, rd, aw, m0, "c0", CompressNever)
+	c0.Start()
+	c1 := NewConnection(c1ID, ar, bw, newTestModel(), "c1", CompressNever)
+	c1.Start()
+
+	t0 := time.Now()
+	c1.ClusterConfig(ClusterConfig{Folders: []Folder{{ID: "default"}}})
+
+	select {
+	case cc := <-received:
+		if d := time.Since(t0); d < 100*time.Millisecond {
+			t.Errorf("Cluster config received after %v, expected a delay", d)
+		}
+		if len(cc.Folders) != 1 || cc.Folders[0].ID != "default" {
+			t.Errorf("Unexpected cluster config %v", cc)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for cluster config")
+	}
+}
+
+func TestWriterFaults(t *testing.T) {
+	// The first message written is the cluster config; with checksums the
+	// header is 6 bytes long, making the message body start at offset 12.
+	cases := []struct {
+		name   string
+		fault  testutils.Fault
+		offset int64
+	}{
+		{"nothing written", testutils.FaultTruncate, 0},
+		{"partial header", testutils.FaultTruncate, 4},
+		{"partial message", testutils.FaultEOF, 13},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newTestModel()
+			var wire bytes.Buffer
+			wr := testutils.NewFaultyWriter(&wire, tc.fault, tc.offset, 0)
+			c := NewConnection(c0ID, &testutils.BlockingRW{}, wr, m, "c", CompressNever, WithChecksums(), WithoutPinger())
+			c.Start()
+			c.ClusterConfig(ClusterConfig{Folders: []Folder{{ID: "default"}}})
+
+			if err := m.closedError(); !errors.Is(err, testutils.ErrFaultInjected) {
+				t.Errorf("Unexpected close error %v, expected %v", err, testutils.ErrFaultInjected)
+			}
+			// What comes before the fault is written, in the same call.
+			if wire.Len() != int(tc.offset) {
+				t.Errorf("%d bytes written, expected %d", wire.Len(), tc.offset)
+			}
+		})
+	}
+
+	t.Run("partial write", func(t *testing.T) {
+		var wire bytes.Buffer
+		wr := testutils.NewFaultyWriter(&wire, testutils.FaultTruncate, 5, 0)
+		if n, err := wr.Write([]byte("abc")); n != 3 || err != nil {
+			t.Fatalf("First write returned %d, %v", n, err)
+		}
+		if n, err := wr.Write([]byte("defg")); n != 2 || err != testutils.ErrFaultInjected {
+			t.Errorf("Second write returned %d, %v, expected 2, %v", n, err, testutils.ErrFaultInjected)
+		}
+		if n, err := wr.Write([]byte("h")); n != 0 || err != testutils.ErrFaultInjected {
+			t.Errorf("Write after the fault returned %d, %v", n, err)
+		}
+		if wire.String() != "abcde" {
+			t.Errorf("Wrote %q, expected %q", wire.String(), "abcde")
+		}
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		var wire bytes.Buffer
+		wr := testutils.NewFaultyWriter(&wire, testutils.FaultCorrupt, 1, 0)
+		if n, err := wr.Write([]byte{1, 2, 3}); n != 3 || err != nil {
+			t.Fatalf("Write returned %d, %v", n, err)
+		}
+		if exp := []byte{1, 2 ^ 0xff, 3}; !bytes.Equal(wire.Bytes(), exp) {
+			t.Errorf("Wrote %v, expected %v", wire.Bytes(), exp)
+		}
+	})
+}
+
+func TestLowLatencyFlush(t *testing.T) {
+	for _, lowLatency := range []bool{false, true} {
+		received := make(chan struct{}, 1)
+		m1 := newTestModel()
+		m1.ccFn = func(DeviceID, ClusterConfig) {
+			received <- struct{}{}
+		}
+
+		ar, aw := io.Pipe()
+		br, bw := io.Pipe()
+
+		var opts []Option
+		if lowLatency {
+			opts = append(opts, WithLowLatency())
+		}
+		c0 := NewConnection(c0ID, ar, bufio.NewWriter(bw), newTestModel(), "c0", CompressNever, opts...)
+		c0.Start()
+		c1 := NewConnection(c1ID, br, aw, m1, "c1", CompressNever)
+		c1.Start()
+		c0.ClusterConfig(ClusterConfig{})
+
+		select {
+		case <-received:
+			if !lowLatency {
+				t.Error("Message should have stayed in the write buffer")
+			}
+		case <-time.After(100 * time.Millisecond):
+			if lowLatency {
+				t.Error("Message should have been flushed")
+			}
+		}
+		ar.Close()
+		br.Close()
+	}
+}
+
+func TestBufferedReadWrite(t *testing.T) {
+	m1 := newTestModel()
+	m1.data = make([]byte, 128<<10)
+	buffered := []Option{WithReadBufferSize(64 << 10), WithWriteBufferSize(64 << 10)}
+	c0, _ := newTestConnections(t, newTestModel(), m1, CompressNever, buffered, buffered)
+
+	// Buffered messages must be flushed once there is nothing more to
+	// send, or the request would never complete.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c0.Request(ctx, "default", "foo", 0, 128<<10, nil, 0, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// ... and so must the close message.
+	c0.Close(errors.New("because"))
+	select {
+	case <-m1.closedCh:
+		if err := m1.closedErr; err == nil || !strings.Contains(err.Error(), "because") {
+			t.Errorf("Unexpected close error %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for close")
+	}
+}
+
+func TestPendingWriteBytes(t *testing.T) {
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	defer ar.Close()
+	defer br.Close()
+
+	c := NewConnection(c0ID, ar, bw, newTestModel(), "name", CompressNever, WithoutPinger(), WithWriteBufferSize(1<<20)).(wireFormatConnection)
+	c.Start()
+	c.ClusterConfig(ClusterConfig{})
+	if err := NewEncoder(aw, CompressNever).Encode(&ClusterConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDecoder(br).Decode(); err != nil { // its cluster config
+		t.Fatal(err)
+	}
+
+	waitPending := func(what string, ok func(int) bool) {
+		t.Helper()
+		for i := 0; !ok(c.PendingWriteBytes()); i++ {
+			if i == 1000 {
+				t.Fatalf("%d bytes pending, expected %s", c.PendingWriteBytes(), what)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitPending("none once flushed", func(n int) bool { return n == 0 })
+
+	// Nothing is read from now on, so the flush of the index is stuck and
+	// it stays pending.
+	files := make([]FileInfo, 100)
+	for i := range files {
+		files[i] = FileInfo{Name: fmt.Sprintf("file%d", i)}
+	}
+	go c.Index(context.Background(), "default", files)
+	waitPending("the index", func(n int) bool { return n > 0 })
+
+	go io.Copy(ioutil.Discard, br)
+	waitPending("none once read", func(n int) bool { return n == 0 })
+}
+
+type testObserverModel struct {
+	*TestModel
+	mut    sync.Mutex
+	events []string
+}
+
+func (m *testObserverModel) RequestStarted(deviceID DeviceID, folder, name string) {
+	m.mut.Lock()
+	m.events = append(m.events, "started "+name)
+	m.mut.Unlock()
+}
+
+func (m *testObserverModel) RequestFinished(deviceID DeviceID, folder, name string) {
+	m.mut.Lock()
+	m.events = append(m.events, "finished "+name)
+	m.mut.Unlock()
+}
+
+func TestRequestObserver(t *testing.T) {
+	m0 := &testObserverModel{TestModel: newTestModel()}
+	m0.requestFn = func(folder, name string, size int32, offset int64) (RequestResponse, error) {
+		m0.mut.Lock()
+		m0.events = append(m0.events, "serving "+name)
+		m0.mut.Unlock()
+		if name == "missing" {
+			return nil, ErrNoSuchFile
+		}
+		return &fakeRequestResponse{make([]byte, size)}, nil
+	}
+
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+	if _, err := c1.Request(context.Background(), "default", "foo", 0, 128, nil, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	m0.waitForEvents(t, "started foo, serving foo, finished foo")
+	if _, err := c1.Request(context.Background(), "default", "missing", 0, 128, nil, 0, false); err != ErrNoSuchFile {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	m0.waitForEvents(t, "started foo, serving foo, finished foo, started missing, serving missing, finished missing")
+}
+
+// waitForEvents waits for the given events, as the finished event may race
+// with the response being received.
+func (m *testObserverModel) waitForEvents(t *testing.T, exp string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		m.mut.Lock()
+		events := strings.Join(m.events, ", ")
+		m.mut.Unlock()
+		if events == exp {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Got events %q, expected %q", events, exp)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRequestAuthorizer(t *testing.T) {
+	var calls int32
+	m0 := newTestModel()
+	m0.requestFn = func(folder, name string, size int32, offset int64) (RequestResponse, error) {
+		atomic.AddInt32(&calls, 1)
+		return &fakeRequestResponse{make([]byte, size)}, nil
+	}
+	auth := func(deviceID DeviceID, folder, name string, offset int64, size int) error {
+		if deviceID != c1ID {
+			t.Errorf("Unexpected device ID %v", deviceID)
+		}
+		if name == "secret" {
+			return errors.New("denied")
+		}
+		return nil
+	}
+
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, []Option{WithRequestAuthorizer(auth)}, nil)
+
+	if _, err := c1.Request(context.Background(), "default", "secret", 0, 128, nil, 0, false); err != ErrUnauthorized {
+		t.Errorf("Unexpected error %v, expected %v", err, ErrUnauthorized)
+	}
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Errorf("Denied request reached the model")
+	}
+
+	if _, err := c1.Request(context.Background(), "default", "public", 0, 128, nil, 0, false); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Model got %d requests, expected 1", n)
+	}
+}
+
+func TestSuspendPings(t *testing.T) {
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.BlockingRW{}, newTestModel(), "name", CompressAlways).(wireFormatConnection).rawConnection
+
+	if c.pingsSuspended() {
+		t.Fatal("Pings should not be suspended initially")
+	}
+
+	c.SuspendPings(time.Hour)
+	if !c.pingsSuspended() {
+		t.Fatal("Pings should be suspended")
+	}
+	if d := c.sinceLastRead(); d >= 0 {
+		t.Errorf("Time since last read %v should be negative while suspended", d)
+	}
+
+	c.ResumePings()
+	if c.pingsSuspended() {
+		t.Fatal("Pings should have been resumed")
+	}
+	if d := c.sinceLastRead(); d < 0 || d > time.Second {
+		t.Errorf("Time since last read %v should count from resumption", d)
+	}
+
+	c.SuspendPings(10 * time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if c.pingsSuspended() {
+		t.Fatal("Suspension should have expired")
+	}
+}
+
+func TestWaitHandshake(t *testing.T) {
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+
+	c0 := NewConnection(c0ID, ar, bw, newTestModel(), "c0", CompressNever).(wireFormatConnection)
+	c0.Start()
+	c1 := NewConnection(c1ID, br, aw, newTestModel(), "c1", CompressNever).(wireFormatConnection)
+	c1.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c0.WaitHandshake(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Unexpected error %v before cluster config, expected %v", err, context.DeadlineExceeded)
+	}
+
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c0.WaitHandshake(ctx); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if err := c1.WaitHandshake(ctx); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+}
+
+func TestWaitHandshakeClosed(t *testing.T) {
+	m := newTestModel()
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, m, "name", CompressAlways).(wireFormatConnection)
+	c.Start()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- c.WaitHandshake(context.Background())
+	}()
+
+	c.Close(errManual)
+	select {
+	case err := <-errs:
+		if err != ErrClosed {
+			t.Errorf("Unexpected error %v, expected %v", err, ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for WaitHandshake to return")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	defer ar.Close()
+	defer br.Close()
+
+	c0 := NewConnection(c0ID, ar, bw, newTestModel(), "c0", CompressNever, WithoutPinger()).(wireFormatConnection)
+	c0.Start()
+	c1 := NewConnection(c1ID, br, aw, newTestModel(), "c1", CompressNever, WithoutPinger()).(wireFormatConnection)
+	c1.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c0.Verify(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Unexpected error %v before cluster config, expected %v", err, context.DeadlineExceeded)
+	}
+
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c0.Verify(ctx); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if c0.Statistics().Latency == 0 {
+		t.Error("Expected the round trip to be measured")
+	}
+
+	c0.Close(errManual)
+	<-c0.closed
+	if err := c0.Verify(ctx); err != ErrClosed {
+		t.Errorf("Unexpected error %v after close, expected %v", err, ErrClosed)
+	}
+}
+
+func TestSetCompression(t *testing.T) {
+	ar, aw := io.Pipe()
+	defer ar.Close()
+
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, aw, newTestModel(), "c", CompressNever, WithoutPinger()).(wireFormatConnection)
+	c.Start()
+	c.ClusterConfig(ClusterConfig{})
+
+	files := make([]FileInfo, 100)
+	for i := range files {
+		files[i] = FileInfo{Name: fmt.Sprintf("dir/file%d", i), Type: FileInfoTypeDirectory}
+	}
+	dec := NewDecoder(ar)
+	expect := func(exp MessageCompression) {
+		t.Helper()
+		hdr, err := dec.DecodeHeader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Compression != exp {
+			t.Errorf("%v: compression %v, expected %v", hdr.Type, hdr.Compression, exp)
+		}
+		msg, err := dec.DecodeMessage(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg, ok := msg.(*IndexUpdate); ok && len(msg.Files) != len(files) {
+			t.Errorf("%v: %d files, expected %d", hdr.Type, len(msg.Files), len(files))
+		}
+	}
+	expect(MessageCompressionNone) // cluster config
+
+	// Switch only once the previous message has been written, as a switch
+	// applies to whatever is written next.
+	ctx := context.Background()
+	for _, comp := range []Compression{CompressNever, CompressAlways, CompressNever} {
+		c.SetCompression(comp)
+		go c.IndexUpdate(ctx, "default", files)
+		if comp == CompressAlways {
+			expect(MessageCompressionLZ4)
+		} else {
+			expect(MessageCompressionNone)
+		}
+	}
+}
+
+func TestRequestRetries(t *testing.T) {
+	cases := []struct {
+		name     string
+		opts     []Option
+		failures int
+		err      error
+		expErr   error
+		expCalls int32
+	}{
+		{"succeeds on third attempt", []Option{WithRequestRetries(3, time.Millisecond)}, 2, &TemporaryError{ErrNoSuchFile}, nil, 3},
+		{"too few attempts", []Option{WithRequestRetries(2, time.Millisecond)}, 2, &TemporaryError{ErrNoSuchFile}, ErrNoSuchFile, 2},
+		{"not temporary", []Option{WithRequestRetries(3, time.Millisecond)}, 2, ErrNoSuchFile, ErrNoSuchFile, 1},
+		{"no retries", nil, 2, &TemporaryError{ErrNoSuchFile}, ErrNoSuchFile, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int32
+			m0 := newTestModel()
+			m0.requestFn = func(string, string, int32, int64) (RequestResponse, error) {
+				if int(atomic.AddInt32(&calls, 1)) <= tc.failures {
+					return nil, tc.err
+				}
+				return &fakeRequestResponse{[]byte("data")}, nil
+			}
+			_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, tc.opts, nil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			buf, err := c1.Request(ctx, "default", "foo", 0, 4, nil, 0, false)
+			if err != tc.expErr {
+				t.Errorf("Unexpected error %v, expected %v", err, tc.expErr)
+			}
+			if err == nil && string(buf) != "data" {
+				t.Errorf("Unexpected data %q", buf)
+			}
+			if n := atomic.LoadInt32(&calls); n != tc.expCalls {
+				t.Errorf("Model called %d times, expected %d", n, tc.expCalls)
+			}
+		})
+	}
+}
+
+func TestBufferUnavailable(t *testing.T) {
+	// A model that couldn't get a buffer of the requested size returns
+	// less data. That must be neither sent as is nor leaked, but fail as
+	// a temporary error.
+	for _, retries := range []bool{false, true} {
+		t.Run(fmt.Sprintf("retries=%v", retries), func(t *testing.T) {
+			var calls, closes int32
+			m0 := newTestModel()
+			m0.requestFn = func(string, string, int32, int64) (RequestResponse, error) {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					return &countingResponse{fakeRequestResponse{[]byte("da")}, &closes}, nil
+				}
+				return &fakeRequestResponse{[]byte("data")}, nil
+			}
+			var opts []Option
+			if retries {
+				opts = append(opts, WithRequestRetries(2, time.Millisecond))
+			}
+			_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, opts, nil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			buf, err := c1.Request(ctx, "default", "foo", 0, 4, nil, 0, false)
+			if retries {
+				if err != nil || string(buf) != "data" {
+					t.Errorf("Request returned %q, %v; expected the data from the retry", buf, err)
+				}
+			} else if err != ErrGeneric {
+				t.Errorf("Unexpected error %v, expected %v", err, ErrGeneric)
+			}
+			if n := atomic.LoadInt32(&closes); n != 1 {
+				t.Errorf("Short response closed %d times, expected once", n)
+			}
+		})
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	var served int32
+	release := make(chan struct{})
+	m0 := newTestModel()
+	m0.requestFn = func(_, name string, _ int32, _ int64) (RequestResponse, error) {
+		atomic.AddInt32(&served, 1)
+		if name == "slow" {
+			<-release
+		}
+		return &fakeRequestResponse{[]byte("data")}, nil
+	}
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, []Option{WithMaxConcurrentRequests(1)}, nil)
+
+	slowDone := make(chan error, 1)
+	go func() {
+		_, err := c1.Request(context.Background(), "default", "slow", 0, 4, nil, 0, false)
+		slowDone <- err
+	}()
+	for atomic.LoadInt32(&served) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Queued behind the slow request until after its timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c1.Request(ctx, "default", "expiring", 0, 4, nil, 0, false); err != context.DeadlineExceeded {
+		t.Fatalf("Unexpected error %v, expected %v", err, context.DeadlineExceeded)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	if err := <-slowDone; err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests are served in order, so the expired one has been dealt with
+	// once this one is answered.
+	if _, err := c1.Request(context.Background(), "default", "after", 0, 4, nil, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&served); n != 2 {
+		t.Errorf("Expected the expired request not to be served, got %d served", n)
+	}
+}
+
+func TestSetMaxConcurrentRequests(t *testing.T) {
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	defer ar.Close()
+	defer br.Close()
+
+	var running, lowered, violations int32
+	release := make(chan struct{})
+	m0 := newTestModel()
+	m0.requestFn = func(string, string, int32, int64) (RequestResponse, error) {
+		if n := atomic.AddInt32(&running, 1); atomic.LoadInt32(&lowered) == 1 && n > 1 {
+			atomic.AddInt32(&violations, 1)
+		}
+		<-release
+		atomic.AddInt32(&running, -1)
+		return &fakeRequestResponse{[]byte("data")}, nil
+	}
+	c0 := NewConnection(c1ID, ar, bw, m0, "c0", CompressNever, WithMaxConcurrentRequests(4), WithoutPinger()).(wireFormatConnection).rawConnection
+	c0.Start()
+	c1 := NewConnection(c0ID, br, aw, newTestModel(), "c1", CompressNever, WithoutPinger())
+	c1.Start()
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+
+	request := func(n int) chan error {
+		errs := make(chan error, n)
+		for i := 0; i < n; i++ {
+			go func() {
+				_, err := c1.Request(context.Background(), "default", "foo", 0, 4, nil, 0, false)
+				errs <- err
+			}()
+		}
+		return errs
+	}
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s", what)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// Lowering the limit under load lets the running requests finish, and
+	// then enforces the new limit.
+	errs := request(8)
+	waitFor("four running requests", func() bool {
+		return atomic.LoadInt32(&running) == 4 && c0.requests.queued() == 4
+	})
+	c0.SetMaxConcurrentRequests(1)
+	atomic.StoreInt32(&lowered, 1)
+	for i := 0; i < 8; i++ {
+		release <- struct{}{}
+	}
+	for i := 0; i < 8; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&violations); n != 0 {
+		t.Errorf("%d requests started above the lowered limit", n)
+	}
+
+	// Raising it starts queued requests right away.
+	atomic.StoreInt32(&lowered, 0)
+	errs = request(3)
+	waitFor("one running request", func() bool {
+		return atomic.LoadInt32(&running) == 1 && c0.requests.queued() == 2
+	})
+	c0.SetMaxConcurrentRequests(0)
+	waitFor("three running requests", func() bool {
+		return atomic.LoadInt32(&running) == 3
+	})
+	close(release)
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResponseTooLong(t *testing.T) {
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	defer br.Close()
+
+	m := newTestModel()
+	c := NewConnection(c0ID, ar, bw, m, "name", CompressNever, WithoutPinger())
+	c.Start()
+	c.ClusterConfig(ClusterConfig{})
+	enc := NewEncoder(aw, CompressNever)
+	if err := enc.Encode(&ClusterConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	dec := NewDecoder(br)
+	if _, err := dec.Decode(); err != nil { // our cluster config
+		t.Fatal(err)
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := c.Request(context.Background(), "default", "foo", 0, 4, nil, 0, false)
+		errs <- err
+	}()
+	msg, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, ok := msg.(*Request)
+	if !ok {
+		t.Fatalf("Expected a request, got %T", msg)
+	}
+	go io.Copy(ioutil.Discard, br)
+	if err := enc.Encode(&Response{ID: req.ID, Data: []byte("too long")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.closedError(); !errors.Is(err, ErrResponseTooLong) {
+		t.Errorf("Connection closed with %v, expected ErrResponseTooLong", err)
+	}
+	if err := <-errs; err != ErrClosed {
+		t.Errorf("Request returned %v, expected ErrClosed", err)
+	}
+
+	if _, err := c.Request(context.Background(), "default", "foo", 0, MaxBlockSize+1, nil, 0, false); err != ErrRequestTooLarge {
+		t.Errorf("Request larger than a block returned %v, expected ErrRequestTooLarge", err)
+	}
+}
+
+type countingResponse struct {
+	fakeRequestResponse
+	closes *int32
+}
+
+func (r *countingResponse) Close() {
+	atomic.AddInt32(r.closes, 1)
+}
+
+func TestCloseWhileServingRequests(t *testing.T) {
+	// Closes the connection while requests are being served and many more
+	// are queued. The requests being served must give back their
+	// responses, and the queued ones mustn't reach the model. Best run
+	// with -race.
+	const maxConcurrent = 4
+	for i := 0; i < 20; i++ {
+		var served, closes, afterClose int32
+		var closed int32
+		m0 := newTestModel()
+		m0.requestFn = func(string, string, int32, int64) (RequestResponse, error) {
+			if atomic.LoadInt32(&closed) == 1 {
+				atomic.AddInt32(&afterClose, 1)
+			}
+			atomic.AddInt32(&served, 1)
+			time.Sleep(time.Millisecond)
+			return &countingResponse{fakeRequestResponse{[]byte("data")}, &closes}, nil
+		}
+		c0, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, []Option{WithMaxConcurrentRequests(maxConcurrent)}, nil)
+
+		const requests = 100
+		errs := make(chan error, requests)
+		for j := 0; j < requests; j++ {
+			go func() {
+				_, err := c1.Request(context.Background(), "default", "foo", 0, 4, nil, 0, false)
+				errs <- err
+			}()
+		}
+		for atomic.LoadInt32(&served) < maxConcurrent {
+			time.Sleep(time.Millisecond)
+		}
+		c0.Close(errors.New("closing"))
+		atomic.StoreInt32(&closed, 1)
+		c1.Close(errors.New("closing"))
+		for j := 0; j < requests; j++ {
+			<-errs
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for atomic.LoadInt32(&closes) != atomic.LoadInt32(&served) && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if c, s := atomic.LoadInt32(&closes), atomic.LoadInt32(&served); c != s {
+			t.Fatalf("%d of %d responses closed", c, s)
+		}
+		// Only requests that got past the check just as the connection
+		// closed may still reach the model.
+		if n := atomic.LoadInt32(&afterClose); n > maxConcurrent {
+			t.Fatalf("%d requests passed to the model after close", n)
+		}
+	}
+}
+
+func TestCloseDuringIndex(t *testing.T) {
+	// An index being passed to the model when the connection closes is
+	// delivered in full before the model is told about the close, while
+	// one already read behind it is discarded.
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	defer ar.Close()
+	defer br.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var mut sync.Mutex
+	var delivered []string
+	m := newTestModel()
+	m.indexFn = func(_ DeviceID, folder string, _ []FileInfo) {
+		if folder == "first" {
+			close(started)
+			<-release
+		}
+		mut.Lock()
+		delivered = append(delivered, folder)
+		mut.Unlock()
+	}
+	c := NewConnection(c0ID, ar, bw, m, "name", CompressNever, WithoutPinger())
+	c.Start()
+	c.ClusterConfig(ClusterConfig{})
+	go io.Copy(ioutil.Discard, br)
+
+	enc := NewEncoder(aw, CompressNever)
+	if err := enc.Encode(&ClusterConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(&Index{Folder: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	// Returns once the connection has read it.
+	if err := enc.Encode(&Index{Folder: "second"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Close(errManual)
+	select {
+	case <-m.closedCh:
+		t.Fatal("Model told about the close before the index was delivered")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if err := m.closedError(); err != errManual {
+		t.Fatalf("Unexpected close error %v", err)
+	}
+
+	mut.Lock()
+	defer mut.Unlock()
+	if len(delivered) != 1 || delivered[0] != "first" {
+		t.Errorf("Delivered %v, expected only the first index", delivered)
+	}
+	if n := c.Statistics().DiscardedMessages; n != 1 {
+		t.Errorf("%d messages discarded, expected 1", n)
+	}
+}
+
+func TestRequestExpired(t *testing.T) {
+	ar, aw := io.Pipe()
+	defer ar.Close()
+
+	m := newTestModel()
+	c := NewConnection(c1ID, &testutils.BlockingRW{}, aw, m, "c", CompressNever, WithoutPinger()).(wireFormatConnection).rawConnection
+	c.Start()
+	c.ClusterConfig(ClusterConfig{})
+
+	go c.handleRequest(Request{ID: 1, Folder: "default", Name: "foo", Size: 4}, time.Now().Add(-time.Second))
+
+	dec := NewDecoder(ar)
+	for {
+		msg, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp, ok := msg.(*Response); ok {
+			if err := responseError(*resp); err != ErrExpired {
+				t.Errorf("Unexpected error %v, expected %v", err, ErrExpired)
+			}
+			break
+		}
+	}
+	if m.name != "" {
+		t.Error("Expired request passed to the model")
+	}
+}
+
+func TestBulkMessagesDontDelayPings(t *testing.T) {
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	defer ar.Close()
+	defer br.Close()
+
+	var updates int32
+	m1 := newTestModel()
+	m1.indexUpdateFn = func(DeviceID, string, []FileInfo) {
+		atomic.AddInt32(&updates, 1)
+	}
+	const delay = 100 * time.Millisecond
+	c0 := NewConnection(c1ID, ar, &slowWriter{bw, delay}, newTestModel(), "c0", CompressNever, WithoutPinger()).(wireFormatConnection)
+	c0.Start()
+	c1 := NewConnection(c0ID, br, aw, m1, "c1", CompressNever, WithoutPinger()).(wireFormatConnection)
+	c1.Start()
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, c := range []wireFormatConnection{c0, c1} {
+		if err := c.WaitHandshake(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// One index update being written and another one waiting when the
+	// ping is sent. The ping goes out before the waiting update.
+	files := []FileInfo{{Name: "dir", Type: FileInfoTypeDirectory}}
+	go c0.IndexUpdate(ctx, "default", files)
+	time.Sleep(delay / 4)
+	go c0.IndexUpdate(ctx, "default", files)
+	time.Sleep(delay / 4)
+
+	if _, err := c0.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&updates); n != 1 {
+		t.Errorf("Ping arrived after %d index updates, expected 1", n)
+	}
+}
+
+func TestMaxPendingResponseBytes(t *testing.T) {
+	const (
+		reqSize = 128
+		limit   = 3 * reqSize
+	)
+
+	var cur, max int32
+	m0 := newTestModel()
+	m0.requestFn = func(folder, name string, size int32, offset int64) (RequestResponse, error) {
+		n := atomic.AddInt32(&cur, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&cur, -1)
+		return &fakeRequestResponse{make([]byte, size)}, nil
+	}
+
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, nil, []Option{WithMaxPendingResponseBytes(limit)})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := c1.Request(context.Background(), "default", "foo", 0, reqSize, nil, 0, false)
+			if err != nil {
+				t.Error(err)
+			} else if len(data) != reqSize {
+				t.Errorf("Got %d bytes, expected %d", len(data), reqSize)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if m := atomic.LoadInt32(&max); m > limit/reqSize {
+		t.Errorf("Saw %d concurrent requests, expected at most %d", m, limit/reqSize)
+	}
+
+	// A request larger than the limit is allowed on its own, while one
+	// that can't get started in time fails with the context error.
+	if _, err := c1.Request(context.Background(), "default", "foo", 0, 2*limit, nil, 0, false); err != nil {
+		t.Error("Unexpected error:", err)
+	}
+	unblock := make(chan struct{})
+	m0.requestFn = func(folder, name string, size int32, offset int64) (RequestResponse, error) {
+		<-unblock
+		return &fakeRequestResponse{make([]byte, size)}, nil
+	}
+	go c1.Request(context.Background(), "default", "foo", 0, limit, nil, 0, false)
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c1.Request(ctx, "default", "foo", 0, reqSize, nil, 0, false); err != context.DeadlineExceeded {
+		t.Errorf("Unexpected error %v, expected %v", err, context.DeadlineExceeded)
+	}
+	close(unblock)
+}
+
+func TestModTimeRoundTrip(t *testing.T) {
+	loc := time.FixedZone("UTC+5", 5*60*60)
+	times := []time.Time{
+		time.Unix(0, 0),
+		time.Unix(1234567890, 123456789),
+		time.Unix(-1234567890, 999999999),
+		time.Date(2020, 2, 29, 23, 59, 59, 1, loc),
+	}
+
+	for _, mt := range times {
+		var f FileInfo
+		f.Name = "foo"
+		f.SetModTime(mt)
+
+		bs, err := f.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var f2 FileInfo
+		if err := f2.Unmarshal(bs); err != nil {
+			t.Fatal(err)
+		}
+
+		if !f2.ModTime().Equal(mt) {
+			t.Errorf("Modification time %v became %v", mt, f2.ModTime())
+		}
+		if f2.ModifiedS != mt.Unix() || f2.ModifiedNs != int32(mt.Nanosecond()) {
+			t.Errorf("Modification time %v stored as %d s + %d ns", mt, f2.ModifiedS, f2.ModifiedNs)
+		}
+	}
+}
+
+func TestLatencyMeasurement(t *testing.T) {
+	// The connections don't ping, so only our own pings measure latency.
+	c0, c1 := newTestConnections(t, newTestModel(), newTestModel(), CompressAlways, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c0.WaitHandshake(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !c0.peerSupports(CapabilityPong) {
+		t.Fatal("Other side should support pongs")
+	}
+
+	d1, err := c1.measureLatency(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d1 <= 0 {
+		t.Errorf("Unexpected latency %v", d1)
+	}
+	if lat := c1.Statistics().Latency; lat != d1 {
+		t.Errorf("Statistics has latency %v, expected %v", lat, d1)
+	}
+
+	// The pong in return to our ping carries the latency measured by the
+	// other side.
+	d0, err := c0.measureLatency(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := c0.Statistics()
+	if stats.Latency != d0 {
+		t.Errorf("Statistics has latency %v, expected %v", stats.Latency, d0)
+	}
+	if stats.PeerReportedLatency != d1 {
+		t.Errorf("Statistics has peer reported latency %v, expected %v", stats.PeerReportedLatency, d1)
+	}
+}
+
+func TestRequestPriority(t *testing.T) {
+	var mut sync.Mutex
+	var order []string
+	unblock := make(chan struct{})
+	m0 := newTestModel()
+	m0.requestFn = func(folder, name string, size int32, offset int64) (RequestResponse, error) {
+		if name == "first" {
+			<-unblock
+		}
+		mut.Lock()
+		order = append(order, name)
+		mut.Unlock()
+		return &fakeRequestResponse{make([]byte, size)}, nil
+	}
+
+	c0, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, []Option{WithMaxConcurrentRequests(1)}, nil)
+	if err := c1.WaitHandshake(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	request := func(ctx context.Context, name string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c1.Request(ctx, "default", name, 0, 128, nil, 0, false); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	waitQueued := func(n int) {
+		t.Helper()
+		for i := 0; c0.requests.queued() != n; i++ {
+			if i == 1000 {
+				t.Fatalf("Expected %d queued requests, got %d", n, c0.requests.queued())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// The first request occupies the only slot, the rest are queued.
+	request(context.Background(), "first")
+	time.Sleep(10 * time.Millisecond)
+	low := ContextWithRequestPriority(context.Background(), PriorityLow)
+	for i := 0; i < 5; i++ {
+		request(low, "low")
+	}
+	waitQueued(5)
+	request(context.Background(), "normal")
+	waitQueued(6)
+	request(ContextWithRequestPriority(context.Background(), PriorityHigh), "high")
+	waitQueued(7)
+
+	close(unblock)
+	wg.Wait()
+
+	expected := []string{"first", "high", "normal", "low", "low", "low", "low", "low"}
+	if len(order) != len(expected) {
+		t.Fatalf("Got requests %v, expected %v", order, expected)
+	}
+	for i := range order {
+		if order[i] != expected[i] {
+			t.Fatalf("Got requests %v, expected %v", order, expected)
+		}
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+
+	c0 := NewConnection(c1ID, ar, bw, newTestModel(), "c0", CompressAlways).(wireFormatConnection).rawConnection
+	c0.Start()
+	c1 := NewConnection(c0ID, br, aw, newTestModel(), "c1", CompressAlways).(wireFormatConnection).rawConnection
+	c1.Start()
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+
+	// Latency and skew are measured right after the handshake.
+	deadline := time.Now().Add(time.Second)
+	for c0.Statistics().Latency == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for latency measurement")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Both sides share a clock, so any skew is measurement error bounded
+	// by the round trip time.
+	if skew, rtt := c0.ClockSkew(), c0.Statistics().Latency; skew > rtt || skew < -rtt {
+		t.Errorf("Clock skew %v larger than round trip time %v", skew, rtt)
+	}
+
+	c0.setClockSkew(-2 * ClockSkewWarning)
+	if skew := c0.ClockSkew(); skew != -2*ClockSkewWarning {
+		t.Errorf("Clock skew %v, expected %v", skew, -2*ClockSkewWarning)
+	}
+	if atomic.LoadInt32(&c0.clockSkewWarned) != 1 {
+		t.Error("Expected a warning about clock skew")
+	}
+}
+
+func TestOneWayLatency(t *testing.T) {
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, newTestModel(), "name", CompressNever).(wireFormatConnection).rawConnection
+
+	// Nothing is estimated before the skew is known.
+	c.measureOneWayLatency(time.Now().Add(-time.Second))
+	if d := c.Statistics().OneWayLatency; d != 0 {
+		t.Errorf("One way latency %v without a known clock skew", d)
+	}
+
+	// The other side is an hour ahead and sent the ping 100ms ago.
+	c.setClockSkew(time.Hour)
+	c.measureOneWayLatency(time.Now().Add(time.Hour - 100*time.Millisecond))
+	if d := c.Statistics().OneWayLatency; d < 100*time.Millisecond || d > time.Second {
+		t.Errorf("One way latency %v, expected about 100ms", d)
+	}
+
+	// A ping from the future is taken as immediate.
+	c.measureOneWayLatency(time.Now().Add(2 * time.Hour))
+	if d := c.Statistics().OneWayLatency; d != 0 {
+		t.Errorf("One way latency %v, expected zero", d)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	m0 := newTestModel()
+	m0.requestFn = func(string, string, int32, int64) (RequestResponse, error) {
+		// Hold the request so it stays outstanding.
+		<-release
+		return nil, ErrNoSuchFile
+	}
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c1.WaitHandshake(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	h := c1.HealthCheck()
+	if !h.Alive || h.Closed {
+		t.Errorf("Expected an alive connection, got %+v", h)
+	}
+	if time.Since(h.LastReceive) > time.Second {
+		t.Errorf("Expected a recent receive, got %v", h.LastReceive)
+	}
+	if h.OutstandingRequests != 0 {
+		t.Errorf("Expected no outstanding requests, got %d", h.OutstandingRequests)
+	}
+
+	go c1.Request(context.Background(), "default", "foo", 0, 10, nil, 0, false)
+	for c1.HealthCheck().OutstandingRequests != 1 {
+		if ctx.Err() != nil {
+			t.Fatal("Timed out waiting for outstanding request")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c1.Close(errManual)
+	<-c1.closed
+	h = c1.HealthCheck()
+	if h.Alive || !h.Closed || h.OutstandingRequests != 0 {
+		t.Errorf("Expected a closed connection, got %+v", h)
+	}
+}
+
+func TestResponseOfWrongType(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	m := newTestCloseReasonModel()
+	c := NewConnection(c0ID, r, ioutil.Discard, m, "name", CompressNever, WithoutPinger()).(wireFormatConnection)
+	c.Start()
+	c.ClusterConfig(ClusterConfig{})
+	enc := NewEncoder(w, CompressNever)
+	if err := enc.Encode(&ClusterConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		data []byte
+		err  error
+	}
+	requested := make(chan result, 1)
+	go func() {
+		data, err := c.Request(context.Background(), "default", "foo", 0, 4, nil, 0, false)
+		requested <- result{data, err}
+	}()
+	var inFlight []RequestStat
+	for len(inFlight) == 0 {
+		time.Sleep(time.Millisecond)
+		inFlight = c.InFlight()
+	}
+
+	// A pong is no answer to a request.
+	go enc.Encode(&Pong{ID: inFlight[0].ID})
+
+	m.expectReason(t, c, CloseReasonProtocolError)
+	select {
+	case res := <-requested:
+		if res.err != ErrClosed {
+			t.Errorf("Request returned %v, %v, expected ErrClosed", res.data, res.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Request didn't return")
+	}
+}
+
+func TestEmptyResponse(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithRequestCoalescing()}} {
+		m0 := newTestModel()
+		m0.data = []byte{}
+		_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, nil, opts)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		buf, err := c1.Request(ctx, "default", "empty", 0, 0, nil, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf == nil || len(buf) != 0 {
+			t.Errorf("Expected an empty, non-nil block, got %#v", buf)
+		}
+
+		m0.requestFn = func(string, string, int32, int64) (RequestResponse, error) {
+			return nil, ErrNoSuchFile
+		}
+		buf, err = c1.Request(ctx, "default", "missing", 0, 0, nil, 0, false)
+		if err != ErrNoSuchFile || buf != nil {
+			t.Errorf("Expected no block and ErrNoSuchFile, got %#v, %v", buf, err)
+		}
+
+		cancel()
+	}
+}
+
+func TestPingRetries(t *testing.T) {
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, newTestModel(), "name", CompressAlways, WithPingRetries(3, 10*time.Millisecond)).(wireFormatConnection).rawConnection
+	c.Start()
+	c.ClusterConfig(ClusterConfig{})
+
+	// Nothing received, gives up after two retries with 10 + 20 ms waits.
+	t0 := time.Now()
+	if c.retryPings() {
+		t.Error("Retrying pings should have failed")
+	}
+	if d := time.Since(t0); d < 30*time.Millisecond {
+		t.Errorf("Gave up after %v, expected at least 30ms", d)
+	}
+
+	// Something received while waiting.
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		atomic.StoreInt64(&c.cr.last, time.Now().UnixNano())
+	}()
+	if !c.retryPings() {
+		t.Error("Retrying pings should have succeeded")
+	}
+
+	// The default is to not retry at all.
+	c = NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, newTestModel(), "name", CompressAlways).(wireFormatConnection).rawConnection
+	t0 = time.Now()
+	if c.retryPings() {
+		t.Error("Retrying pings should have failed")
+	}
+	if d := time.Since(t0); d > time.Second {
+		t.Errorf("Gave up after %v, expected right away", d)
+	}
+}
+
+func TestRequestPanicRecovery(t *testing.T) {
+	m0 := newTestModel()
+	m0.requestFn = func(folder, name string, size int32, offset int64) (RequestResponse, error) {
+		if name == "boom" {
+			panic("boom")
+		}
+		return &fakeRequestResponse{make([]byte, size)}, nil
+	}
+
+	c0, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+	if _, err := c1.Request(context.Background(), "default", "boom", 0, 128, nil, 0, false); err != ErrGeneric {
+		t.Errorf("Unexpected error %v, expected %v", err, ErrGeneric)
+	}
+	if _, err := c1.Request(context.Background(), "default", "fine", 0, 128, nil, 0, false); err != nil {
+		t.Error("Unexpected error:", err)
+	}
+	if c0.Closed() || c1.Closed() {
+		t.Error("Connections should have survived the panic")
+	}
+}
+
+func TestNotReadyRetryAfter(t *testing.T) {
+	m0 := newTestModel()
+	m0.requestFn = func(folder, name string, size int32, offset int64) (RequestResponse, error) {
+		if name == "plain" {
+			return nil, ErrNotReady
+		}
+		return nil, &NotReadyError{RetryAfter: 2500 * time.Millisecond}
+	}
+
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+	for name, retryAfter := range map[string]time.Duration{"plain": 0, "delayed": 2500 * time.Millisecond} {
+		_, err := c1.Request(context.Background(), "default", name, 0, 128, nil, 0, false)
+		if !errors.Is(err, ErrNotReady) {
+			t.Errorf("%s: unexpected error %v, expected %v", name, err, ErrNotReady)
+			continue
+		}
+		var nr *NotReadyError
+		if !errors.As(err, &nr) {
+			t.Errorf("%s: error %v is not a NotReadyError", name, err)
+			continue
+		}
+		if nr.RetryAfter != retryAfter {
+			t.Errorf("%s: got retry after %v, expected %v", name, nr.RetryAfter, retryAfter)
+		}
+	}
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	m := newTestModel()
+	rd, wr := io.Pipe()
+	c := NewConnection(c0ID, rd, &testutils.NoopRW{}, m, "name", CompressNever, WithHandshakeTimeout(50*time.Millisecond))
+	c.Start()
+	c.ClusterConfig(ClusterConfig{})
+
+	// The other side starts sending its cluster config, and then stalls.
+	go wr.Write([]byte{0, 2, 0x08})
+
+	if err := m.closedError(); err != ErrHandshakeTimeout {
+		t.Fatalf("Unexpected close error %v, expected %v", err, ErrHandshakeTimeout)
+	}
+}
+
+func TestHandshakeTimeoutCompleted(t *testing.T) {
+	m0 := newTestModel()
+
+	c0, _ := newTestConnections(t, m0, newTestModel(), CompressNever, []Option{WithHandshakeTimeout(50 * time.Millisecond)}, nil)
+
+	time.Sleep(100 * time.Millisecond)
+	if c0.Closed() {
+		t.Fatal("Connection should not be closed after completing the handshake")
+	}
+}
+
+func TestUnknownMessageSkipped(t *testing.T) {
+	// With WithUnknownMessages(true), a message of a type from the future,
+	// from a newer peer, is skipped and the connection carries on with the
+	// messages after it.
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	defer ar.Close()
+	defer br.Close()
+
+	received := make(chan string, 1)
+	m := newTestModel()
+	m.indexFn = func(_ DeviceID, folder string, _ []FileInfo) {
+		received <- folder
+	}
+	c := NewConnection(c0ID, ar, bw, m, "name", CompressNever, WithoutPinger(), WithUnknownMessages(true))
+	c.Start()
+	c.ClusterConfig(ClusterConfig{})
+	go io.Copy(ioutil.Discard, br)
+
+	enc := NewEncoder(aw, CompressNever)
+	if err := enc.Encode(&ClusterConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	writeUnknownMessage(t, aw)
+	if err := enc.Encode(&Index{Folder: "default"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case folder := <-received:
+		if folder != "default" {
+			t.Errorf("Got index for %q", folder)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the index after the unknown message")
+	}
+	select {
+	case <-m.closedCh:
+		t.Fatalf("Connection closed on an unknown message: %v", m.closedErr)
+	default:
+	}
+}
+
+func TestUnknownMessageCloses(t *testing.T) {
+	// By default, a message of an unknown type closes the connection as a
+	// protocol error.
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	defer ar.Close()
+	defer br.Close()
+
+	m := newTestCloseReasonModel()
+	c := NewConnection(c0ID, ar, bw, m, "name", CompressNever, WithoutPinger())
+	c.Start()
+	c.ClusterConfig(ClusterConfig{})
+	go io.Copy(ioutil.Discard, br)
+
+	if err := NewEncoder(aw, CompressNever).Encode(&ClusterConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	writeUnknownMessage(t, aw)
+
+	m.expectReason(t, c, CloseReasonProtocolError)
+	if err := m.closedError(); !errors.Is(err, ErrUnknownMessage) {
+		t.Errorf("Closed with %v, expected %v", err, ErrUnknownMessage)
+	}
+}
+
+// writeUnknownMessage writes a message of a type that doesn't exist, as if
+// from a newer peer.
+func writeUnknownMessage(t *testing.T, w io.Writer) {
+	t.Helper()
+	hdrBs, _ := (&Header{Type: MessageType(1000)}).Marshal()
+	bs := make([]byte, 2+len(hdrBs)+4+3)
+	binary.BigEndian.PutUint16(bs, uint16(len(hdrBs)))
+	copy(bs[2:], hdrBs)
+	binary.BigEndian.PutUint32(bs[2+len(hdrBs):], 3)
+	copy(bs[2+len(hdrBs)+4:], []byte{1, 2, 3})
+	if _, err := w.Write(bs); err != nil {
+		t.Error(err)
+	}
+}
+
+// idSequence returns an idSource giving the IDs in order, then counting up
+// from the last one.
+func idSequence(ids ...int32) func() int32 {
+	var mut sync.Mutex
+	return func() int32 {
+		mut.Lock()
+		defer mut.Unlock()
+		id := ids[0]
+		if len(ids) > 1 {
+			ids = ids[1:]
+		} else {
+			ids[0]++
+		}
+		return id
+	}
+}
+
+func TestMessageIDTaken(t *testing.T) {
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	defer ar.Close()
+	defer br.Close()
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	m1 := newTestModel()
+	m1.requestFn = func(_, _ string, size int32, offset int64) (RequestResponse, error) {
+		started <- struct{}{}
+		<-release
+		return &fakeRequestResponse{bytes.Repeat([]byte{byte(offset)}, int(size))}, nil
+	}
+	c0 := NewConnection(c1ID, ar, bw, newTestModel(), "c0", CompressNever, WithoutPinger())
+	// The second request is first given the ID of the first one, still
+	// waiting for its response.
+	raw0 := c0.(wireFormatConnection).rawConnection
+	raw0.idSource = idSequence(7, 7, 7, 8)
+	c0.Start()
+	c1 := NewConnection(c0ID, br, aw, m1, "c1", CompressNever, WithoutPinger())
+	c1.Start()
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	results := make(chan []byte, 2)
+	for offset := int64(1); offset <= 2; offset++ {
+		go func(offset int64) {
+			data, err := c0.Request(ctx, "default", "foo", offset, 1, nil, 0, false)
+			if err != nil {
+				t.Error(err)
+			}
+			if len(data) != 1 || data[0] != byte(offset) {
+				t.Errorf("Got %v for the request at offset %d", data, offset)
+			}
+			results <- data
+		}(offset)
+		select {
+		case <-started:
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for request")
+		}
+	}
+
+	raw0.awaitingMut.Lock()
+	_, ok7 := raw0.awaiting[7]
+	_, ok8 := raw0.awaiting[8]
+	raw0.awaitingMut.Unlock()
+	if !ok7 || !ok8 {
+		t.Errorf("Requests not awaiting IDs 7 and 8")
+	}
+	close(release)
+	for i := 0; i < 2; i++ {
+		<-results
+	}
+}
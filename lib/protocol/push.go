@@ -0,0 +1,82 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+)
+
+// A PushModel accepts data pushed to us by the other side. A Model passed to
+// NewConnection that also implements PushModel makes the connection
+// advertise CapabilityPush.
+type PushModel interface {
+	// The peer device pushed data for the given file
+	Push(deviceID DeviceID, folder, name string, offset int64, data []byte) error
+}
+
+// defaultMaxPendingPushBytes is the default limit on the amount of pushed
+// data that has not yet been acknowledged by the other side.
+const defaultMaxPendingPushBytes = 2 * MaxBlockSize
+
+// Push sends data to be written at the given offset in the file on the
+// other side, and waits for it to be acknowledged. It waits for the
+// handshake to complete, and returns ErrUnsupported if the other side
+// doesn't accept pushes. Pushes wait for earlier ones to be acknowledged
+// when the amount of outstanding data would otherwise exceed the limit set
+// by WithMaxPendingPushBytes, so the other side is never overwhelmed.
+func (c *rawConnection) Push(ctx context.Context, folder, name string, offset int64, data []byte) error {
+	if err := c.WaitHandshake(ctx); err != nil {
+		return err
+	}
+	if !c.peerCapabilities.Has(CapabilityPush) {
+		return ErrUnsupported
+	}
+
+	if err := c.pushBytes.take(ctx, len(data)); err != nil {
+		return err
+	}
+	defer c.pushBytes.give(len(data))
+
+	id, rc, err := c.newAwaiting(messageTypePush)
+	if err != nil {
+		return err
+	}
+	ok := c.send(ctx, &Push{
+		ID:     id,
+		Folder: folder,
+		Name:   name,
+		Offset: offset,
+		Data:   data,
+	}, nil)
+	if !ok {
+		c.forgetAwaiting(id)
+		return ErrClosed
+	}
+
+	select {
+	case res, ok := <-rc:
+		if !ok {
+			return ErrClosed
+		}
+		return res.err
+	case <-ctx.Done():
+		c.forgetAwaiting(id)
+		return ctx.Err()
+	}
+}
+
+func (c *rawConnection) handlePush(push Push) {
+	var err error
+	if len(push.Data) > c.maxRequestSize {
+		l.Debugf("Push(%v, %v, %q, %d) exceeds max request size %d", c.id, push.Folder, push.Name, len(push.Data), c.maxRequestSize)
+		err = ErrRequestTooLarge
+	} else if c.capabilities.Has(CapabilityPush) {
+		err = c.receiver.(PushModel).Push(c.id, push.Folder, push.Name, push.Offset, push.Data)
+	} else {
+		err = ErrUnsupported
+	}
+	c.send(context.Background(), &Response{
+		ID:   push.ID,
+		Code: errorToCode(err),
+	}, nil)
+}
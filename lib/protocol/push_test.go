@@ -0,0 +1,113 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type pushedData struct {
+	folder string
+	name   string
+	offset int64
+	data   []byte
+}
+
+type testPushModel struct {
+	*TestModel
+	pushFn func(folder, name string, offset int64, data []byte) error
+}
+
+func (t *testPushModel) Push(deviceID DeviceID, folder, name string, offset int64, data []byte) error {
+	return t.pushFn(folder, name, offset, data)
+}
+
+func TestPush(t *testing.T) {
+	pushed := make(chan pushedData, 1)
+	m0 := &testPushModel{TestModel: newTestModel()}
+	m0.pushFn = func(folder, name string, offset int64, data []byte) error {
+		if name == "missing" {
+			return ErrNoSuchFile
+		}
+		pushed <- pushedData{folder, name, offset, data}
+		return nil
+	}
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressAlways, nil, nil)
+
+	data := []byte("some pushed data")
+	if err := c1.Push(context.Background(), "default", "foo", 128, data); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	select {
+	case p := <-pushed:
+		if p.folder != "default" || p.name != "foo" || p.offset != 128 || !bytes.Equal(p.data, data) {
+			t.Errorf("Unexpected push %+v", p)
+		}
+	default:
+		t.Fatal("Push was acknowledged before reaching the model")
+	}
+
+	if err := c1.Push(context.Background(), "default", "missing", 0, data); err != ErrNoSuchFile {
+		t.Errorf("Unexpected error %v, expected %v", err, ErrNoSuchFile)
+	}
+}
+
+func TestPushUnsupported(t *testing.T) {
+	m0 := &testPushModel{TestModel: newTestModel()}
+	m0.pushFn = func(folder, name string, offset int64, data []byte) error {
+		t.Error("Unexpected push")
+		return nil
+	}
+	// The side that can accept pushes can't push to the one that can't.
+	c0, _ := newTestConnections(t, m0, newTestModel(), CompressAlways, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c0.Push(ctx, "default", "foo", 0, []byte("data")); err != ErrUnsupported {
+		t.Errorf("Unexpected error %v, expected %v", err, ErrUnsupported)
+	}
+}
+
+func TestPushFlowControl(t *testing.T) {
+	const (
+		pushSize = 128
+		window   = 2 * pushSize
+	)
+
+	var cur, max int32
+	m0 := &testPushModel{TestModel: newTestModel()}
+	m0.pushFn = func(folder, name string, offset int64, data []byte) error {
+		n := atomic.AddInt32(&cur, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&cur, -1)
+		return nil
+	}
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressAlways, nil, []Option{WithMaxPendingPushBytes(window)})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := c1.Push(context.Background(), "default", "foo", int64(i*pushSize), make([]byte, pushSize)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if m := atomic.LoadInt32(&max); m > window/pushSize {
+		t.Errorf("Saw %d concurrent pushes, expected at most %d", m, window/pushSize)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import "context"
+
+// Quiesce prepares the connection for closing without anything in flight.
+// From when it is called, Request, RequestRanges, Push, PeerQuota, Listing
+// and FileInfo fail with ErrQuiescing without sending anything, as do
+// Index, IndexUpdate and IndexSummary, and index updates held back by
+// WithIndexThrottle are dropped. An index already being sent is sent in
+// full, all its messages if split. Quiesce then waits until messages being
+// sent have been handed to the writer and all responses we are waiting for
+// have arrived, including those to requests whose caller has given up, or
+// the context is done. Pings still work, and requests from the other side
+// are still answered, as it doesn't know we are about to close. After
+// Quiesce returns nil, Close sends its message after everything else.
+//
+// It returns ErrClosed if the connection closes first, and the error of the
+// context if that is done first. The connection keeps rejecting new
+// messages either way; there is no way back.
+func (c *rawConnection) Quiesce(ctx context.Context) error {
+	c.awaitingMut.Lock()
+	c.quiescing = true
+	c.awaitingMut.Unlock()
+
+	// Wait for index data that is being sent; anything later sees that
+	// we are quiescing.
+	sent := make(chan struct{})
+	go func() {
+		c.idxMut.Lock()
+		c.idxMut.Unlock()
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-c.closed:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	c.awaitingMut.Lock()
+	if c.drained == nil {
+		c.drained = make(chan struct{})
+	}
+	drained := c.drained
+	c.checkDrainedLocked()
+	c.awaitingMut.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-c.closed:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isQuiescing returns true once Quiesce has been called.
+func (c *rawConnection) isQuiescing() bool {
+	c.awaitingMut.Lock()
+	defer c.awaitingMut.Unlock()
+	return c.quiescing
+}
+
+// checkDrainedLocked closes the drained channel, if Quiesce is waiting on
+// it and there are no more responses to wait for other than pongs. The
+// awaitingMut must be held.
+func (c *rawConnection) checkDrainedLocked() {
+	if c.drained == nil {
+		return
+	}
+	select {
+	case <-c.drained:
+		return
+	default:
+	}
+	for _, ar := range c.awaiting {
+		if ar.msgType != messageTypePing {
+			return
+		}
+	}
+	close(c.drained)
+}
@@ -0,0 +1,173 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newQuiesceTestModel returns a model answering requests only once release
+// is closed.
+func newQuiesceTestModel(release chan struct{}) *TestModel {
+	m := newTestModel()
+	m.requestFn = func(string, string, int32, int64) (RequestResponse, error) {
+		<-release
+		return &fakeRequestResponse{[]byte("data")}, nil
+	}
+	return m
+}
+
+func TestQuiesceWaitsForResponses(t *testing.T) {
+	release := make(chan struct{})
+	_, c1 := newTestConnections(t, newQuiesceTestModel(release), newTestModel(), CompressNever, nil, nil)
+
+	type result struct {
+		data []byte
+		err  error
+	}
+	requested := make(chan result, 1)
+	go func() {
+		data, err := c1.Request(context.Background(), "default", "foo", 0, 4, nil, 0, false)
+		requested <- result{data, err}
+	}()
+	// Wait for the request to be on its way.
+	for len(c1.InFlight()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	quiesced := make(chan error, 1)
+	go func() {
+		quiesced <- c1.Quiesce(context.Background())
+	}()
+	select {
+	case err := <-quiesced:
+		t.Fatalf("Quiesce returned %v with a request outstanding", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// New messages are refused in the meantime, while pings still work.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c1.Request(ctx, "default", "bar", 0, 4, nil, 0, false); err != ErrQuiescing {
+		t.Errorf("Request during quiesce returned %v, expected ErrQuiescing", err)
+	}
+	if _, err := c1.RequestRanges(ctx, "default", "bar", []Range{{Size: 4}}); err != ErrQuiescing {
+		t.Errorf("RequestRanges during quiesce returned %v, expected ErrQuiescing", err)
+	}
+	if err := c1.Index(ctx, "default", nil); err != ErrQuiescing {
+		t.Errorf("Index during quiesce returned %v, expected ErrQuiescing", err)
+	}
+	if _, err := c1.Ping(ctx); err != nil {
+		t.Errorf("Ping during quiesce returned %v", err)
+	}
+
+	close(release)
+	select {
+	case err := <-quiesced:
+		if err != nil {
+			t.Errorf("Quiesce returned %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Quiesce didn't return after the response")
+	}
+	res := <-requested
+	if res.err != nil || string(res.data) != "data" {
+		t.Errorf("Outstanding request returned %q, %v", res.data, res.err)
+	}
+}
+
+func TestQuiesceContext(t *testing.T) {
+	release := make(chan struct{})
+	_, c1 := newTestConnections(t, newQuiesceTestModel(release), newTestModel(), CompressNever, nil, nil)
+	defer close(release)
+
+	go c1.Request(context.Background(), "default", "foo", 0, 4, nil, 0, false)
+	for len(c1.InFlight()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c1.Quiesce(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Quiesce returned %v, expected context.DeadlineExceeded", err)
+	}
+	// It keeps refusing new requests.
+	if _, err := c1.Request(context.Background(), "default", "bar", 0, 4, nil, 0, false); err != ErrQuiescing {
+		t.Errorf("Request after quiesce returned %v, expected ErrQuiescing", err)
+	}
+}
+
+func TestQuiesceIdle(t *testing.T) {
+	release := make(chan struct{})
+	_, c1 := newTestConnections(t, newQuiesceTestModel(release), newTestModel(), CompressNever, nil, nil)
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c1.Quiesce(ctx); err != nil {
+		t.Errorf("Quiesce returned %v", err)
+	}
+}
+
+func TestQuiesceFinishesSplitIndex(t *testing.T) {
+	var files []FileInfo
+	for i := 0; i < 10; i++ {
+		files = append(files, FileInfo{Name: fmt.Sprintf("file%d", i), Type: FileInfoTypeDirectory})
+	}
+
+	// The other side holds on to the first batch, so that we are still
+	// sending when quiescing.
+	first := make(chan struct{})
+	release := make(chan struct{})
+	received := make(chan int, len(files))
+	m1 := newTestModel()
+	m1.indexFn = func(_ DeviceID, _ string, files []FileInfo) {
+		close(first)
+		<-release
+		received <- len(files)
+	}
+	m1.indexUpdateFn = func(_ DeviceID, _ string, files []FileInfo) {
+		received <- len(files)
+	}
+	c0, _ := newTestConnections(t, newTestModel(), m1, CompressNever, []Option{WithIndexSplitting()}, []Option{WithMaxIndexFiles(1)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c0.WaitHandshake(ctx); err != nil {
+		t.Fatal(err)
+	}
+	indexed := make(chan error, 1)
+	go func() {
+		indexed <- c0.Index(ctx, "default", files)
+	}()
+	select {
+	case <-first:
+	case <-ctx.Done():
+		t.Fatal("The first batch didn't arrive")
+	}
+	quiesced := make(chan error, 1)
+	go func() {
+		quiesced <- c0.Quiesce(ctx)
+	}()
+	for !c0.isQuiescing() {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	if err := <-indexed; err != nil {
+		t.Errorf("Index returned %v", err)
+	}
+	if err := <-quiesced; err != nil {
+		t.Errorf("Quiesce returned %v", err)
+	}
+	for i := range files {
+		select {
+		case <-received:
+		case <-ctx.Done():
+			t.Fatalf("Received %d of %d batches", i, len(files))
+		}
+	}
+}
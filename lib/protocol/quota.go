@@ -0,0 +1,76 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+)
+
+// A QuotaModel reports the available space for a folder to the other side.
+// A Model passed to NewConnection that also implements QuotaModel makes the
+// connection advertise CapabilityQuota.
+type QuotaModel interface {
+	// The peer device asked for the free and total space of the folder,
+	// in bytes
+	Quota(deviceID DeviceID, folder string) (free, total uint64, err error)
+}
+
+// PeerQuota asks the other side for the free and total space, in bytes,
+// available to the given folder. It waits for the handshake to complete,
+// and returns ErrUnsupported if the other side doesn't report quotas.
+func (c *rawConnection) PeerQuota(ctx context.Context, folder string) (free, total uint64, err error) {
+	if err := c.WaitHandshake(ctx); err != nil {
+		return 0, 0, err
+	}
+	if !c.peerCapabilities.Has(CapabilityQuota) {
+		return 0, 0, ErrUnsupported
+	}
+
+	id, rc, err := c.newAwaiting(messageTypeQuotaRequest)
+	if err != nil {
+		return 0, 0, err
+	}
+	ok := c.send(ctx, &QuotaRequest{
+		ID:     id,
+		Folder: folder,
+	}, nil)
+	if !ok {
+		c.forgetAwaiting(id)
+		return 0, 0, ErrClosed
+	}
+
+	select {
+	case res, ok := <-rc:
+		if !ok {
+			return 0, 0, ErrClosed
+		}
+		if res.err != nil {
+			return 0, 0, res.err
+		}
+		resp, ok := res.msg.(*QuotaResponse)
+		if !ok {
+			// The other side answered with the wrong kind of message
+			return 0, 0, ErrGeneric
+		}
+		return resp.Free, resp.Total, nil
+	case <-ctx.Done():
+		c.forgetAwaiting(id)
+		return 0, 0, ctx.Err()
+	}
+}
+
+func (c *rawConnection) handleQuotaRequest(req QuotaRequest) {
+	resp := &QuotaResponse{ID: req.ID}
+	var err error
+	if c.quotaModel != nil {
+		resp.Free, resp.Total, err = c.quotaModel.Quota(c.id, req.Folder)
+	} else {
+		err = ErrUnsupported
+	}
+	resp.Code = errorToCode(err)
+	c.send(context.Background(), resp, nil)
+}
+
+func (c *rawConnection) handleQuotaResponse(resp QuotaResponse) error {
+	return c.resolveAwaiting(resp.ID, messageTypeQuotaResponse, asyncResult{err: codeToError(resp.Code), msg: &resp})
+}
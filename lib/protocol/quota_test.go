@@ -0,0 +1,44 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type testQuotaModel struct {
+	*TestModel
+}
+
+func (t *testQuotaModel) Quota(deviceID DeviceID, folder string) (uint64, uint64, error) {
+	if folder != "default" {
+		return 0, 0, ErrGeneric
+	}
+	return 1 << 30, 1 << 40, nil
+}
+
+func TestPeerQuota(t *testing.T) {
+	c0, c1 := newTestConnections(t, &testQuotaModel{newTestModel()}, newTestModel(), CompressAlways, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	free, total, err := c1.PeerQuota(ctx, "default")
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if free != 1<<30 || total != 1<<40 {
+		t.Errorf("Got quota %d/%d, expected %d/%d", free, total, 1<<30, 1<<40)
+	}
+
+	if _, _, err := c1.PeerQuota(ctx, "other"); err != ErrGeneric {
+		t.Errorf("Unexpected error %v, expected %v", err, ErrGeneric)
+	}
+
+	// The other way around isn't supported.
+	if _, _, err := c0.PeerQuota(ctx, "default"); err != ErrUnsupported {
+		t.Errorf("Unexpected error %v, expected %v", err, ErrUnsupported)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// MaxRequestRanges is the largest number of ranges in a RequestRanges call.
+const MaxRequestRanges = 1024
+
+// A RangeResult is the outcome of reading one of the ranges passed to
+// RequestRanges: the data of the range, or the error reading it.
+type RangeResult struct {
+	Data []byte
+	Err  error
+}
+
+// RequestRanges asks the other side for several byte ranges of the file in
+// one round trip, e.g. for sparse reads. The ranges may overlap and be in
+// any order. The results are in the order of the ranges; each range
+// succeeds or fails on its own, as if requested separately without a hash.
+// The returned error is for the request as a whole: ErrRequestTooLarge
+// when there are more than MaxRequestRanges ranges, or their total size is
+// above what the other side serves in a request (see WithMaxRequestSize),
+// ErrInvalid when a range has a negative offset or size, ErrUnsupported
+// when the other side doesn't serve range requests, and ErrQuiescing once
+// Quiesce has been called, in which case nothing is sent. RequestRanges
+// waits for the handshake to complete.
+func (c *rawConnection) RequestRanges(ctx context.Context, folder, name string, ranges []Range) ([]RangeResult, error) {
+	if err := checkFilename(name); err != nil {
+		return nil, err
+	}
+	if len(ranges) > MaxRequestRanges {
+		return nil, ErrRequestTooLarge
+	}
+	if err := c.WaitHandshake(ctx); err != nil {
+		return nil, err
+	}
+	if !c.peerCapabilities.Has(CapabilityRangeRequest) {
+		return nil, ErrUnsupported
+	}
+
+	id, rc, err := c.newAwaiting(messageTypeRangeRequest)
+	if err != nil {
+		return nil, err
+	}
+	ok := c.send(ctx, &RangeRequest{
+		ID:     id,
+		Folder: folder,
+		Name:   name,
+		Ranges: ranges,
+	}, nil)
+	if !ok {
+		c.forgetAwaiting(id)
+		return nil, ErrClosed
+	}
+
+	select {
+	case res, ok := <-rc:
+		if !ok {
+			return nil, ErrClosed
+		}
+		if res.err != nil {
+			return nil, res.err
+		}
+		resp, ok := res.msg.(*RangeResponse)
+		if !ok {
+			// The other side answered with the wrong kind of message
+			return nil, ErrGeneric
+		}
+		if len(resp.Segments) != len(ranges) {
+			return nil, fmt.Errorf("protocol error: range response: %d segments for %d ranges", len(resp.Segments), len(ranges))
+		}
+		if c.folderStats != nil {
+			c.folderStats.inResponse(folder, segmentsSize(resp.Segments))
+		}
+		results := make([]RangeResult, len(ranges))
+		for i, seg := range resp.Segments {
+			if err := codeToError(seg.Code); err != nil {
+				results[i].Err = err
+				continue
+			}
+			if len(seg.Data) != int(ranges[i].Size) {
+				return nil, fmt.Errorf("protocol error: range response: %d bytes for range of %d", len(seg.Data), ranges[i].Size)
+			}
+			results[i].Data = seg.Data
+			if results[i].Data == nil {
+				results[i].Data = []byte{}
+			}
+		}
+		return results, nil
+	case <-ctx.Done():
+		c.forgetAwaiting(id)
+		return nil, ctx.Err()
+	}
+}
+
+// handleRangeRequest reads the ranges from the model one by one, as
+// requests without a hash, and sends them in a single response.
+func (c *rawConnection) handleRangeRequest(req RangeRequest) {
+	resp := &RangeResponse{ID: req.ID}
+	var total int64
+	for _, r := range req.Ranges {
+		if r.Offset < 0 || r.Size < 0 {
+			// Negative sizes would also make the total look small.
+			l.Debugf("RangeRequest(%v, %v, %q, %d ranges) with invalid range %v", c.id, req.Folder, req.Name, len(req.Ranges), r)
+			resp.Code = errorToCode(ErrInvalid)
+			c.send(context.Background(), resp, nil)
+			return
+		}
+		total += int64(r.Size)
+	}
+	if len(req.Ranges) > MaxRequestRanges || total > int64(c.maxRequestSize) {
+		l.Debugf("RangeRequest(%v, %v, %q, %d ranges, %d bytes) too large", c.id, req.Folder, req.Name, len(req.Ranges), total)
+		resp.Code = errorToCode(ErrRequestTooLarge)
+		c.send(context.Background(), resp, nil)
+		return
+	}
+
+	if c.requestObserver != nil {
+		c.requestObserver.RequestStarted(c.id, req.Folder, req.Name)
+		defer c.requestObserver.RequestFinished(c.id, req.Folder, req.Name)
+	}
+
+	resp.Segments = make([]RangeSegment, len(req.Ranges))
+	for i, r := range req.Ranges {
+		if c.Closed() {
+			// There is nobody to answer anymore.
+			return
+		}
+		data, err := c.readRange(req.Folder, req.Name, r)
+		// The same code as for a single request, without the retry hint
+		resp.Segments[i] = RangeSegment{Data: data, Code: errorResponse(req.ID, err).Code}
+	}
+	if c.send(context.Background(), resp, nil) && c.folderStats != nil {
+		c.folderStats.outResponse(req.Folder, segmentsSize(resp.Segments))
+	}
+}
+
+// segmentsSize returns the amount of data in the segments.
+func segmentsSize(segs []RangeSegment) int {
+	var size int
+	for _, seg := range segs {
+		size += len(seg.Data)
+	}
+	return size
+}
+
+// readRange returns a copy of the data of the range as returned by the
+// model.
+func (c *rawConnection) readRange(folder, name string, r Range) (data []byte, err error) {
+	if r.Offset < 0 || r.Size < 0 {
+		return nil, ErrInvalid
+	}
+	if c.authorizer != nil {
+		if err := c.authorizer(c.id, folder, name, r.Offset, int(r.Size)); err != nil {
+			return nil, ErrUnauthorized
+		}
+	}
+	if !c.noPanicRecovery {
+		defer func() {
+			if p := recover(); p != nil {
+				l.Warnf("Panic handling range request for %q in folder %q from %v: %v\n%s", name, folder, c.id, p, debug.Stack())
+				data, err = nil, ErrGeneric
+			}
+		}()
+	}
+	res, err := c.receiver.Request(c.id, folder, name, r.Size, r.Offset, nil, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	if len(res.Data()) != int(r.Size) {
+		return nil, &TemporaryError{ErrBufferUnavailable}
+	}
+	return append([]byte(nil), res.Data()...), nil
+}
+
+func (c *rawConnection) handleRangeResponse(resp RangeResponse) error {
+	return c.resolveAwaiting(resp.ID, messageTypeRangeResponse, asyncResult{err: codeToError(resp.Code), msg: &resp})
+}
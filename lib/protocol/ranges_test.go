@@ -0,0 +1,94 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestRequestRanges(t *testing.T) {
+	content := []byte("0123456789abcdef")
+	m0 := newTestModel()
+	m0.requestFn = func(folder, name string, size int32, offset int64) (RequestResponse, error) {
+		if offset+int64(size) > int64(len(content)) {
+			return nil, ErrNoSuchFile
+		}
+		return &fakeRequestResponse{content[offset : offset+int64(size)]}, nil
+	}
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressAlways, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ranges := []Range{
+		{Offset: 10, Size: 4},
+		{Offset: 0, Size: 4},
+		{Offset: 2, Size: 4},
+		{Offset: 14, Size: 4},
+		{Offset: 3, Size: 0},
+	}
+	res, err := c1.RequestRanges(ctx, "default", "foo", ranges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != len(ranges) {
+		t.Fatalf("Got %d results, expected %d", len(res), len(ranges))
+	}
+	for i, r := range ranges {
+		if i == 3 {
+			if res[i].Err != ErrNoSuchFile {
+				t.Errorf("Range %d returned %v, expected %v", i, res[i].Err, ErrNoSuchFile)
+			}
+			continue
+		}
+		if res[i].Err != nil {
+			t.Errorf("Range %d returned %v", i, res[i].Err)
+			continue
+		}
+		if exp := content[r.Offset : r.Offset+int64(r.Size)]; !bytes.Equal(res[i].Data, exp) {
+			t.Errorf("Range %d returned %q, expected %q", i, res[i].Data, exp)
+		}
+	}
+
+	if _, err := c1.RequestRanges(ctx, "default", "foo", make([]Range, MaxRequestRanges+1)); err != ErrRequestTooLarge {
+		t.Errorf("Too many ranges returned %v, expected %v", err, ErrRequestTooLarge)
+	}
+	huge := []Range{{Offset: 0, Size: MaxBlockSize}, {Offset: 0, Size: MaxBlockSize}}
+	if _, err := c1.RequestRanges(ctx, "default", "foo", huge); err != ErrRequestTooLarge {
+		t.Errorf("Too much data returned %v, expected %v", err, ErrRequestTooLarge)
+	}
+	// A negative size must not make up for a large one.
+	negative := []Range{{Size: 2147483647}, {Size: -2147483647}}
+	if _, err := c1.RequestRanges(ctx, "default", "foo", negative); err != ErrInvalid {
+		t.Errorf("Negative size returned %v, expected %v", err, ErrInvalid)
+	}
+	if _, err := c1.RequestRanges(ctx, "default", "../escape", ranges); err == nil {
+		t.Error("Invalid name was accepted")
+	}
+}
+
+func TestRequestRangesUnsupported(t *testing.T) {
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	defer ar.Close()
+	defer br.Close()
+
+	c := NewConnection(c0ID, ar, bw, newTestModel(), "name", CompressNever, WithoutPinger()).(wireFormatConnection)
+	c.Start()
+	c.ClusterConfig(ClusterConfig{})
+	// An older peer, advertising no capabilities
+	if err := NewEncoder(aw, CompressNever).Encode(&ClusterConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	go io.Copy(ioutil.Discard, br)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c.RequestRanges(ctx, "default", "foo", []Range{{Size: 1}}); err != ErrUnsupported {
+		t.Errorf("Got %v, expected %v", err, ErrUnsupported)
+	}
+}
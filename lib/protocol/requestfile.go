@@ -0,0 +1,184 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// RequestFileWindow is the number of blocks RequestFile has requested
+	// or holds at any time.
+	RequestFileWindow = 16
+	// requestFileAttempts is how many times RequestFile requests a block
+	// before giving up on it.
+	requestFileAttempts = 3
+)
+
+// ErrHashMismatch is returned by RequestFile for a block whose data didn't
+// match its hash.
+var ErrHashMismatch = errors.New("block hash mismatch")
+
+// RequestFile requests the given blocks of the file from the other side,
+// and writes their data to dst, in order. Up to RequestFileWindow blocks
+// are requested at a time, further limited by the connection as usual,
+// e.g. by WithMaxPendingResponseBytes. The data of each block is verified
+// against its hash; a block that doesn't match, or that the other side
+// isn't ready to serve, is requested again, up to three times in total. On
+// any other error, or when the context is done, RequestFile stops and
+// returns the error, with the blocks before the failing one written to
+// dst.
+func RequestFile(ctx context.Context, conn Connection, folder, name string, blocks []BlockInfo, dst io.Writer) error {
+	return requestFile(ctx, conn, folder, name, blocks, dst, 0)
+}
+
+// RequestFileMerged is like RequestFile, except that runs of adjacent
+// blocks are requested together, in one request of up to maxSize bytes
+// and at most RequestFileWindow blocks, for fewer messages and larger
+// reads on the other side, e.g. for sequential reads of files with small
+// blocks. A merged request carries no hash; its data is split at the block
+// boundaries and each block verified against its own hash. Blocks that
+// don't match, and all blocks of a merged request that fails, e.g. as too
+// large for the other side, are then requested on their own, as by
+// RequestFile. maxSize should be within what the other side serves in a
+// request, which is MaxBlockSize by default (see WithMaxRequestSize).
+func RequestFileMerged(ctx context.Context, conn Connection, folder, name string, blocks []BlockInfo, dst io.Writer, maxSize int) error {
+	return requestFile(ctx, conn, folder, name, blocks, dst, maxSize)
+}
+
+type requestFileResult struct {
+	data []byte
+	err  error
+}
+
+func requestFile(ctx context.Context, conn Connection, folder, name string, blocks []BlockInfo, dst io.Writer, maxSize int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]chan requestFileResult, len(blocks))
+	for i := range results {
+		results[i] = make(chan requestFileResult, 1)
+	}
+
+	// A slot is taken for each block requested, and freed once its data
+	// has been written, bounding both the requests and the data held.
+	slots := make(chan struct{}, RequestFileWindow)
+	go func() {
+		for start := 0; start < len(blocks); {
+			end := mergeRun(blocks, start, maxSize)
+			for j := start; j < end; j++ {
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			go func(start, end int) {
+				if end-start == 1 {
+					data, err := requestBlock(ctx, conn, folder, name, blocks[start])
+					results[start] <- requestFileResult{data, err}
+					return
+				}
+				requestRun(ctx, conn, folder, name, blocks[start:end], results[start:end])
+			}(start, end)
+			start = end
+		}
+	}()
+
+	for i, block := range blocks {
+		var res requestFileResult
+		select {
+		case res = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if res.err != nil {
+			return errors.Wrapf(res.err, "requesting block at offset %d of %q", block.Offset, name)
+		}
+		if _, err := dst.Write(res.data); err != nil {
+			return err
+		}
+		<-slots
+	}
+	return nil
+}
+
+// mergeRun returns the end of the run of blocks starting at start that are
+// adjacent in the file and together at most maxSize bytes and
+// RequestFileWindow blocks. The run is at least the first block.
+func mergeRun(blocks []BlockInfo, start, maxSize int) int {
+	end := start + 1
+	size := int64(blocks[start].Size)
+	for end < len(blocks) && end-start < RequestFileWindow {
+		prev, next := blocks[end-1], blocks[end]
+		if next.Offset != prev.Offset+int64(prev.Size) || size+int64(next.Size) > int64(maxSize) {
+			break
+		}
+		size += int64(next.Size)
+		end++
+	}
+	return end
+}
+
+// requestRun requests the adjacent blocks in one request, delivering the
+// data of each verified block to its result, and requesting those that
+// can't be verified on their own.
+func requestRun(ctx context.Context, conn Connection, folder, name string, run []BlockInfo, results []chan requestFileResult) {
+	start := run[0].Offset
+	last := run[len(run)-1]
+	size := int(last.Offset + int64(last.Size) - start)
+	data, err := conn.Request(ctx, folder, name, start, size, nil, 0, false)
+	if err == nil && len(data) != size {
+		err = ErrGeneric
+	}
+	if err != nil {
+		l.Debugf("Merged request for %d bytes at offset %d of %q: %v", size, start, name, err)
+	}
+	for i, block := range run {
+		if err == nil {
+			off := block.Offset - start
+			if bd := data[off : off+int64(block.Size)]; hashMatches(bd, block.Hash) {
+				results[i] <- requestFileResult{bd, nil}
+				continue
+			}
+		}
+		bd, berr := requestBlock(ctx, conn, folder, name, block)
+		results[i] <- requestFileResult{bd, berr}
+	}
+}
+
+// requestBlock requests the block, verifying its hash, with retries.
+func requestBlock(ctx context.Context, conn Connection, folder, name string, block BlockInfo) ([]byte, error) {
+	backoff := defaultRequestBackoff
+	for attempt := 1; ; attempt++ {
+		data, err := conn.Request(ctx, folder, name, block.Offset, int(block.Size), block.Hash, block.WeakHash, false)
+		if err == nil && !hashMatches(data, block.Hash) {
+			err = ErrHashMismatch
+		}
+		if err == nil || attempt >= requestFileAttempts {
+			return data, err
+		}
+
+		if !errors.Is(err, ErrNotReady) && err != ErrHashMismatch {
+			return nil, err
+		}
+		wait := backoff
+		backoff *= 2
+		var nre *NotReadyError
+		if errors.As(err, &nre) && nre.RetryAfter > 0 {
+			wait = nre.RetryAfter
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newRequestFileTestModel returns a model serving the data through the
+// given function.
+func newRequestFileTestModel(serve func(offset int64, size int32) ([]byte, error)) *TestModel {
+	m := newTestModel()
+	m.requestFn = func(_, _ string, size int32, offset int64) (RequestResponse, error) {
+		data, err := serve(offset, size)
+		if err != nil {
+			return nil, err
+		}
+		return &fakeRequestResponse{data}, nil
+	}
+	return m
+}
+
+func requestFileBlocks(file []byte, blockSize int) []BlockInfo {
+	var blocks []BlockInfo
+	for off := 0; off < len(file); off += blockSize {
+		end := off + blockSize
+		if end > len(file) {
+			end = len(file)
+		}
+		hash := sha256.Sum256(file[off:end])
+		blocks = append(blocks, BlockInfo{Offset: int64(off), Size: int32(end - off), Hash: hash[:]})
+	}
+	return blocks
+}
+
+func TestRequestFileOutOfOrder(t *testing.T) {
+	file := make([]byte, 40*100+42)
+	for i := range file {
+		file[i] = byte(i % 251)
+	}
+	blocks := requestFileBlocks(file, 100)
+
+	// Later blocks are served sooner, so responses arrive out of order.
+	m0 := newRequestFileTestModel(func(offset int64, size int32) ([]byte, error) {
+		time.Sleep(time.Duration(len(file)-int(offset)) * time.Microsecond)
+		return file[offset : offset+int64(size)], nil
+	})
+	_, c := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+	var dst bytes.Buffer
+	if err := RequestFile(context.Background(), c, "default", "foo", blocks, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst.Bytes(), file) {
+		t.Error("File data was not reassembled in order")
+	}
+}
+
+func TestRequestFileRetries(t *testing.T) {
+	file := []byte("0123456789abcdefghij")
+	blocks := requestFileBlocks(file, 5)
+
+	var mut sync.Mutex
+	served := make(map[int64]int)
+	m0 := newRequestFileTestModel(func(offset int64, size int32) ([]byte, error) {
+		mut.Lock()
+		served[offset]++
+		n := served[offset]
+		mut.Unlock()
+		switch {
+		case offset == 5 && n == 1:
+			return []byte("xxxxx"), nil // corrupt the first time
+		case offset == 10 && n == 1:
+			return nil, &NotReadyError{RetryAfter: time.Millisecond}
+		}
+		return file[offset : offset+int64(size)], nil
+	})
+	_, c := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+	var dst bytes.Buffer
+	if err := RequestFile(context.Background(), c, "default", "foo", blocks, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst.Bytes(), file) {
+		t.Errorf("Got %q, expected %q", dst.Bytes(), file)
+	}
+	mut.Lock()
+	defer mut.Unlock()
+	if served[5] != 2 || served[10] != 2 || served[0] != 1 {
+		t.Errorf("Unexpected requests per block %v", served)
+	}
+}
+
+func TestRequestFileError(t *testing.T) {
+	file := []byte("0123456789abcdefghij")
+	blocks := requestFileBlocks(file, 5)
+
+	m0 := newRequestFileTestModel(func(offset int64, size int32) ([]byte, error) {
+		if offset == 10 {
+			return nil, ErrNoSuchFile
+		}
+		return file[offset : offset+int64(size)], nil
+	})
+	_, c := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+	var dst bytes.Buffer
+	err := RequestFile(context.Background(), c, "default", "foo", blocks, &dst)
+	if !errors.Is(err, ErrNoSuchFile) {
+		t.Errorf("Got error %v, expected ErrNoSuchFile", err)
+	}
+	if dst.String() != "0123456789" {
+		t.Errorf("Got %q written before the failing block", dst.String())
+	}
+}
+
+func TestRequestFileEmpty(t *testing.T) {
+	m0 := newRequestFileTestModel(func(offset int64, size int32) ([]byte, error) {
+		t.Errorf("Unexpected request for %d bytes at %d", size, offset)
+		return nil, ErrNoSuchFile
+	})
+	_, c := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+	var dst bytes.Buffer
+	if err := RequestFile(context.Background(), c, "default", "empty", nil, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Len() != 0 {
+		t.Errorf("Wrote %d bytes for an empty file", dst.Len())
+	}
+}
+
+func TestRequestFileMerged(t *testing.T) {
+	file := make([]byte, 40*100+42)
+	for i := range file {
+		file[i] = byte(i % 251)
+	}
+	blocks := requestFileBlocks(file, 100)
+
+	var mut sync.Mutex
+	served := make(map[int64][]int32)
+	m0 := newRequestFileTestModel(func(offset int64, size int32) ([]byte, error) {
+		mut.Lock()
+		served[offset] = append(served[offset], size)
+		mut.Unlock()
+		data := file[offset : offset+int64(size)]
+		if offset == 0 && size > 100 {
+			// Corrupt the third block of the first merged request.
+			data = append([]byte(nil), data...)
+			data[250]++
+		}
+		return data, nil
+	})
+	_, c := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+	var dst bytes.Buffer
+	if err := RequestFileMerged(context.Background(), c, "default", "foo", blocks, &dst, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst.Bytes(), file) {
+		t.Error("File data was not reassembled in order")
+	}
+
+	// Ten blocks per request, the last one alone, and the corrupt block
+	// again on its own.
+	mut.Lock()
+	defer mut.Unlock()
+	expected := map[int64][]int32{0: {1000}, 200: {100}, 1000: {1000}, 2000: {1000}, 3000: {1000}, 4000: {42}}
+	if !reflect.DeepEqual(served, expected) {
+		t.Errorf("Got requests %v, expected %v", served, expected)
+	}
+}
+
+func TestRequestFileMergedTooLarge(t *testing.T) {
+	file := []byte("0123456789abcdefghij")
+	blocks := requestFileBlocks(file, 5)
+
+	var mut sync.Mutex
+	var requests int
+	m0 := newRequestFileTestModel(func(offset int64, size int32) ([]byte, error) {
+		mut.Lock()
+		requests++
+		mut.Unlock()
+		if size > 5 {
+			return nil, ErrRequestTooLarge
+		}
+		return file[offset : offset+int64(size)], nil
+	})
+	_, c := newTestConnections(t, m0, newTestModel(), CompressNever, nil, nil)
+
+	var dst bytes.Buffer
+	if err := RequestFileMerged(context.Background(), c, "default", "foo", blocks, &dst, 20); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst.Bytes(), file) {
+		t.Errorf("Got %q, expected %q", dst.Bytes(), file)
+	}
+	mut.Lock()
+	defer mut.Unlock()
+	if requests != 5 {
+		t.Errorf("Got %d requests, expected one merged and one per block", requests)
+	}
+}
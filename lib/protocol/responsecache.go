@@ -0,0 +1,170 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// A ResponseCache keeps the data of recently served requests, so that
+// requests for hot blocks are answered without asking the model. It may be
+// shared by several connections, e.g. by all connections of a device
+// serving many others.
+//
+// Only requests carrying a block hash are cached, keyed by that hash
+// together with the folder, name, offset and size. The model is expected to
+// verify the data it returns against the hash, as Syncthing's does, so an
+// entry stays valid for as long as it is requested; a changed file is
+// requested with another hash. Invalidate drops the entries of a file that
+// must not be served anymore, e.g. because it was removed or the folder
+// stopped being shared. Otherwise the least recently used entries are
+// evicted to stay within the size limit.
+type ResponseCache struct {
+	maxBytes int
+
+	mut     sync.Mutex
+	bytes   int
+	lru     *list.List // of *cacheEntry, most recently used first
+	entries map[cacheKey]*list.Element
+}
+
+type cacheKey struct {
+	folder string
+	name   string
+	offset int64
+	size   int32
+	hash   string
+}
+
+type cacheEntry struct {
+	key            cacheKey
+	data           []byte // from the BufferPool
+	incompressible bool
+}
+
+// NewResponseCache returns a cache holding at most maxBytes of response
+// data, counting the buffers it is held in.
+func NewResponseCache(maxBytes int) *ResponseCache {
+	return &ResponseCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[cacheKey]*list.Element),
+	}
+}
+
+// Invalidate drops the cached data of the given file, by name in wire
+// format.
+func (rc *ResponseCache) Invalidate(folder, name string) {
+	rc.mut.Lock()
+	defer rc.mut.Unlock()
+	for key, elem := range rc.entries {
+		if key.folder == folder && key.name == name {
+			rc.removeLocked(elem)
+		}
+	}
+}
+
+// get returns a copy of the cached data for the key, or nil if there is
+// none.
+func (rc *ResponseCache) get(key cacheKey) RequestResponse {
+	rc.mut.Lock()
+	defer rc.mut.Unlock()
+	elem, ok := rc.entries[key]
+	if !ok {
+		return nil
+	}
+	rc.lru.MoveToFront(elem)
+	entry := elem.Value.(*cacheEntry)
+	res := newCachedResponse(len(entry.data))
+	copy(res.data, entry.data)
+	res.incompressible = entry.incompressible
+	return res
+}
+
+// put caches a copy of the data, and whether it is incompressible, evicting
+// the least recently used entries as necessary to make room. The size of
+// an entry is that of the buffer holding it, which may be larger than the
+// data.
+func (rc *ResponseCache) put(key cacheKey, data []byte, incompressible bool) {
+	buf := BufferPool.Get(len(data))
+	if cap(buf) > rc.maxBytes {
+		BufferPool.Put(buf)
+		return
+	}
+	copy(buf, data)
+
+	rc.mut.Lock()
+	defer rc.mut.Unlock()
+	if _, ok := rc.entries[key]; ok {
+		BufferPool.Put(buf)
+		return
+	}
+	for rc.bytes+cap(buf) > rc.maxBytes {
+		rc.removeLocked(rc.lru.Back())
+	}
+	rc.entries[key] = rc.lru.PushFront(&cacheEntry{key: key, data: buf, incompressible: incompressible})
+	rc.bytes += cap(buf)
+}
+
+func (rc *ResponseCache) removeLocked(elem *list.Element) {
+	entry := rc.lru.Remove(elem).(*cacheEntry)
+	delete(rc.entries, entry.key)
+	rc.bytes -= cap(entry.data)
+	BufferPool.Put(entry.data)
+}
+
+// A cachedResponse is a RequestResponse served from the cache, with data
+// from the BufferPool.
+type cachedResponse struct {
+	data           []byte
+	incompressible bool
+	closed         chan struct{}
+	once           sync.Once
+}
+
+func newCachedResponse(size int) *cachedResponse {
+	return &cachedResponse{
+		data:   BufferPool.Get(size),
+		closed: make(chan struct{}),
+	}
+}
+
+func (r *cachedResponse) Data() []byte {
+	return r.data
+}
+
+func (r *cachedResponse) Close() {
+	r.once.Do(func() {
+		BufferPool.Put(r.data)
+		close(r.closed)
+	})
+}
+
+func (r *cachedResponse) Wait() {
+	<-r.closed
+}
+
+func (r *cachedResponse) Incompressible() bool {
+	return r.incompressible
+}
+
+// cachedModelRequest answers the request from the response cache, if
+// there is one and it has the data, or otherwise passes it to the model
+// and caches the data returned.
+func (c *rawConnection) cachedModelRequest(req Request, deadline time.Time) (RequestResponse, error) {
+	if c.responseCache == nil || len(req.Hash) == 0 {
+		return c.modelRequestWithRetries(req, deadline)
+	}
+	key := cacheKey{req.Folder, req.Name, req.Offset, req.Size, string(req.Hash)}
+	if res := c.responseCache.get(key); res != nil {
+		l.Debugf("Request(%v, %v, %q, %d, %d) served from cache", c.id, req.Folder, req.Name, req.Offset, req.Size)
+		return res, nil
+	}
+	res, err := c.modelRequestWithRetries(req, deadline)
+	if err == nil {
+		c.responseCache.put(key, res.Data(), isIncompressible(res))
+	}
+	return res, err
+}
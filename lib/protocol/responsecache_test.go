@@ -0,0 +1,85 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestResponseCache(t *testing.T) {
+	var requests int32
+	m0 := newTestModel()
+	m0.requestFn = func(folder, name string, size int32, offset int64) (RequestResponse, error) {
+		atomic.AddInt32(&requests, 1)
+		return &fakeRequestResponse{[]byte(name)}, nil
+	}
+	cache := NewResponseCache(4 * MinBlockSize)
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, []Option{WithResponseCache(cache)}, nil)
+
+	request := func(name string, hash []byte, expRequests int32) {
+		t.Helper()
+		data, err := c1.Request(context.Background(), "default", name, 0, len(name), hash, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != name {
+			t.Errorf("Got %q, expected %q", data, name)
+		}
+		if n := atomic.LoadInt32(&requests); n != expRequests {
+			t.Errorf("Model got %d requests, expected %d", n, expRequests)
+		}
+	}
+
+	request("foo", []byte("hash1"), 1)
+	request("foo", []byte("hash1"), 1) // from the cache
+	request("foo", []byte("hash2"), 2) // another version of the file
+	request("bar", nil, 3)             // no hash, not cached
+	request("bar", nil, 4)
+
+	cache.Invalidate("default", "foo")
+	request("foo", []byte("hash1"), 5)
+	request("foo", []byte("hash1"), 5)
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	// Buffers of the largest block size are never larger than requested.
+	cache := NewResponseCache(2 * MaxBlockSize)
+	data := make([]byte, MaxBlockSize)
+	key := func(name string) cacheKey {
+		return cacheKey{folder: "default", name: name, size: MaxBlockSize, hash: name}
+	}
+	cached := func(name string) bool {
+		res := cache.get(key(name))
+		if res == nil {
+			return false
+		}
+		res.Close()
+		return true
+	}
+
+	cache.put(key("a"), data, false)
+	cache.put(key("b"), data, false)
+	if !cached("a") { // now used more recently than b
+		t.Fatal("a not cached")
+	}
+	cache.put(key("c"), data, false)
+
+	if cached("b") {
+		t.Error("Least recently used b wasn't evicted")
+	}
+	if !cached("a") || !cached("c") {
+		t.Error("Recently used entries were evicted")
+	}
+	if cache.bytes > cache.maxBytes {
+		t.Errorf("Cache holds %d bytes, more than %d", cache.bytes, cache.maxBytes)
+	}
+
+	// Data larger than the cache is not cached.
+	small := NewResponseCache(MinBlockSize)
+	small.put(key("a"), data, false)
+	if small.lru.Len() != 0 {
+		t.Error("Data larger than the cache was cached")
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"sync"
+)
+
+// requestScheduler runs the handling of incoming requests, at most max at
+// a time (or without limit when max is zero). Queued requests are started
+// in order of priority, and in order of arrival within a priority.
+type requestScheduler struct {
+	mut     sync.Mutex
+	max     int
+	running int
+	queues  [numPriorityRanks][]func()
+}
+
+const numPriorityRanks = 3
+
+// rank orders priorities, with higher ranks being handled first.
+func (p RequestPriority) rank() int {
+	switch p {
+	case PriorityLow:
+		return 0
+	case PriorityHigh:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func (s *requestScheduler) schedule(prio RequestPriority, fn func()) {
+	s.mut.Lock()
+	if s.max > 0 && s.running >= s.max {
+		rank := prio.rank()
+		s.queues[rank] = append(s.queues[rank], fn)
+		s.mut.Unlock()
+		return
+	}
+	s.running++
+	s.mut.Unlock()
+	go s.run(fn)
+}
+
+// run runs fn, and then queued functions for as long as there are any.
+func (s *requestScheduler) run(fn func()) {
+	for fn != nil {
+		fn()
+		s.mut.Lock()
+		fn = s.nextLocked()
+		if fn == nil {
+			s.running--
+		}
+		s.mut.Unlock()
+	}
+}
+
+func (s *requestScheduler) nextLocked() func() {
+	if s.max > 0 && s.running > s.max {
+		return nil
+	}
+	return s.popLocked()
+}
+
+// popLocked returns the first queued function of the highest priority, or
+// nil if there is none.
+func (s *requestScheduler) popLocked() func() {
+	for rank := len(s.queues) - 1; rank >= 0; rank-- {
+		if q := s.queues[rank]; len(q) > 0 {
+			fn := q[0]
+			q[0] = nil
+			s.queues[rank] = q[1:]
+			return fn
+		}
+	}
+	return nil
+}
+
+// setMax changes the limit, with zero meaning no limit. Queued functions
+// are started right away when it is raised. When it is lowered, running
+// functions carry on, but no more are started until fewer than max are
+// running.
+func (s *requestScheduler) setMax(max int) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.max = max
+	for s.max == 0 || s.running < s.max {
+		fn := s.popLocked()
+		if fn == nil {
+			return
+		}
+		s.running++
+		go s.run(fn)
+	}
+}
+
+func (s *requestScheduler) queued() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	n := 0
+	for _, q := range s.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// SetMaxConcurrentRequests changes the limit on requests from the other
+// side handled concurrently, as set by WithMaxConcurrentRequests, without
+// reconnecting. Queued requests are started right away when the limit is
+// raised. When it is lowered, requests being handled carry on, and no more
+// are started until fewer than n are being handled. A limit of zero or
+// less removes the limit.
+func (c *rawConnection) SetMaxConcurrentRequests(n int) {
+	if n < 0 {
+		n = 0
+	}
+	c.requests.setMax(n)
+}
+
+type requestPriorityKey struct{}
+
+// ContextWithRequestPriority returns a context that makes requests made
+// with it carry the given priority. The priority is only sent to devices
+// that support it, and is honored by the other side when it limits the
+// number of requests handled concurrently.
+func ContextWithRequestPriority(ctx context.Context, prio RequestPriority) context.Context {
+	return context.WithValue(ctx, requestPriorityKey{}, prio)
+}
+
+func requestPriority(ctx context.Context) RequestPriority {
+	prio, _ := ctx.Value(requestPriorityKey{}).(RequestPriority)
+	return prio
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+// Small messages, such as most requests and the responses to metadata reads,
+// are many and cheap to copy, so for them the round trip through the
+// BufferPool costs more than it saves: a pooled buffer is at least
+// MinBlockSize bytes, and each Put allocates. An Encoder or Decoder with a
+// small message size instead keeps a buffer of its own that frames and
+// messages of up to that size are assembled or read in. Larger messages are
+// handled as before.
+
+// DefaultSmallMessageSize is the small message size of connections created
+// without WithSmallMessageSize.
+const DefaultSmallMessageSize = 4 << KiB
+
+// maxSmallMessageSize is the largest small message size. It is well below
+// MinBlockSize so that no buffer of the BufferPool can be mistaken for one
+// of our own.
+const maxSmallMessageSize = MinBlockSize / 2
+
+// frameOverhead is room for the lengths and header of a frame, so that a
+// small message fits in one along with them.
+const frameOverhead = 64
+
+// SetSmallMessageSize makes the Encoder assemble frames of messages of up
+// to size bytes in a buffer of its own rather than one from the BufferPool.
+// Zero, the default, disables this; sizes above 64 KiB are reduced to that.
+func (e *Encoder) SetSmallMessageSize(size int) {
+	e.small = newSmallBuffer(size)
+}
+
+// SetSmallMessageSize makes the Decoder read messages of up to size bytes
+// into a buffer of its own rather than one from the BufferPool. Zero, the
+// default, disables this; sizes above 64 KiB are reduced to that.
+func (d *Decoder) SetSmallMessageSize(size int) {
+	d.small = newSmallBuffer(size)
+}
+
+func newSmallBuffer(size int) []byte {
+	if size <= 0 {
+		return nil
+	}
+	if size > maxSmallMessageSize {
+		size = maxSmallMessageSize
+	}
+	return make([]byte, size+frameOverhead)
+}
+
+// getBuffer returns a buffer of the given size, which is the small buffer
+// if it fits and one from the BufferPool otherwise.
+func getBuffer(small []byte, size int) []byte {
+	if size <= cap(small) {
+		return small[:size]
+	}
+	return BufferPool.Get(size)
+}
+
+// upgradeBuffer is like BufferPool.Upgrade for a buffer returned by
+// getBuffer, except that the contents aren't kept.
+func upgradeBuffer(small, buf []byte, size int) []byte {
+	if size <= cap(buf) {
+		return buf[:size]
+	}
+	putBuffer(buf)
+	return getBuffer(small, size)
+}
+
+// putBuffer gives back a buffer returned by getBuffer.
+func putBuffer(buf []byte) {
+	if cap(buf) > maxSmallMessageSize+frameOverhead {
+		// Not a small buffer, all of which are smaller than any pooled
+		// one.
+		BufferPool.Put(buf)
+	}
+}
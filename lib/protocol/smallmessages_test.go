@@ -0,0 +1,76 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSmallMessages(t *testing.T) {
+	// Small and large messages, written whole and interleaved in frames,
+	// come out as they went in, and decoded messages don't share the
+	// buffers they were read into.
+	small := &Response{ID: 1, Data: bytes.Repeat([]byte{1}, 256)}
+	other := &Response{ID: 2, Data: bytes.Repeat([]byte{2}, 256)}
+	ping := &Ping{ID: 3}
+	large := &Response{ID: 4, Data: bytes.Repeat([]byte{4}, 3*maxFrameSize)}
+
+	for _, comp := range []Compression{CompressNever, CompressAlways} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf, comp)
+		enc.SetSmallMessageSize(DefaultSmallMessageSize)
+		dec := NewDecoder(&buf)
+		dec.SetStreams(true)
+		dec.SetSmallMessageSize(DefaultSmallMessageSize)
+
+		for _, msg := range []Message{small, large, ping} {
+			if err := enc.Encode(msg); err != nil {
+				t.Fatal(err)
+			}
+		}
+		fLarge, err := enc.frames(large, streamData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fSmall, err := enc.frames(other, streamControl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Messages are decoded in the order their last frames are
+		// written, and the large one might compress into one frame.
+		expected := []Message{small, large, ping, other, large}
+		done, err := enc.writeFrame(fLarge)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done {
+			expected = []Message{small, large, ping, large, other}
+		}
+		if _, err := enc.writeFrame(fSmall); err != nil {
+			t.Fatal(err)
+		}
+		for !done {
+			if done, err = enc.writeFrame(fLarge); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var decoded []Message
+		for _, exp := range expected {
+			msg, err := dec.Decode()
+			if err != nil {
+				t.Fatalf("%v: %v", comp, err)
+			}
+			got, _ := msg.Marshal()
+			want, _ := exp.Marshal()
+			if !bytes.Equal(got, want) {
+				t.Errorf("%v: decoded %v, expected %v", comp, typeOf(msg), typeOf(exp))
+			}
+			decoded = append(decoded, msg)
+		}
+		if !bytes.Equal(decoded[0].(*Response).Data, small.Data) {
+			t.Errorf("%v: first small response changed by decoding the others", comp)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"io"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// tuneConn calls the socket tuner, if any, on the underlying connection, if
+// either the reader or the writer is one. An error is kept for Start to
+// close the connection with.
+func (c *rawConnection) tuneConn(reader io.Reader, writer io.Writer) {
+	if c.socketTuner == nil {
+		return
+	}
+	conn, ok := reader.(net.Conn)
+	if !ok {
+		if conn, ok = writer.(net.Conn); !ok {
+			return
+		}
+	}
+	if err := c.socketTuner(conn); err != nil {
+		c.socketTunerErr = errors.Wrap(err, "setting socket options")
+	}
+}
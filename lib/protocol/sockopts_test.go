@@ -0,0 +1,57 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/testutils"
+)
+
+func TestSocketOptions(t *testing.T) {
+	c0, c1 := net.Pipe()
+	defer c0.Close()
+	defer c1.Close()
+
+	var calls int
+	var tuned net.Conn
+	tuner := func(conn net.Conn) error {
+		calls++
+		tuned = conn
+		return nil
+	}
+	c := NewConnection(c1ID, c0, c0, newTestModel(), "c", CompressNever, WithSocketOptions(tuner))
+	if calls != 1 || tuned != c0 {
+		t.Errorf("Expected one call with the connection, got %d calls with %v", calls, tuned)
+	}
+	if c.Closed() {
+		t.Error("Unexpected closed connection")
+	}
+
+	calls = 0
+	NewConnection(c1ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, newTestModel(), "c", CompressNever, WithSocketOptions(tuner))
+	if calls != 0 {
+		t.Errorf("Expected no calls for other transports, got %d", calls)
+	}
+}
+
+func TestSocketOptionsError(t *testing.T) {
+	c0, c1 := net.Pipe()
+	defer c0.Close()
+	defer c1.Close()
+
+	m := newTestModel()
+	c := NewConnection(c1ID, c0, c0, m, "c", CompressNever, WithSocketOptions(func(net.Conn) error {
+		return errors.New("no such option")
+	})).(wireFormatConnection)
+	c.Start()
+	if err := m.closedError(); err == nil || !strings.Contains(err.Error(), "no such option") {
+		t.Errorf("Expected close with the tuner error, got %v", err)
+	}
+	if reason := c.CloseReason(); reason != CloseReasonLocalClose {
+		t.Errorf("Expected %v, got %v", CloseReasonLocalClose, reason)
+	}
+}
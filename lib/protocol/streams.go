@@ -0,0 +1,144 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// Messages are multiplexed on a few streams, so that a large message on one
+// doesn't hold up those on the others: with CapabilityStreams, messages on
+// the index and data streams are split into frames of at most
+// maxFrameSize bytes, and frames of different streams are interleaved. The
+// control stream has priority over the others, which take turns frame by
+// frame. Each stream has at most one message being written at a time;
+// senders on a busy stream wait for it without holding up the others.
+//
+// Without CapabilityStreams on the other side, all messages are written
+// whole and carry the default stream zero, as before, though the writer
+// still prefers the control stream between messages.
+const (
+	streamControl int32 = iota // everything not on another stream; the default
+	streamIndex                // index data and download progress
+	streamData                 // block data, i.e. responses and pushes
+	numStreams
+)
+
+// maxFrameSize is the largest number of message bytes in a frame of a
+// message split into frames.
+const maxFrameSize = 64 << 10
+
+// streamOf returns the stream the message goes on.
+func streamOf(msg Message) int32 {
+	switch msg.(type) {
+	case *Index, *IndexUpdate, *IndexSummary, *DownloadProgress:
+		return streamIndex
+	case *Response, *Push, *RangeResponse:
+		return streamData
+	}
+	return streamControl
+}
+
+// A frameWriter holds a marshalled message that is written in frames of at
+// most maxFrameSize bytes.
+type frameWriter struct {
+	hdr     Header
+	payload []byte // from the BufferPool or the stream's own, possibly compressed
+	off     int    // the part of payload written so far
+}
+
+// frames marshals and, depending on the compression setting, compresses the
+// message, for writing in frames on the given stream using writeFrame. A
+// small message is kept in a buffer of the stream's own until written, as
+// each stream has at most one message being written at a time.
+func (e *Encoder) frames(msg Message, stream int32) (*frameWriter, error) {
+	size := msg.ProtoSize()
+	var buf []byte
+	if e.small != nil && size <= len(e.small) && stream >= 0 && stream < numStreams {
+		if e.payloads[stream] == nil {
+			e.payloads[stream] = make([]byte, len(e.small))
+		}
+		buf = e.payloads[stream][:size]
+	} else {
+		buf = BufferPool.Get(size)
+	}
+	if _, err := msg.MarshalTo(buf); err != nil {
+		putBuffer(buf)
+		return nil, errors.Wrap(err, "marshalling message")
+	}
+
+	hdr := Header{
+		Type:   typeOf(msg),
+		Stream: stream,
+	}
+	if e.checksums {
+		hdr.Checksum = checksum(buf)
+	}
+	if e.shouldCompress(msg) {
+		compressed, err := lz4Compress(buf)
+		putBuffer(buf)
+		if err != nil {
+			return nil, errors.Wrap(err, "compressing message")
+		}
+		buf = compressed
+		hdr.Compression = MessageCompressionLZ4
+	}
+	return &frameWriter{hdr: hdr, payload: buf}, nil
+}
+
+// writeFrame writes the next frame of the message, in one call to the
+// underlying writer, returning true when it was the last one.
+func (e *Encoder) writeFrame(f *frameWriter) (bool, error) {
+	chunk := f.payload[f.off:]
+	hdr := f.hdr
+	if len(chunk) > maxFrameSize {
+		chunk = chunk[:maxFrameSize]
+		hdr.More = true
+		hdr.Checksum = nil // goes with the last frame
+	}
+	hdrSize := hdr.ProtoSize()
+
+	totSize := 2 + hdrSize + 4 + len(chunk)
+	buf := getBuffer(e.small, totSize)
+	binary.BigEndian.PutUint16(buf, uint16(hdrSize))
+	if _, err := hdr.MarshalTo(buf[2:]); err != nil {
+		putBuffer(buf)
+		return false, errors.Wrap(err, "marshalling header")
+	}
+	binary.BigEndian.PutUint32(buf[2+hdrSize:], uint32(len(chunk)))
+	copy(buf[2+hdrSize+4:], chunk)
+
+	n, err := e.w.Write(buf)
+	putBuffer(buf)
+	if shouldDebug() {
+		l.Debugf("wrote %d bytes on the wire (frame of %d bytes on stream %d, more=%v), err=%v", n, len(chunk), hdr.Stream, hdr.More, err)
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "writing frame")
+	}
+
+	f.off += len(chunk)
+	if hdr.More {
+		return false, nil
+	}
+	f.release()
+	return true, nil
+}
+
+// release returns the payload buffer to the pool, if it hasn't been
+// already.
+func (f *frameWriter) release() {
+	if f.payload != nil {
+		putBuffer(f.payload)
+		f.payload = nil
+	}
+}
+
+// A partialMessage is what has been read of a message split into frames.
+type partialMessage struct {
+	msgType MessageType
+	data    []byte
+	size    int // bytes on the wire
+}
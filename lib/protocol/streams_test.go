@@ -0,0 +1,304 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/rand"
+)
+
+func TestFramesInterleaved(t *testing.T) {
+	// Random data doesn't compress, so every frame is full but the last.
+	data := make([]byte, 5*maxFrameSize/2)
+	if _, err := io.ReadFull(rand.Reader, data); err != nil {
+		t.Fatal(err)
+	}
+	resp := &Response{ID: 1, Data: data}
+	idx := &Index{Folder: "default", Files: []FileInfo{{Name: "foo", Size: 128, Blocks: []BlockInfo{{Size: 128, Hash: bytes.Repeat([]byte{1}, 32)}}}}}
+	for i := 0; i < 5000; i++ {
+		idx.Files = append(idx.Files, idx.Files[0])
+	}
+	ping := &Ping{}
+
+	for _, checksums := range []bool{false, true} {
+		for _, comp := range []Compression{CompressNever, CompressAlways} {
+			var buf bytes.Buffer
+			enc := NewEncoder(&buf, comp)
+			enc.SetChecksums(checksums)
+			respFrames, err := enc.frames(resp, streamData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			idxFrames, err := enc.frames(idx, streamIndex)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Alternate frames of both, with a ping in between, until both
+			// are done.
+			var order []Message
+			respDone, idxDone := false, false
+			for !respDone || !idxDone {
+				if !respDone {
+					if respDone, err = enc.writeFrame(respFrames); err != nil {
+						t.Fatal(err)
+					} else if respDone {
+						order = append(order, resp)
+					}
+				}
+				if !idxDone {
+					if idxDone, err = enc.writeFrame(idxFrames); err != nil {
+						t.Fatal(err)
+					} else if idxDone {
+						order = append(order, idx)
+					}
+				}
+				if err := enc.Encode(ping); err != nil {
+					t.Fatal(err)
+				}
+				order = append(order, ping)
+			}
+			if len(order) < 5 {
+				t.Fatalf("%v/%v: messages weren't split, got %d", comp, checksums, len(order))
+			}
+
+			total := buf.Len()
+			var decoded int
+			dec := NewDecoder(&buf)
+			dec.SetStreams(true)
+			for i, msg := range order {
+				res, err := dec.Decode()
+				if err != nil {
+					t.Fatalf("%v/%v: message %d: %v", comp, checksums, i, err)
+				}
+				decoded += dec.size
+				exp, _ := msg.Marshal()
+				got, _ := res.Marshal()
+				if typeOf(res) != typeOf(msg) || !bytes.Equal(got, exp) {
+					t.Errorf("%v/%v: message %d: decoded %v, expected %v", comp, checksums, i, typeOf(res), typeOf(msg))
+				}
+			}
+			if decoded != total {
+				t.Errorf("%v/%v: decoded sizes add up to %d, expected %d", comp, checksums, decoded, total)
+			}
+		}
+	}
+}
+
+func TestFramesMismatchedType(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, CompressNever)
+	frames, err := enc.frames(&Response{ID: 1, Data: make([]byte, 2*maxFrameSize)}, streamData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done, err := enc.writeFrame(frames); err != nil || done {
+		t.Fatal(done, err)
+	}
+	// A frame of another type on the same stream.
+	other, err := enc.frames(&Push{ID: 2}, streamData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enc.writeFrame(other); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := NewDecoder(&buf)
+	dec.SetStreams(true)
+	if _, err := dec.Decode(); err == nil {
+		t.Error("Expected an error for a frame continuing a message of another type")
+	}
+}
+
+func TestLargeResponseDoesntDelayPings(t *testing.T) {
+	for _, streams := range []bool{true, false} {
+		ar, aw := io.Pipe()
+		br, bw := io.Pipe()
+
+		const (
+			size  = 32 * maxFrameSize
+			delay = 5 * time.Millisecond
+		)
+		var served int32
+		m0 := newTestModel()
+		m0.requestFn = func(string, string, int32, int64) (RequestResponse, error) {
+			atomic.StoreInt32(&served, 1)
+			return &fakeRequestResponse{make([]byte, size)}, nil
+		}
+		// Every frame, or whole message, is written in one call, taking
+		// at least the delay.
+		c0 := NewConnection(c1ID, ar, &slowWriter{bw, delay}, m0, "c0", CompressNever, WithoutPinger()).(wireFormatConnection)
+		c1 := NewConnection(c0ID, br, aw, newTestModel(), "c1", CompressNever, WithoutPinger()).(wireFormatConnection)
+		if !streams {
+			c1.capabilities &^= CapabilityStreams
+		}
+		c0.Start()
+		c1.Start()
+		c0.ClusterConfig(ClusterConfig{})
+		c1.ClusterConfig(ClusterConfig{})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		for _, c := range []wireFormatConnection{c0, c1} {
+			if err := c.WaitHandshake(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		responded := make(chan struct{})
+		go func() {
+			defer close(responded)
+			data, err := c1.Request(ctx, "default", "foo", 0, size, nil, 0, false)
+			if err != nil || len(data) != size {
+				t.Errorf("Request returned %d bytes, %v", len(data), err)
+			}
+		}()
+		for atomic.LoadInt32(&served) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		// Let the response get going.
+		time.Sleep(4 * delay)
+
+		if _, err := c0.Ping(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if streams {
+			select {
+			case <-responded:
+				t.Error("Ping arrived after the response, expected it in between")
+			default:
+			}
+		}
+		// Without streams on the other side, the response is written
+		// whole, but still arrives.
+		<-responded
+
+		cancel()
+		ar.Close()
+		br.Close()
+	}
+}
+
+// holdingWriter is like a buffered writer, holding what is written, once
+// holding, until flushed.
+type holdingWriter struct {
+	w       io.Writer
+	holding int32
+	held    int64
+	buf     bytes.Buffer
+}
+
+func (w *holdingWriter) Write(bs []byte) (int, error) {
+	if atomic.LoadInt32(&w.holding) == 0 {
+		return w.w.Write(bs)
+	}
+	atomic.AddInt64(&w.held, int64(len(bs)))
+	return w.buf.Write(bs)
+}
+
+func (w *holdingWriter) Flush() error {
+	atomic.StoreInt64(&w.held, 0)
+	_, err := w.w.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+func TestPongFlushedDuringIndex(t *testing.T) {
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	defer ar.Close()
+	defer br.Close()
+
+	hw := &holdingWriter{w: bw}
+	c0 := NewConnection(c1ID, ar, hw, newTestModel(), "c0", CompressNever, WithoutPinger(), WithWriteBufferSize(1<<20)).(wireFormatConnection)
+	c1 := NewConnection(c0ID, br, aw, newTestModel(), "c1", CompressNever, WithoutPinger()).(wireFormatConnection)
+	c0.Start()
+	c1.Start()
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, c := range []wireFormatConnection{c0, c1} {
+		if err := c.WaitHandshake(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// From now on nothing goes out without a flush, so the index is held
+	// back, and the pong only arrives if it is flushed as it is written.
+	atomic.StoreInt32(&hw.holding, 1)
+	files := make([]FileInfo, 20000)
+	for i := range files {
+		files[i] = FileInfo{Name: fmt.Sprintf("file%d", i), Size: 1, Blocks: []BlockInfo{{Size: 1, Hash: make([]byte, 32)}}}
+	}
+	go c0.Index(ctx, "default", files)
+	for atomic.LoadInt64(&hw.held) < maxFrameSize {
+		time.Sleep(time.Millisecond)
+	}
+
+	latency, err := c1.Ping(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Pong latency %v during index", latency)
+}
+
+// writeRawFrame writes a frame with the given header and message bytes.
+func writeRawFrame(buf *bytes.Buffer, hdr Header, data []byte) {
+	hdrBs, _ := hdr.Marshal()
+	bs := make([]byte, 2)
+	binary.BigEndian.PutUint16(bs, uint16(len(hdrBs)))
+	buf.Write(bs)
+	buf.Write(hdrBs)
+	bs = make([]byte, 4)
+	binary.BigEndian.PutUint32(bs, uint32(len(data)))
+	buf.Write(bs)
+	buf.Write(data)
+}
+
+func TestFramesRejected(t *testing.T) {
+	frame := make([]byte, maxFrameSize)
+	cases := []struct {
+		name    string
+		streams bool
+		frames  []Header
+		sizes   []int
+	}{
+		{"frame without streams", false, []Header{{Type: messageTypeResponse, Stream: streamData, More: true}}, []int{100}},
+		{"unknown stream", true, []Header{{Type: messageTypeResponse, Stream: numStreams, More: true}}, []int{100}},
+		{"negative stream", true, []Header{{Type: messageTypeResponse, Stream: -1}}, []int{100}},
+		{"long frame", true, []Header{{Type: messageTypeResponse, Stream: streamData, More: true}}, []int{maxFrameSize + 1}},
+		{"long last frame", true, []Header{{Type: messageTypeResponse, Stream: streamData, More: true}, {Type: messageTypeResponse, Stream: streamData}}, []int{100, maxFrameSize + 1}},
+		{"too much held", true, []Header{
+			{Type: messageTypeIndex, Stream: streamIndex, More: true},
+			{Type: messageTypeResponse, Stream: streamData, More: true},
+			{Type: messageTypeIndex, Stream: streamIndex, More: true},
+		}, []int{maxFrameSize, maxFrameSize, 1}},
+	}
+	for _, tc := range cases {
+		var buf bytes.Buffer
+		for i, hdr := range tc.frames {
+			if tc.sizes[i] > len(frame) {
+				writeRawFrame(&buf, hdr, make([]byte, tc.sizes[i]))
+			} else {
+				writeRawFrame(&buf, hdr, frame[:tc.sizes[i]])
+			}
+		}
+		dec := NewDecoder(&buf)
+		dec.SetStreams(tc.streams)
+		dec.maxPartialLen = 2 * maxFrameSize
+		if _, err := dec.Decode(); err == nil || errors.Is(err, io.EOF) {
+			t.Errorf("%s: got %v, expected a protocol error", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+// Direction tells whether a message was read or written.
+type Direction int
+
+const (
+	DirectionIn Direction = iota
+	DirectionOut
+)
+
+func (d Direction) String() string {
+	switch d {
+	case DirectionIn:
+		return "in"
+	case DirectionOut:
+		return "out"
+	default:
+		return "unknown"
+	}
+}
+
+// A Tap is called for every message read or written, with the type of the
+// message, the ID for messages that have one (or zero), and the number of
+// bytes on the wire including framing. It is called inline from the reader
+// and writer loops, so it must be quick and must not block; any slow work
+// belongs in a goroutine of its own.
+type Tap func(dir Direction, msgType MessageType, msgID int32, size int)
+
+// messageID returns the ID of messages that have one, or zero.
+func messageID(msg Message) int32 {
+	switch msg := msg.(type) {
+	case *Request:
+		return msg.ID
+	case *Response:
+		return msg.ID
+	case *Push:
+		return msg.ID
+	case *Ping:
+		return msg.ID
+	case *Pong:
+		return msg.ID
+	case *QuotaRequest:
+		return msg.ID
+	case *QuotaResponse:
+		return msg.ID
+	case *ListingRequest:
+		return msg.ID
+	case *ListingResponse:
+		return msg.ID
+	case *FileInfoRequest:
+		return msg.ID
+	case *FileInfoResponse:
+		return msg.ID
+	case *RangeRequest:
+		return msg.ID
+	case *RangeResponse:
+		return msg.ID
+	}
+	return 0
+}
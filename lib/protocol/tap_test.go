@@ -0,0 +1,93 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type tapRecord struct {
+	dir     Direction
+	msgType MessageType
+	msgID   int32
+	size    int
+}
+
+type tapRecorder struct {
+	mut     sync.Mutex
+	records []tapRecord
+}
+
+func (r *tapRecorder) tap(dir Direction, msgType MessageType, msgID int32, size int) {
+	r.mut.Lock()
+	r.records = append(r.records, tapRecord{dir, msgType, msgID, size})
+	r.mut.Unlock()
+}
+
+// find returns the first record of the given direction and type, waiting
+// a while for it as the other side may see a message before our tap does.
+func (r *tapRecorder) find(dir Direction, msgType MessageType) (tapRecord, bool) {
+	deadline := time.Now().Add(time.Second)
+	for {
+		r.mut.Lock()
+		for _, rec := range r.records {
+			if rec.dir == dir && rec.msgType == msgType {
+				r.mut.Unlock()
+				return rec, true
+			}
+		}
+		r.mut.Unlock()
+		if time.Now().After(deadline) {
+			return tapRecord{}, false
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTap(t *testing.T) {
+	var r0, r1 tapRecorder
+	m0 := newTestModel()
+	m0.data = []byte("some data")
+	_, c1 := newTestConnections(t, m0, newTestModel(), CompressNever, []Option{WithTap(r0.tap)}, []Option{WithTap(r1.tap)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c1.Request(ctx, "default", "foo", 0, len(m0.data), nil, 0, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, msgType := range []MessageType{messageTypeClusterConfig, messageTypeRequest, messageTypeResponse} {
+		sender, receiver := &r1, &r0
+		if msgType == messageTypeResponse {
+			sender, receiver = &r0, &r1
+		}
+		out, ok := sender.find(DirectionOut, msgType)
+		if !ok {
+			t.Errorf("%v not tapped when written", msgType)
+			continue
+		}
+		in, ok := receiver.find(DirectionIn, msgType)
+		if !ok {
+			t.Errorf("%v not tapped when read", msgType)
+			continue
+		}
+		if out.size == 0 || in.size != out.size {
+			t.Errorf("%v: wrote %d bytes, read %d", msgType, out.size, in.size)
+		}
+		if in.msgID != out.msgID {
+			t.Errorf("%v: wrote ID %d, read %d", msgType, out.msgID, in.msgID)
+		}
+	}
+
+	req, _ := r1.find(DirectionOut, messageTypeRequest)
+	resp, _ := r1.find(DirectionIn, messageTypeResponse)
+	if req.msgID != resp.msgID {
+		t.Errorf("Request ID %d, response ID %d", req.msgID, resp.msgID)
+	}
+	if resp.size < len(m0.data) {
+		t.Errorf("Response of %d bytes can't hold %d bytes of data", resp.size, len(m0.data))
+	}
+}
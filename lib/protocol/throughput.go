@@ -0,0 +1,73 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"sync"
+	"time"
+)
+
+// throughputInterval is how often the smoothed throughput is updated.
+const throughputInterval = time.Second
+
+// throughputMeter keeps an exponentially weighted moving average of the
+// incoming and outgoing byte rates, updated at regular intervals.
+type throughputMeter struct {
+	alpha   float64 // weight of the newest sample
+	mut     sync.Mutex
+	started bool
+	lastIn  int64
+	lastOut int64
+	inRate  float64
+	outRate float64
+}
+
+// update adds a sample based on the byte totals, elapsed time after the
+// previous one.
+func (m *throughputMeter) update(inTotal, outTotal int64, elapsed time.Duration) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if !m.started {
+		// The first sample only sets the baseline.
+		m.started = true
+		m.lastIn, m.lastOut = inTotal, outTotal
+		return
+	}
+	if elapsed <= 0 {
+		return
+	}
+
+	secs := elapsed.Seconds()
+	inRate := float64(inTotal-m.lastIn) / secs
+	outRate := float64(outTotal-m.lastOut) / secs
+	m.lastIn, m.lastOut = inTotal, outTotal
+
+	m.inRate += m.alpha * (inRate - m.inRate)
+	m.outRate += m.alpha * (outRate - m.outRate)
+}
+
+func (m *throughputMeter) rates() (in, out float64) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return m.inRate, m.outRate
+}
+
+// throughputUpdater periodically updates the throughput meter, until the
+// connection is closed.
+func (c *rawConnection) throughputUpdater() {
+	ticker := time.NewTicker(throughputInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	c.throughput.update(c.cr.Tot(), c.cw.Tot(), 0)
+	for {
+		select {
+		case now := <-ticker.C:
+			c.throughput.update(c.cr.Tot(), c.cw.Tot(), now.Sub(last))
+			last = now
+		case <-c.closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/testutils"
+)
+
+func TestThroughputMeter(t *testing.T) {
+	m := &throughputMeter{alpha: 0.5}
+
+	// The first sample is just the baseline
+	m.update(1000, 2000, 0)
+	if in, out := m.rates(); in != 0 || out != 0 {
+		t.Fatalf("Unexpected rates %v, %v after first sample", in, out)
+	}
+
+	cases := []struct {
+		in, out         int64
+		elapsed         time.Duration
+		inRate, outRate float64
+	}{
+		// 1000 B/s in and 2000 B/s out, half of it is taken
+		{2000, 4000, time.Second, 500, 1000},
+		// Same rate again, over two seconds, approaches it
+		{4000, 8000, 2 * time.Second, 750, 1500},
+		// Idle
+		{4000, 8000, time.Second, 375, 750},
+	}
+	for i, tc := range cases {
+		m.update(tc.in, tc.out, tc.elapsed)
+		in, out := m.rates()
+		if math.Abs(in-tc.inRate) > 1e-9 || math.Abs(out-tc.outRate) > 1e-9 {
+			t.Errorf("%d: got rates %v, %v, expected %v, %v", i, in, out, tc.inRate, tc.outRate)
+		}
+	}
+}
+
+func TestThroughputSmoothingOption(t *testing.T) {
+	for _, alpha := range []float64{-1, 0, 1.5} {
+		c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, newTestModel(), "name", CompressAlways, WithThroughputSmoothing(alpha)).(wireFormatConnection).rawConnection
+		if c.throughput != nil {
+			t.Errorf("Alpha %v should be ignored", alpha)
+		}
+	}
+	c := NewConnection(c0ID, &testutils.BlockingRW{}, &testutils.NoopRW{}, newTestModel(), "name", CompressAlways, WithThroughputSmoothing(0.2)).(wireFormatConnection).rawConnection
+	if c.throughput == nil || c.throughput.alpha != 0.2 {
+		t.Error("Throughput smoothing should be enabled")
+	}
+}
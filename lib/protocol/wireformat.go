@@ -10,7 +10,7 @@ import (
 )
 
 type wireFormatConnection struct {
-	Connection
+	*rawConnection
 }
 
 func (c wireFormatConnection) Index(ctx context.Context, folder string, fs []FileInfo) error {
@@ -21,7 +21,7 @@ func (c wireFormatConnection) Index(ctx context.Context, folder string, fs []Fil
 		myFs[i].Name = norm.NFC.String(filepath.ToSlash(myFs[i].Name))
 	}
 
-	return c.Connection.Index(ctx, folder, myFs)
+	return c.rawConnection.Index(ctx, folder, myFs)
 }
 
 func (c wireFormatConnection) IndexUpdate(ctx context.Context, folder string, fs []FileInfo) error {
@@ -32,10 +32,30 @@ func (c wireFormatConnection) IndexUpdate(ctx context.Context, folder string, fs
 		myFs[i].Name = norm.NFC.String(filepath.ToSlash(myFs[i].Name))
 	}
 
-	return c.Connection.IndexUpdate(ctx, folder, myFs)
+	return c.rawConnection.IndexUpdate(ctx, folder, myFs)
 }
 
 func (c wireFormatConnection) Request(ctx context.Context, folder string, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
 	name = norm.NFC.String(filepath.ToSlash(name))
-	return c.Connection.Request(ctx, folder, name, offset, size, hash, weakHash, fromTemporary)
+	return c.rawConnection.Request(ctx, folder, name, offset, size, hash, weakHash, fromTemporary)
+}
+
+func (c wireFormatConnection) Push(ctx context.Context, folder, name string, offset int64, data []byte) error {
+	name = norm.NFC.String(filepath.ToSlash(name))
+	return c.rawConnection.Push(ctx, folder, name, offset, data)
+}
+
+func (c wireFormatConnection) Listing(ctx context.Context, folder, path, after string, limit int) ([]ListingEntry, string, error) {
+	path = norm.NFC.String(filepath.ToSlash(path))
+	return c.rawConnection.Listing(ctx, folder, path, after, limit)
+}
+
+func (c wireFormatConnection) FileInfo(ctx context.Context, folder, name string) (FileInfo, error) {
+	name = norm.NFC.String(filepath.ToSlash(name))
+	return c.rawConnection.FileInfo(ctx, folder, name)
+}
+
+func (c wireFormatConnection) RequestRanges(ctx context.Context, folder, name string, ranges []Range) ([]RangeResult, error) {
+	name = norm.NFC.String(filepath.ToSlash(name))
+	return c.rawConnection.RequestRanges(ctx, folder, name, ranges)
 }
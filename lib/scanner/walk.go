@@ -609,8 +609,7 @@ func CreateFileInfo(fi fs.FileInfo, name string, filesystem fs.Filesystem) (prot
 		return f, nil
 	}
 	f.Permissions = uint32(fi.Mode() & fs.ModePerm)
-	f.ModifiedS = fi.ModTime().Unix()
-	f.ModifiedNs = int32(fi.ModTime().Nanosecond())
+	f.SetModTime(fi.ModTime())
 	if fi.IsDir() {
 		f.Type = protocol.FileInfoTypeDirectory
 		return f, nil
@@ -0,0 +1,158 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package testutils
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// Fault is a kind of failure injected by FaultyReader and FaultyWriter.
+type Fault int
+
+const (
+	// FaultNone passes all data through unchanged.
+	FaultNone Fault = iota
+	// FaultEOF ends the stream cleanly at the fault offset.
+	FaultEOF
+	// FaultTruncate ends the stream at the fault offset with an error.
+	FaultTruncate
+	// FaultCorrupt flips all bits of the byte at the fault offset.
+	FaultCorrupt
+	// FaultDelay sleeps for the configured delay once the fault offset
+	// has been reached, then passes data through unchanged.
+	FaultDelay
+)
+
+// ErrFaultInjected is returned by writes after a FaultEOF or
+// FaultTruncate fault has been triggered.
+var ErrFaultInjected = errors.New("injected fault")
+
+// faultInjector holds the state shared by FaultyReader and FaultyWriter.
+type faultInjector struct {
+	fault     Fault
+	offset    int64
+	delay     time.Duration
+	pos       int64
+	triggered bool
+	mut       sync.Mutex
+}
+
+// limit returns how many of the next n bytes may be passed through before
+// the fault offset is reached, and whether the fault applies at the current
+// position.
+func (f *faultInjector) limit(n int) (int, bool) {
+	if f.fault == FaultNone || f.triggered || f.pos+int64(n) <= f.offset {
+		return n, false
+	}
+	if f.pos < f.offset {
+		return int(f.offset - f.pos), false
+	}
+	return n, true
+}
+
+// FaultyReader wraps an io.Reader and injects a fault once Offset bytes
+// have been read from it.
+type FaultyReader struct {
+	faultInjector
+	r io.Reader
+}
+
+// NewFaultyReader returns a reader that reads from r and injects the given
+// fault at offset. The delay is only used by FaultDelay.
+func NewFaultyReader(r io.Reader, fault Fault, offset int64, delay time.Duration) *FaultyReader {
+	return &FaultyReader{
+		faultInjector: faultInjector{fault: fault, offset: offset, delay: delay},
+		r:             r,
+	}
+}
+
+func (f *FaultyReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	l, atFault := f.limit(len(p))
+	if atFault {
+		switch f.fault {
+		case FaultEOF:
+			return 0, io.EOF
+		case FaultTruncate:
+			return 0, io.ErrUnexpectedEOF
+		case FaultDelay:
+			f.triggered = true
+			time.Sleep(f.delay)
+		case FaultCorrupt:
+			n, err := f.r.Read(p[:1])
+			if n > 0 {
+				p[0] ^= 0xff
+				f.triggered = true
+				f.pos++
+			}
+			return n, err
+		}
+	}
+
+	n, err := f.r.Read(p[:l])
+	f.pos += int64(n)
+	return n, err
+}
+
+// FaultyWriter wraps an io.Writer and injects a fault once Offset bytes
+// have been written to it.
+type FaultyWriter struct {
+	faultInjector
+	w io.Writer
+}
+
+// NewFaultyWriter returns a writer that writes to w and injects the given
+// fault at offset. The delay is only used by FaultDelay.
+func NewFaultyWriter(w io.Writer, fault Fault, offset int64, delay time.Duration) *FaultyWriter {
+	return &FaultyWriter{
+		faultInjector: faultInjector{fault: fault, offset: offset, delay: delay},
+		w:             w,
+	}
+}
+
+func (f *FaultyWriter) Write(p []byte) (int, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	written := 0
+	for written < len(p) {
+		l, atFault := f.limit(len(p) - written)
+		if atFault {
+			switch f.fault {
+			case FaultEOF, FaultTruncate:
+				return written, ErrFaultInjected
+			case FaultDelay:
+				f.triggered = true
+				time.Sleep(f.delay)
+			case FaultCorrupt:
+				n, err := f.w.Write([]byte{p[written] ^ 0xff})
+				written += n
+				f.pos += int64(n)
+				if err != nil {
+					return written, err
+				}
+				f.triggered = true
+				continue
+			}
+		}
+		n, err := f.w.Write(p[written : written+l])
+		written += n
+		f.pos += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}